@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/gorilla/handlers"
 	"github.com/gorilla/mux"
@@ -15,25 +16,84 @@ import (
 	_ "trading-matching-service/docs"
 	"trading-matching-service/pkg/api"
 	"trading-matching-service/pkg/engine"
+	booksvc "trading-matching-service/pkg/service/book"
 	cancelsvc "trading-matching-service/pkg/service/cancel"
+	feesvc "trading-matching-service/pkg/service/fee"
+	instrumentsvc "trading-matching-service/pkg/service/instrument"
+	journalsvc "trading-matching-service/pkg/service/journal"
+	marketdatasvc "trading-matching-service/pkg/service/marketdata"
 	msgsvc "trading-matching-service/pkg/service/message"
 	ordersvc "trading-matching-service/pkg/service/order"
+	statussvc "trading-matching-service/pkg/service/status"
 	tradesvc "trading-matching-service/pkg/service/trade"
+	"trading-matching-service/util/clock"
 )
 
 var (
-	qNameOrder  = "order"
-	qNameTrade  = "trade"
-	qNameCancel = "cancel"
+	qNameOrder      = "order"
+	qNameTrade      = "trade"
+	qNameCancel     = "cancel"
+	qNameStatus     = "status"
+	qNameReport     = "report"
+	qNameMarketData = "market-data"
 )
 
+// serverShutdownTimeout is how long the server waits for the requests in flight when it stops.
+const serverShutdownTimeout = 5 * time.Second
+
 // ApplicationConfig defines application config struct.
 type ApplicationConfig struct {
 	ServicePort string
+	Instruments []instrumentsvc.Instrument
 
 	OrderQueueSize  int
 	TradeQueueSize  int
 	CancelQueueSize int
+	StatusQueueSize int
+	ReportQueueSize int
+
+	MarketDataQueueSize int
+	// MarketDataLevels is how many price levels of each side of a book are streamed as depth.
+	MarketDataLevels int
+	// StreamBufferSize is how many market data events are buffered for a stream client before it
+	// is disconnected as a slow consumer.
+	StreamBufferSize int
+	// StreamRecentTrades is how many recent trades are sent as the snapshot of a trades subscription.
+	StreamRecentTrades int
+
+	// DayClose is the time of day in UTC at which day orders expire.
+	DayClose time.Duration
+	// PostOnlyPolicy decides how post only orders crossing the book are handled.
+	PostOnlyPolicy engine.PostOnlyPolicy
+	// SelfTradePrevention decides how orders of the same account are prevented from trading.
+	SelfTradePrevention engine.SelfTradePreventionMode
+	// MarketOrderPolicy decides how the unfilled remainders of market orders are handled. Its zero
+	// value rests them in book as the match engine always did, while the server cancels them by
+	// default.
+	MarketOrderPolicy engine.MarketOrderPolicy
+	// Auctions are the daily periods in which orders are collected for a call auction.
+	Auctions []engine.AuctionWindow
+	// HaltCooldown is how long a book halted by its price band waits before resuming.
+	HaltCooldown time.Duration
+	// ResumeAuction is how long the auction resuming a halted book lasts.
+	ResumeAuction time.Duration
+	// HaltOrderPolicy decides how new orders are handled while a book is halted.
+	HaltOrderPolicy engine.HaltOrderPolicy
+
+	// Fees are the fee schedules charged on trades.
+	Fees feesvc.Config
+
+	// JournalDir is where the match engine keeps its journal and snapshots, no journal if empty.
+	JournalDir string
+	// JournalSyncPolicy decides when journal writes are flushed to disk.
+	JournalSyncPolicy journalsvc.SyncPolicy
+	// JournalSyncInterval is how often journal writes are flushed to disk with the interval policy.
+	JournalSyncInterval time.Duration
+	// SnapshotInterval is how many inbound messages apart the match engine saves snapshots.
+	SnapshotInterval uint64
+
+	// APIKeys maps api keys to account ids. Requests are anonymous if it is empty.
+	APIKeys map[string]string
 }
 
 // Application is a collection of applications including http server or any other apps.
@@ -43,21 +103,44 @@ type Application struct {
 	matchEngine  engine.Engine
 	tradeEngine  engine.Engine
 	cancelEngine engine.Engine
+	statusEngine engine.Engine
+	reportEngine engine.Engine
+	mdEngine     engine.Engine
+	journal      journalsvc.Journal
 }
 
 // NewApplication creates a application.
 func NewApplication(config ApplicationConfig) (*Application, error) {
 	queues := getQueues(config)
 	store := ordersvc.NewMemoryStore()
+	instrumentStore := instrumentsvc.NewMemoryStore(config.Instruments...)
+	feeStore := feesvc.NewMemoryStore(config.Fees)
+	clk := clock.NewSystemClock()
+	hub := marketdatasvc.NewMemoryHub(config.StreamBufferSize, config.StreamRecentTrades)
 
-	h, err := getHTTPHandler(config, queues, store)
+	jnl, err := getJournal(config)
 	if err != nil {
 		return nil, err
 	}
 
-	me := getMatchEngine(queues, store)
-	te := getTradeEngine(queues)
+	me := getMatchEngine(config, queues, store, instrumentStore, clk, jnl)
+	if r, ok := me.(engine.Recoverer); ok {
+		if err := r.Recover(context.Background()); err != nil {
+			return nil, errors.Errorf("failed to recover match engine: %v", err)
+		}
+	}
+	bookReader := me.(booksvc.Reader)
+
+	h, err := getHTTPHandler(config, queues, store, instrumentStore, feeStore, bookReader, hub, clk)
+	if err != nil {
+		return nil, err
+	}
+
+	te := getTradeEngine(queues, feeStore)
 	ce := getCancelEngine(queues)
+	se := getStatusEngine(queues)
+	re := getReportEngine(queues)
+	mde := getMarketDataEngine(queues, hub)
 
 	return &Application{
 		ApplicationConfig: config,
@@ -65,23 +148,52 @@ func NewApplication(config ApplicationConfig) (*Application, error) {
 		matchEngine:       me,
 		tradeEngine:       te,
 		cancelEngine:      ce,
+		statusEngine:      se,
+		reportEngine:      re,
+		mdEngine:          mde,
+		journal:           jnl,
 	}, nil
 }
 
 // Run runs the application.
 func (a *Application) Run(ctx context.Context) error {
-	eg := errgroup.Group{}
+	if a.journal != nil {
+		defer a.journal.Close()
+	}
+
+	// any engine or the server stopping stops the others, so no order is accepted once the match
+	// engine is gone
+	eg, ctx := errgroup.WithContext(ctx)
 	eg.Go(func() error {
 		return a.tradeEngine.Run(ctx)
 	})
 	eg.Go(func() error {
 		return a.cancelEngine.Run(ctx)
 	})
+	eg.Go(func() error {
+		return a.statusEngine.Run(ctx)
+	})
+	eg.Go(func() error {
+		return a.reportEngine.Run(ctx)
+	})
+	eg.Go(func() error {
+		return a.mdEngine.Run(ctx)
+	})
 	eg.Go(func() error {
 		return a.matchEngine.Run(ctx)
 	})
+	srv := &http.Server{Addr: fmt.Sprintf(":%s", a.ServicePort), Handler: a.handler}
+	eg.Go(func() error {
+		if err := srv.ListenAndServe(); err != http.ErrServerClosed {
+			return err
+		}
+		return nil
+	})
 	eg.Go(func() error {
-		return http.ListenAndServe(fmt.Sprintf(":%s", a.ServicePort), a.handler)
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), serverShutdownTimeout)
+		defer cancel()
+		return srv.Shutdown(shutdownCtx)
 	})
 
 	if err := eg.Wait(); err != nil {
@@ -93,28 +205,31 @@ func (a *Application) Run(ctx context.Context) error {
 
 func getQueues(config ApplicationConfig) map[string]msgsvc.Queue {
 	m := map[string]msgsvc.Queue{
-		qNameOrder:  msgsvc.NewQueue(config.OrderQueueSize),
-		qNameTrade:  msgsvc.NewQueue(config.TradeQueueSize),
-		qNameCancel: msgsvc.NewQueue(config.CancelQueueSize),
+		qNameOrder:      msgsvc.NewQueue(config.OrderQueueSize),
+		qNameTrade:      msgsvc.NewQueue(config.TradeQueueSize),
+		qNameCancel:     msgsvc.NewQueue(config.CancelQueueSize),
+		qNameStatus:     msgsvc.NewQueue(config.StatusQueueSize),
+		qNameReport:     msgsvc.NewQueue(config.ReportQueueSize),
+		qNameMarketData: msgsvc.NewQueue(config.MarketDataQueueSize),
 	}
 	return m
 }
 
-func getHTTPHandler(config ApplicationConfig, queues map[string]msgsvc.Queue, orderStore ordersvc.Store) (http.Handler, error) {
-	router, err := getRouter(queues, orderStore)
+func getHTTPHandler(config ApplicationConfig, queues map[string]msgsvc.Queue, orderStore ordersvc.Store, instrumentStore instrumentsvc.Store, feeStore feesvc.Store, bookReader booksvc.Reader, marketData marketdatasvc.Hub, clk clock.Clock) (http.Handler, error) {
+	router, err := getRouter(config, queues, orderStore, instrumentStore, feeStore, bookReader, marketData, clk)
 	if err != nil {
 		return nil, errors.Errorf("failed to get router: %v", err)
 	}
 
-	headersOk := handlers.AllowedHeaders([]string{"Origin", "Content-Type"})
+	headersOk := handlers.AllowedHeaders([]string{"Origin", "Content-Type", "X-API-Key"})
 	originsOk := handlers.AllowedOrigins([]string{fmt.Sprintf("http://localhost:%s", config.ServicePort), fmt.Sprintf("http://127.0.0.1:%s", config.ServicePort)})
-	methodsOk := handlers.AllowedMethods([]string{"POST", "DELETE", "OPTIONS"})
+	methodsOk := handlers.AllowedMethods([]string{"GET", "POST", "DELETE", "PATCH", "OPTIONS"})
 
 	return handlers.CORS(headersOk, originsOk, methodsOk)(router), nil
 }
 
-func getRouter(queues map[string]msgsvc.Queue, orderStore ordersvc.Store) (*mux.Router, error) {
-	controller, err := getController(queues, orderStore)
+func getRouter(config ApplicationConfig, queues map[string]msgsvc.Queue, orderStore ordersvc.Store, instrumentStore instrumentsvc.Store, feeStore feesvc.Store, bookReader booksvc.Reader, marketData marketdatasvc.Hub, clk clock.Clock) (*mux.Router, error) {
+	controller, err := getController(config, queues, orderStore, instrumentStore, feeStore, bookReader, marketData, clk)
 	if err != nil {
 		return nil, err
 	}
@@ -123,22 +238,77 @@ func getRouter(queues map[string]msgsvc.Queue, orderStore ordersvc.Store) (*mux.
 	r.PathPrefix("/swagger-ui/").Handler(httpswagger.WrapHandler)
 	apiV1 := r.PathPrefix("/api/v1").Subrouter()
 	apiV1.HandleFunc("/orders", controller.PlaceOrder).Methods(http.MethodPost)
+	apiV1.HandleFunc("/orders/{oid}", controller.GetOrder).Methods(http.MethodGet)
 	apiV1.HandleFunc("/orders/{oid}", controller.CancelOrder).Methods(http.MethodDelete)
+	apiV1.HandleFunc("/orders/{oid}", controller.AmendOrder).Methods(http.MethodPatch)
+	apiV1.HandleFunc("/order-groups", controller.PlaceOrderGroup).Methods(http.MethodPost)
+	apiV1.HandleFunc("/fees", controller.GetFees).Methods(http.MethodGet)
+	apiV1.HandleFunc("/books/{symbol}/depth", controller.GetDepth).Methods(http.MethodGet)
+	apiV1.HandleFunc("/books/{symbol}/orders", controller.GetBookSnapshot).Methods(http.MethodGet)
+	apiV1.HandleFunc("/stream", controller.StreamMarketData).Methods(http.MethodGet)
 	return r, nil
 }
 
-func getController(queues map[string]msgsvc.Queue, orderStore ordersvc.Store) (*api.Controller, error) {
-	return api.NewController(queues[qNameOrder], orderStore), nil
+func getController(config ApplicationConfig, queues map[string]msgsvc.Queue, orderStore ordersvc.Store, instrumentStore instrumentsvc.Store, feeStore feesvc.Store, bookReader booksvc.Reader, marketData marketdatasvc.Hub, clk clock.Clock) (*api.Controller, error) {
+	authenticator := api.NewAnonymousAuthenticator()
+	if len(config.APIKeys) > 0 {
+		authenticator = api.NewAPIKeyAuthenticator(config.APIKeys)
+	}
+	return api.NewController(queues[qNameOrder], orderStore, instrumentStore, feeStore, bookReader, marketData, authenticator, clk), nil
 }
 
-func getMatchEngine(queues map[string]msgsvc.Queue, orderStore ordersvc.Store) engine.Engine {
-	return engine.NewMatchEngine(orderStore, queues[qNameOrder], queues[qNameTrade], queues[qNameCancel])
+func getMatchEngine(config ApplicationConfig, queues map[string]msgsvc.Queue, orderStore ordersvc.Store, instrumentStore instrumentsvc.Store, clk clock.Clock, jnl journalsvc.Journal) engine.Engine {
+	opts := []engine.MatchEngineOption{
+		engine.WithInstrumentStore(instrumentStore),
+		engine.WithDayClose(config.DayClose),
+		engine.WithPostOnlyPolicy(config.PostOnlyPolicy),
+		engine.WithSelfTradePrevention(config.SelfTradePrevention),
+		engine.WithMarketOrderPolicy(config.MarketOrderPolicy),
+		engine.WithAuctions(config.Auctions...),
+		engine.WithCircuitBreaker(config.HaltCooldown, config.ResumeAuction),
+		engine.WithHaltOrderPolicy(config.HaltOrderPolicy),
+		engine.WithStatusQueue(queues[qNameStatus]),
+		engine.WithReportQueue(queues[qNameReport]),
+		engine.WithMarketDataQueue(queues[qNameMarketData], config.MarketDataLevels),
+		engine.WithClock(clk),
+	}
+	if jnl != nil {
+		opts = append(opts, engine.WithJournal(jnl, config.SnapshotInterval))
+	}
+	return engine.NewMatchEngine(orderStore, queues[qNameOrder], queues[qNameTrade], queues[qNameCancel], opts...)
+}
+
+func getJournal(config ApplicationConfig) (journalsvc.Journal, error) {
+	if config.JournalDir == "" {
+		return nil, nil
+	}
+	jnl, err := journalsvc.NewFileJournal(journalsvc.Config{
+		Dir:          config.JournalDir,
+		SyncPolicy:   config.JournalSyncPolicy,
+		SyncInterval: config.JournalSyncInterval,
+	})
+	if err != nil {
+		return nil, errors.Errorf("failed to open journal: %v", err)
+	}
+	return jnl, nil
 }
 
-func getTradeEngine(queues map[string]msgsvc.Queue) engine.Engine {
-	return engine.NewTradeEngine(queues[qNameTrade], tradesvc.NewStdoutRecorder())
+func getTradeEngine(queues map[string]msgsvc.Queue, feeStore feesvc.Store) engine.Engine {
+	return engine.NewTradeEngine(queues[qNameTrade], tradesvc.NewStdoutRecorder(), engine.WithFeeStore(feeStore))
 }
 
 func getCancelEngine(queues map[string]msgsvc.Queue) engine.Engine {
 	return engine.NewCancelEngine(queues[qNameCancel], cancelsvc.NewStdoutRecorder())
 }
+
+func getStatusEngine(queues map[string]msgsvc.Queue) engine.Engine {
+	return engine.NewStatusEngine(queues[qNameStatus], statussvc.NewStdoutRecorder())
+}
+
+func getReportEngine(queues map[string]msgsvc.Queue) engine.Engine {
+	return engine.NewReportEngine(queues[qNameReport], tradesvc.NewStdoutReportRecorder())
+}
+
+func getMarketDataEngine(queues map[string]msgsvc.Queue, publisher marketdatasvc.Publisher) engine.Engine {
+	return engine.NewMarketDataEngine(queues[qNameMarketData], publisher)
+}
@@ -0,0 +1,213 @@
+// Command replay runs a stream of orders and cancels through the match engine without the server,
+// writes the trades and the cancels it produces to files and prints summary statistics.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+
+	"trading-matching-service/pkg/engine"
+	cancelsvc "trading-matching-service/pkg/service/cancel"
+	instrumentsvc "trading-matching-service/pkg/service/instrument"
+	msgsvc "trading-matching-service/pkg/service/message"
+	ordersvc "trading-matching-service/pkg/service/order"
+)
+
+var (
+	inputFile       string
+	tradesFile      string
+	cancelsFile     string
+	instrumentsFile string
+	postOnlyReprice bool
+	stpMode         string
+	marketRemainder string
+)
+
+var stpModes = map[string]engine.SelfTradePreventionMode{
+	"none":                 engine.SelfTradePreventionNone,
+	"cancel-newest":        engine.SelfTradePreventionCancelNewest,
+	"cancel-oldest":        engine.SelfTradePreventionCancelOldest,
+	"cancel-both":          engine.SelfTradePreventionCancelBoth,
+	"decrement-and-cancel": engine.SelfTradePreventionDecrementAndCancel,
+}
+
+var marketOrderPolicies = map[string]engine.MarketOrderPolicy{
+	"rest":   engine.MarketOrderPolicyRest,
+	"cancel": engine.MarketOrderPolicyCancel,
+	"limit":  engine.MarketOrderPolicyConvertToLimit,
+	"reject": engine.MarketOrderPolicyReject,
+}
+
+var orderStatusNames = map[ordersvc.OrderStatus]string{
+	ordersvc.OrderStatusPending:         "pending",
+	ordersvc.OrderStatusNew:             "new",
+	ordersvc.OrderStatusPartiallyFilled: "partially filled",
+	ordersvc.OrderStatusFilled:          "filled",
+	ordersvc.OrderStatusCanceled:        "canceled",
+	ordersvc.OrderStatusRejected:        "rejected",
+	ordersvc.OrderStatusExpired:         "expired",
+}
+
+var cancelReasonNames = map[cancelsvc.CancelReason]string{
+	cancelsvc.CancelReasonUser:              "user",
+	cancelsvc.CancelReasonImmediateOrCancel: "ioc",
+	cancelsvc.CancelReasonFillOrKill:        "fok",
+	cancelsvc.CancelReasonExpired:           "expired",
+	cancelsvc.CancelReasonPostOnly:          "post only",
+	cancelsvc.CancelReasonSelfTrade:         "self trade",
+	cancelsvc.CancelReasonMarketRemainder:   "market remainder",
+	cancelsvc.CancelReasonNoLiquidity:       "no liquidity",
+	cancelsvc.CancelReasonHalted:            "halted",
+	cancelsvc.CancelReasonLinked:            "linked",
+}
+
+func init() {
+	flag.StringVar(&inputFile, "input", "", "path to a json lines file of inbound messages, such as a match engine journal")
+	flag.StringVar(&tradesFile, "trades", "trades.jsonl", "path of the json lines file the trades are written to")
+	flag.StringVar(&cancelsFile, "cancels", "cancels.jsonl", "path of the json lines file the cancels are written to")
+	flag.StringVar(&instrumentsFile, "instruments", "", "path to a json file of instrument configs, every symbol uses the default config if not set")
+	flag.BoolVar(&postOnlyReprice, "post-only-reprice", false, "reprice crossing post only orders instead of rejecting them")
+	flag.StringVar(&stpMode, "stp", "cancel-newest", "self trade prevention mode: none, cancel-newest, cancel-oldest, cancel-both or decrement-and-cancel")
+	flag.StringVar(&marketRemainder, "market-remainder", "cancel", "what to do with the unfilled remainder of a market order: cancel, limit, reject or rest")
+}
+
+func main() {
+	flag.Parse()
+	if inputFile == "" {
+		log.Fatal("missing -input")
+	}
+
+	opts, err := getMatchEngineOptions()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	input, err := os.Open(inputFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer input.Close()
+
+	trades, err := os.Create(tradesFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer trades.Close()
+
+	cancels, err := os.Create(cancelsFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer cancels.Close()
+
+	sum, err := replay(context.Background(), replayConfig{
+		input:   input,
+		trades:  trades,
+		cancels: cancels,
+		opts:    opts,
+	})
+	if err != nil {
+		log.Fatalf("failed to replay %s: %v", inputFile, err)
+	}
+	printSummary(os.Stdout, sum)
+}
+
+func getMatchEngineOptions() ([]engine.MatchEngineOption, error) {
+	stp, ok := stpModes[stpMode]
+	if !ok {
+		return nil, fmt.Errorf("invalid self trade prevention mode: %s", stpMode)
+	}
+	marketOrderPolicy, ok := marketOrderPolicies[marketRemainder]
+	if !ok {
+		return nil, fmt.Errorf("invalid market order policy: %s", marketRemainder)
+	}
+	postOnlyPolicy := engine.PostOnlyPolicyReject
+	if postOnlyReprice {
+		postOnlyPolicy = engine.PostOnlyPolicyReprice
+	}
+
+	opts := []engine.MatchEngineOption{
+		engine.WithSelfTradePrevention(stp),
+		engine.WithMarketOrderPolicy(marketOrderPolicy),
+		engine.WithPostOnlyPolicy(postOnlyPolicy),
+	}
+
+	if instrumentsFile != "" {
+		bs, err := os.ReadFile(instrumentsFile)
+		if err != nil {
+			return nil, err
+		}
+		instruments := []instrumentsvc.Instrument{}
+		if err := json.Unmarshal(bs, &instruments); err != nil {
+			return nil, err
+		}
+		opts = append(opts, engine.WithInstrumentStore(instrumentsvc.NewMemoryStore(instruments...)))
+	}
+	return opts, nil
+}
+
+func printSummary(w io.Writer, sum *summary) {
+	messages := 0
+	for _, n := range sum.Messages {
+		messages += n
+	}
+	fmt.Fprintf(w, "messages: %d in %v\n", messages, sum.Elapsed)
+	names := map[msgsvc.MessageKind]string{}
+	for name, kind := range messageKinds {
+		names[kind] = name
+	}
+	for kind := msgsvc.MessageKind(0); int(kind) < msgsvc.NumOfMessageKind; kind++ {
+		n := sum.Messages[kind]
+		if n == 0 {
+			continue
+		}
+		if name, ok := names[kind]; ok {
+			fmt.Fprintf(w, "  %s: %d\n", name, n)
+		} else {
+			fmt.Fprintf(w, "  kind %d: %d\n", kind, n)
+		}
+	}
+
+	orders := 0
+	for _, n := range sum.Orders {
+		orders += n
+	}
+	fmt.Fprintf(w, "orders: %d\n", orders)
+	for status := ordersvc.OrderStatusPending; status <= ordersvc.OrderStatusExpired; status++ {
+		if n := sum.Orders[status]; n > 0 {
+			fmt.Fprintf(w, "  %s: %d\n", orderStatusNames[status], n)
+		}
+	}
+
+	trades := 0
+	for _, n := range sum.Trades {
+		trades += n
+	}
+	fmt.Fprintf(w, "trades: %d\n", trades)
+	symbols := make([]string, 0, len(sum.Trades))
+	for symbol := range sum.Trades {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+	for _, symbol := range symbols {
+		fmt.Fprintf(w, "  %s: %d trades, volume %s, notional %s\n", symbol, sum.Trades[symbol], sum.Volume[symbol], sum.Notional[symbol])
+	}
+
+	cancels := 0
+	for _, n := range sum.Cancels {
+		cancels += n
+	}
+	fmt.Fprintf(w, "cancels: %d\n", cancels)
+	for reason := cancelsvc.CancelReasonUser; reason <= cancelsvc.CancelReasonLinked; reason++ {
+		if n := sum.Cancels[reason]; n > 0 {
+			fmt.Fprintf(w, "  %s: %d\n", cancelReasonNames[reason], n)
+		}
+	}
+	fmt.Fprintf(w, "cancel rejects: %d\n", sum.CancelRejects)
+}
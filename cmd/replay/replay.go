@@ -0,0 +1,267 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"trading-matching-service/pkg/engine"
+	cancelsvc "trading-matching-service/pkg/service/cancel"
+	msgsvc "trading-matching-service/pkg/service/message"
+	ordersvc "trading-matching-service/pkg/service/order"
+	tradesvc "trading-matching-service/pkg/service/trade"
+	"trading-matching-service/util/decimal"
+)
+
+// messageKinds maps the names accepted as the kind of an input line to message kinds.
+var messageKinds = map[string]msgsvc.MessageKind{
+	"order_create":       msgsvc.MessageKindOrderCreate,
+	"order_cancel":       msgsvc.MessageKindOrderCancel,
+	"order_amend":        msgsvc.MessageKindOrderAmend,
+	"order_group_create": msgsvc.MessageKindOrderGroupCreate,
+	"timer":              msgsvc.MessageKindTimer,
+}
+
+// messageKind is the kind of an input line, given by its name or its number.
+type messageKind msgsvc.MessageKind
+
+func (k *messageKind) UnmarshalJSON(bs []byte) error {
+	name := ""
+	if err := json.Unmarshal(bs, &name); err != nil {
+		n, err := strconv.ParseUint(string(bs), 10, 32)
+		if err != nil {
+			return fmt.Errorf("invalid message kind: %s", bs)
+		}
+		*k = messageKind(n)
+		return nil
+	}
+
+	kind, ok := messageKinds[name]
+	if !ok {
+		return fmt.Errorf("invalid message kind: %s", name)
+	}
+	*k = messageKind(kind)
+	return nil
+}
+
+// line is an inbound message of the input. Lines of the match engine journal have the same shape
+// and can be read as they are, but the timer messages and the GTD expiries of a journal of the
+// server are in nanoseconds while a replay runs in sequence numbers, so its expiries, auctions and
+// halts do not reproduce.
+type line struct {
+	Kind messageKind
+	Data json.RawMessage
+}
+
+// replayConfig defines the input and the outputs of a replay and how the match engine runs.
+type replayConfig struct {
+	input   io.Reader
+	trades  io.Writer
+	cancels io.Writer
+	opts    []engine.MatchEngineOption
+}
+
+// summary is the statistics of a replay. Trades, volumes and notionals are by symbol.
+type summary struct {
+	Messages      map[msgsvc.MessageKind]int
+	Orders        map[ordersvc.OrderStatus]int
+	Trades        map[string]int
+	Volume        map[string]decimal.Decimal
+	Notional      map[string]decimal.Decimal
+	Cancels       map[cancelsvc.CancelReason]int
+	CancelRejects int
+	Elapsed       time.Duration
+}
+
+// replay runs the inbound messages of the input through a deterministic match engine, writes the
+// trades and the cancels it publishes as json lines, and returns the statistics of the run.
+func replay(ctx context.Context, cfg replayConfig) (*summary, error) {
+	sum := &summary{
+		Messages: map[msgsvc.MessageKind]int{},
+		Orders:   map[ordersvc.OrderStatus]int{},
+		Trades:   map[string]int{},
+		Volume:   map[string]decimal.Decimal{},
+		Notional: map[string]decimal.Decimal{},
+		Cancels:  map[cancelsvc.CancelReason]int{},
+	}
+	store := ordersvc.NewMemoryStore()
+	orderQ := &inputQueue{scanner: bufio.NewScanner(cfg.input), store: store, summary: sum}
+	orderQ.scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	tradeQ, cancelQ := &bufferQueue{}, &bufferQueue{}
+	tradeR := &tradeRecorder{enc: json.NewEncoder(cfg.trades), summary: sum}
+	cancelR := &cancelRecorder{enc: json.NewEncoder(cfg.cancels), summary: sum}
+
+	opts := append([]engine.MatchEngineOption{engine.WithDeterministic()}, cfg.opts...)
+	me := engine.NewMatchEngine(store, orderQ, tradeQ, cancelQ, opts...)
+	te := engine.NewTradeEngine(tradeQ, tradeR)
+	ce := engine.NewCancelEngine(cancelQ, cancelR)
+
+	start := time.Now()
+	// every engine returns io.EOF once its queue is drained
+	for _, e := range []engine.Engine{me, te, ce} {
+		if err := e.Run(ctx); err != io.EOF {
+			return nil, err
+		}
+	}
+	sum.Elapsed = time.Since(start)
+
+	if orderQ.err != nil {
+		return nil, orderQ.err
+	}
+	if tradeR.err != nil {
+		return nil, tradeR.err
+	}
+	if cancelR.err != nil {
+		return nil, cancelR.err
+	}
+
+	ords, err := store.ListOrders(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, ord := range ords {
+		sum.Orders[ord.Status]++
+	}
+	return sum, nil
+}
+
+// inputQueue pops the inbound messages of the input in order, and returns io.EOF after the last
+// one. Orders are created in store before they are popped, as the controller does.
+type inputQueue struct {
+	scanner *bufio.Scanner
+	store   ordersvc.Store
+	summary *summary
+	lineNo  int
+	err     error
+}
+
+func (q *inputQueue) Push(ctx context.Context, msg msgsvc.Message) error {
+	// the timer of a deterministic match engine is off, so nothing is pushed back
+	return nil
+}
+
+func (q *inputQueue) Pop(ctx context.Context) (msgsvc.AcknowledgementMessage, error) {
+	for q.scanner.Scan() {
+		q.lineNo++
+		bs := q.scanner.Bytes()
+		if len(bs) == 0 {
+			continue
+		}
+
+		l := line{}
+		if err := json.Unmarshal(bs, &l); err != nil {
+			q.err = fmt.Errorf("line %d: %v", q.lineNo, err)
+			return nil, io.EOF
+		}
+		msg := msgsvc.NewMessageWithBytes(msgsvc.MessageKind(l.Kind), l.Data)
+		if err := q.createOrders(ctx, msg); err != nil {
+			q.err = fmt.Errorf("line %d: %v", q.lineNo, err)
+			return nil, io.EOF
+		}
+		q.summary.Messages[msg.GetKind()]++
+		return &bufferMessage{Message: msg}, nil
+	}
+
+	if err := q.scanner.Err(); err != nil {
+		q.err = err
+	}
+	return nil, io.EOF
+}
+
+// createOrders creates the orders of the message in store.
+func (q *inputQueue) createOrders(ctx context.Context, msg msgsvc.Message) error {
+	ords := []ordersvc.Order{}
+	switch msg.GetKind() {
+	case msgsvc.MessageKindOrderCreate:
+		ord := ordersvc.Order{}
+		if err := json.Unmarshal(msg.GetData(), &ord); err != nil {
+			return err
+		}
+		ords = append(ords, ord)
+	case msgsvc.MessageKindOrderGroupCreate:
+		grp := ordersvc.OrderGroup{}
+		if err := json.Unmarshal(msg.GetData(), &grp); err != nil {
+			return err
+		}
+		ords = append(ords, grp.Orders...)
+	}
+
+	for _, ord := range ords {
+		if _, err := q.store.CreateOrder(ctx, ord); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// bufferQueue keeps the pushed messages in memory, and returns io.EOF once they are all popped.
+type bufferQueue struct {
+	msgs []msgsvc.Message
+}
+
+func (q *bufferQueue) Push(ctx context.Context, msg msgsvc.Message) error {
+	q.msgs = append(q.msgs, msg)
+	return nil
+}
+
+func (q *bufferQueue) Pop(ctx context.Context) (msgsvc.AcknowledgementMessage, error) {
+	if len(q.msgs) == 0 {
+		return nil, io.EOF
+	}
+	msg := q.msgs[0]
+	q.msgs = q.msgs[1:]
+	return &bufferMessage{Message: msg}, nil
+}
+
+// bufferMessage is a message of a replay. Nothing is retried, since the recorders never fail.
+type bufferMessage struct {
+	msgsvc.Message
+}
+
+func (m *bufferMessage) Ack() {
+}
+
+func (m *bufferMessage) Nack() {
+}
+
+// tradeRecorder writes trades as json lines and counts them. It keeps the first write error
+// instead of returning it, so the trade is not retried.
+type tradeRecorder struct {
+	enc     *json.Encoder
+	summary *summary
+	err     error
+}
+
+func (r *tradeRecorder) CreateTradeRecord(ctx context.Context, td tradesvc.Trade) error {
+	if err := r.enc.Encode(&td); err != nil && r.err == nil {
+		r.err = err
+	}
+	r.summary.Trades[td.Symbol]++
+	r.summary.Volume[td.Symbol] = r.summary.Volume[td.Symbol].Add(td.Quantity)
+	r.summary.Notional[td.Symbol] = r.summary.Notional[td.Symbol].Add(td.Price.Mul(td.Quantity))
+	return nil
+}
+
+// cancelRecorder writes cancels as json lines and counts them. It keeps the first write error
+// instead of returning it, so the cancel is not retried.
+type cancelRecorder struct {
+	enc     *json.Encoder
+	summary *summary
+	err     error
+}
+
+func (r *cancelRecorder) CreateCancelRecord(ctx context.Context, ccl cancelsvc.Cancel) error {
+	if err := r.enc.Encode(&ccl); err != nil && r.err == nil {
+		r.err = err
+	}
+	if ccl.IsRejected() {
+		r.summary.CancelRejects++
+	} else {
+		r.summary.Cancels[ccl.Reason]++
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	cancelsvc "trading-matching-service/pkg/service/cancel"
+	msgsvc "trading-matching-service/pkg/service/message"
+	ordersvc "trading-matching-service/pkg/service/order"
+	"trading-matching-service/util/decimal"
+)
+
+var update = flag.Bool("update", false, "update the golden files of the replay")
+
+func TestReplayGolden(t *testing.T) {
+	input, err := os.Open(filepath.Join("testdata", "orders.jsonl"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer input.Close()
+
+	trades, cancels := &bytes.Buffer{}, &bytes.Buffer{}
+	sum, err := replay(context.Background(), replayConfig{input: input, trades: trades, cancels: cancels})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assertGolden(t, filepath.Join("testdata", "trades.golden.jsonl"), trades.Bytes())
+	assertGolden(t, filepath.Join("testdata", "cancels.golden.jsonl"), cancels.Bytes())
+
+	assert.Equal(t, map[msgsvc.MessageKind]int{
+		msgsvc.MessageKindOrderCreate: 8,
+		msgsvc.MessageKindOrderCancel: 1,
+		msgsvc.MessageKindOrderAmend:  1,
+		msgsvc.MessageKindTimer:       1,
+	}, sum.Messages)
+	assert.Equal(t, map[string]int{"BTC-USD": 4, "ETH-USD": 1}, sum.Trades)
+	assert.Equal(t, decimal.New(6), sum.Volume["BTC-USD"])
+	assert.Equal(t, decimal.MustParse("9.5"), sum.Notional["ETH-USD"])
+	assert.Equal(t, map[cancelsvc.CancelReason]int{
+		cancelsvc.CancelReasonImmediateOrCancel: 1,
+		cancelsvc.CancelReasonExpired:           1,
+	}, sum.Cancels)
+	assert.Equal(t, 1, sum.CancelRejects)
+	assert.Equal(t, 6, sum.Orders[ordersvc.OrderStatusFilled])
+}
+
+func TestReplayInvalidLine(t *testing.T) {
+	input := bytes.NewBufferString("{\"kind\": \"order_create\", \"data\": {\"ID\": \"B1\"}}\n{\"kind\": \"unknown\"}\n")
+	_, err := replay(context.Background(), replayConfig{input: input, trades: &bytes.Buffer{}, cancels: &bytes.Buffer{}})
+	assert.EqualError(t, err, "line 2: invalid message kind: unknown")
+}
+
+// assertGolden compares the output with the golden file, or rewrites the golden file with -update.
+func assertGolden(t *testing.T, path string, actual []byte) {
+	if *update {
+		assert.NoError(t, os.WriteFile(path, actual, 0o644))
+		return
+	}
+
+	expected, err := os.ReadFile(path)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, string(expected), string(actual))
+}
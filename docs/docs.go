@@ -25,6 +25,158 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
+        "/books/{symbol}/depth": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Book"
+                ],
+                "summary": "GetDepth",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "api key",
+                        "name": "X-API-Key",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "symbol",
+                        "name": "symbol",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "number of price levels of each side, 10 by default",
+                        "name": "levels",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.getDepthResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/books/{symbol}/orders": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Book"
+                ],
+                "summary": "GetBookSnapshot",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "api key",
+                        "name": "X-API-Key",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "symbol",
+                        "name": "symbol",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.getBookSnapshotResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/fees": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Fee"
+                ],
+                "summary": "GetFees",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "api key",
+                        "name": "X-API-Key",
+                        "in": "header"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "start of the period in unix nanoseconds, inclusive",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "end of the period in unix nanoseconds, exclusive, now by default",
+                        "name": "to",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.getFeesResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/order-groups": {
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Order"
+                ],
+                "summary": "PlaceOrderGroup",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "api key",
+                        "name": "X-API-Key",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Body",
+                        "name": "Body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.placeOrderGroupRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.placeOrderGroupResponse"
+                        }
+                    }
+                }
+            }
+        },
         "/orders": {
             "post": {
                 "consumes": [
@@ -38,6 +190,12 @@ const docTemplate = `{
                 ],
                 "summary": "PlaceOrder",
                 "parameters": [
+                    {
+                        "type": "string",
+                        "description": "api key",
+                        "name": "X-API-Key",
+                        "in": "header"
+                    },
                     {
                         "description": "Body",
                         "name": "Body",
@@ -59,6 +217,38 @@ const docTemplate = `{
             }
         },
         "/orders/{oid}": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Order"
+                ],
+                "summary": "GetOrder",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "api key",
+                        "name": "X-API-Key",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "oid",
+                        "name": "oid",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.getOrderResponse"
+                        }
+                    }
+                }
+            },
             "delete": {
                 "consumes": [
                     "application/json"
@@ -71,12 +261,62 @@ const docTemplate = `{
                 ],
                 "summary": "CancelOrder",
                 "parameters": [
+                    {
+                        "type": "string",
+                        "description": "api key",
+                        "name": "X-API-Key",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "oid",
+                        "name": "oid",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.GeneralResponse"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Order"
+                ],
+                "summary": "AmendOrder",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "api key",
+                        "name": "X-API-Key",
+                        "in": "header"
+                    },
                     {
                         "type": "string",
                         "description": "oid",
                         "name": "oid",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "description": "Body",
+                        "name": "Body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.amendOrderRequest"
+                        }
                     }
                 ],
                 "responses": {
@@ -88,6 +328,27 @@ const docTemplate = `{
                     }
                 }
             }
+        },
+        "/stream": {
+            "get": {
+                "tags": [
+                    "Book"
+                ],
+                "summary": "StreamMarketData",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "api key",
+                        "name": "X-API-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "101": {
+                        "description": "Switching Protocols"
+                    }
+                }
+            }
         }
     },
     "definitions": {
@@ -99,22 +360,297 @@ const docTemplate = `{
                 }
             }
         },
+        "api.amendOrderRequest": {
+            "type": "object",
+            "properties": {
+                "price": {
+                    "description": "Price is the new limit price, 0 keeps the current one.",
+                    "type": "number"
+                },
+                "quantity": {
+                    "description": "Quantity is the new unfilled quantity, 0 keeps the current one.",
+                    "type": "number"
+                },
+                "trigger_price": {
+                    "description": "TriggerPrice is the new trigger price of a stop order, 0 keeps the current one.",
+                    "type": "number"
+                }
+            }
+        },
+        "api.bookOrderResponse": {
+            "type": "object",
+            "properties": {
+                "order_id": {
+                    "type": "string"
+                },
+                "price": {
+                    "type": "number"
+                },
+                "price_type": {
+                    "description": "PriceType:\n* 1 - market price, ahead of every limit order.\n* 2 - limit price.",
+                    "type": "integer"
+                },
+                "quantity": {
+                    "description": "Quantity is the remaining quantity shown in book, without the hidden quantity of an iceberg order.",
+                    "type": "number"
+                },
+                "timestamp": {
+                    "description": "Timestamp ranks the orders at the same price, the earliest first.",
+                    "type": "integer"
+                }
+            }
+        },
+        "api.getBookSnapshotResponse": {
+            "type": "object",
+            "properties": {
+                "asks": {
+                    "description": "Asks are the sell orders in priority order.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.bookOrderResponse"
+                    }
+                },
+                "bids": {
+                    "description": "Bids are the buy orders in priority order.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.bookOrderResponse"
+                    }
+                },
+                "sequence": {
+                    "description": "Sequence is the sequence number of the last inbound message the match engine handled.",
+                    "type": "integer"
+                },
+                "symbol": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.getDepthResponse": {
+            "type": "object",
+            "properties": {
+                "asks": {
+                    "description": "Asks are the sell price levels, the lowest price first.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.priceLevelResponse"
+                    }
+                },
+                "bids": {
+                    "description": "Bids are the buy price levels, the highest price first.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.priceLevelResponse"
+                    }
+                },
+                "sequence": {
+                    "description": "Sequence is the sequence number of the last inbound message the match engine handled.",
+                    "type": "integer"
+                },
+                "symbol": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.getFeesResponse": {
+            "type": "object",
+            "properties": {
+                "account_id": {
+                    "type": "string"
+                },
+                "fees": {
+                    "description": "Fees are the fees paid over the period. Liquidity is 1 for maker, 2 for taker and 3 for\nauction fills.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/fee.Fee"
+                    }
+                },
+                "schedules": {
+                    "description": "Schedules are the fee schedules of the tier. Rates are fractions of the traded notional,\nand a negative rate is a rebate.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/fee.Schedule"
+                    }
+                },
+                "tier": {
+                    "description": "Tier is the fee tier of the account.",
+                    "type": "string"
+                },
+                "total": {
+                    "description": "Total is the sum of the fees paid over the period, negative for a net rebate.",
+                    "type": "number"
+                }
+            }
+        },
+        "api.getOrderResponse": {
+            "type": "object",
+            "properties": {
+                "amend_reject_reason": {
+                    "description": "AmendRejectReason is why the last amend request of the order was rejected, with the same\nvalues as cancel_reject_reason.",
+                    "type": "integer"
+                },
+                "average_fill_price": {
+                    "type": "number"
+                },
+                "cancel_reject_reason": {
+                    "description": "CancelRejectReason is why the last cancel request of the order was rejected:\n* 0 - none.\n* 1 - too late to cancel, the order was already filled or closed.\n* 2 - unknown order.",
+                    "type": "integer"
+                },
+                "confirmed_at": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "description": "CreatedAt, ConfirmedAt and UpdatedAt are unix timestamps in nanoseconds.",
+                    "type": "integer"
+                },
+                "expire_at": {
+                    "type": "integer"
+                },
+                "filled_quantity": {
+                    "type": "number"
+                },
+                "group_id": {
+                    "type": "string"
+                },
+                "order_id": {
+                    "type": "string"
+                },
+                "order_kind": {
+                    "description": "OrderKind:\n* 1 - buy order.\n* 2 - sell order.",
+                    "type": "integer"
+                },
+                "price": {
+                    "type": "number"
+                },
+                "price_type": {
+                    "description": "PriceType:\n* 1 - market price.\n* 2 - limit price.\n* 3 - stop, a market order placed when the last trade price reaches trigger_price.\n* 4 - stop limit, a limit order placed when the last trade price reaches trigger_price.\n* 5 - trailing stop, a stop order whose trigger price trails the best price seen since entry.\n* 6 - trailing stop limit, a trailing stop order placing a limit order at the trigger price moved by limit_offset.",
+                    "type": "integer"
+                },
+                "quantity": {
+                    "type": "number"
+                },
+                "remaining_quantity": {
+                    "type": "number"
+                },
+                "status": {
+                    "description": "Status:\n* 1 - pending, accepted but not yet confirmed by the match engine.\n* 2 - new, in book without any fill.\n* 3 - partially filled, in book with some fills.\n* 4 - filled.\n* 5 - canceled.\n* 6 - rejected by the match engine without any fill.\n* 7 - expired.",
+                    "type": "integer"
+                },
+                "symbol": {
+                    "type": "string"
+                },
+                "time_in_force": {
+                    "description": "TimeInForce:\n* 1 - good till canceled (default).\n* 2 - immediate or cancel.\n* 3 - fill or kill.\n* 4 - good till date, requires expire_at.\n* 5 - day.",
+                    "type": "integer"
+                },
+                "trigger_price": {
+                    "type": "number"
+                },
+                "updated_at": {
+                    "type": "integer"
+                }
+            }
+        },
+        "api.placeOrderGroupRequest": {
+            "type": "object",
+            "properties": {
+                "group_kind": {
+                    "description": "GroupKind:\n* 1 - one cancels other, a fill or a cancel of one order cancels the others.\n* 2 - bracket, an entry order followed by a take profit limit order and a stop loss stop order,\nwhich are placed as a one cancels other group once the entry order is filled.",
+                    "type": "integer"
+                },
+                "orders": {
+                    "description": "Orders of the group. The orders of a bracket are the entry, the take profit and the stop\nloss orders in that order.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.placeOrderRequest"
+                    }
+                },
+                "symbol": {
+                    "description": "Symbol is the instrument of every order of the group, e.g. BTC-USD.",
+                    "type": "string"
+                }
+            }
+        },
+        "api.placeOrderGroupResponse": {
+            "type": "object",
+            "properties": {
+                "group_id": {
+                    "type": "string"
+                },
+                "order_ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
         "api.placeOrderRequest": {
             "type": "object",
             "properties": {
+                "all_or_none": {
+                    "description": "AllOrNone makes the order only fill its whole remaining quantity at once.",
+                    "type": "boolean"
+                },
+                "display_quantity": {
+                    "description": "DisplayQuantity makes an iceberg order showing only this quantity in book, 0 shows the whole order.",
+                    "type": "number"
+                },
+                "expire_at": {
+                    "description": "ExpireAt is the unix timestamp in nanoseconds at which a good till date order expires.",
+                    "type": "integer"
+                },
+                "limit_offset": {
+                    "description": "LimitOffset moves the limit price of a triggered trailing stop limit order from its trigger price, in its favor.",
+                    "type": "number"
+                },
+                "min_quantity": {
+                    "description": "MinQuantity is the smallest quantity the order accepts to fill on arrival, and in each fill\nwhile it rests in book. An order that can not fill it on arrival does not trade at all.",
+                    "type": "number"
+                },
                 "order_kind": {
                     "description": "OrderKind:\n* 1 - buy order.\n* 2 - sell order.",
                     "type": "integer"
                 },
+                "post_only": {
+                    "description": "PostOnly makes sure a limit order only adds liquidity to book. If it would match on arrival,\nit is rejected or repriced depending on server config.",
+                    "type": "boolean"
+                },
                 "price": {
+                    "description": "Price must be a multiple of the tick size of the instrument.",
                     "type": "number"
                 },
                 "price_type": {
-                    "description": "PriceType:\n* 1 - market price.\n* 2 - limit price.",
+                    "description": "PriceType:\n* 1 - market price.\n* 2 - limit price.\n* 3 - stop, a market order placed when the last trade price reaches trigger_price.\n* 4 - stop limit, a limit order placed when the last trade price reaches trigger_price.\n* 5 - trailing stop, a stop order whose trigger price trails the best price seen since entry.\n* 6 - trailing stop limit, a trailing stop order placing a limit order at the trigger price moved by limit_offset.",
                     "type": "integer"
                 },
+                "protection_price": {
+                    "description": "ProtectionPrice is the worst price a market or stop order accepts to trade at, 0 for no limit.",
+                    "type": "number"
+                },
                 "quantity": {
+                    "description": "Quantity must be a multiple of the lot size of the instrument, which may be fractional.",
+                    "type": "number"
+                },
+                "symbol": {
+                    "description": "Symbol is the instrument to trade, e.g. BTC-USD.",
+                    "type": "string"
+                },
+                "time_in_force": {
+                    "description": "TimeInForce:\n* 1 - good till canceled (default).\n* 2 - immediate or cancel.\n* 3 - fill or kill.\n* 4 - good till date, requires expire_at.\n* 5 - day.",
                     "type": "integer"
+                },
+                "trail_amount": {
+                    "description": "TrailAmount is the distance of the trigger price of a trailing stop order from the best price seen.",
+                    "type": "number"
+                },
+                "trail_percent": {
+                    "description": "TrailPercent is the distance of the trigger price of a trailing stop order from the best price seen in percent.",
+                    "type": "number"
+                },
+                "trigger_price": {
+                    "description": "TriggerPrice is required by stop and stop limit orders.",
+                    "type": "number"
                 }
             }
         },
@@ -125,6 +661,73 @@ const docTemplate = `{
                     "type": "string"
                 }
             }
+        },
+        "api.priceLevelResponse": {
+            "type": "object",
+            "properties": {
+                "order_count": {
+                    "type": "integer"
+                },
+                "price": {
+                    "type": "number"
+                },
+                "quantity": {
+                    "description": "Quantity is the total quantity of the level, without the hidden quantity of iceberg orders.",
+                    "type": "number"
+                }
+            }
+        },
+        "fee.Fee": {
+            "type": "object",
+            "properties": {
+                "account_id": {
+                    "type": "string"
+                },
+                "amount": {
+                    "type": "number"
+                },
+                "liquidity": {
+                    "type": "integer"
+                },
+                "order_id": {
+                    "type": "string"
+                },
+                "rate": {
+                    "type": "number"
+                },
+                "symbol": {
+                    "type": "string"
+                },
+                "timestamp": {
+                    "description": "Timestamp is the unix timestamp in nanoseconds of the trade.",
+                    "type": "integer"
+                },
+                "trade_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "fee.Schedule": {
+            "type": "object",
+            "properties": {
+                "maker_rate": {
+                    "type": "number"
+                },
+                "min_fee": {
+                    "description": "MinFee is the smallest fee charged for a fill. Rebates are not raised to it.",
+                    "type": "number"
+                },
+                "symbol": {
+                    "description": "Symbol is the instrument of the schedule, empty for the instruments without their own schedule.",
+                    "type": "string"
+                },
+                "taker_rate": {
+                    "type": "number"
+                },
+                "tier": {
+                    "type": "string"
+                }
+            }
         }
     }
 }`
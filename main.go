@@ -2,10 +2,19 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"flag"
+	"fmt"
 	"log"
+	"os"
+	"strings"
+	"time"
 
 	"trading-matching-service/app"
+	"trading-matching-service/pkg/engine"
+	feesvc "trading-matching-service/pkg/service/fee"
+	instrumentsvc "trading-matching-service/pkg/service/instrument"
+	journalsvc "trading-matching-service/pkg/service/journal"
 )
 
 const (
@@ -13,15 +22,80 @@ const (
 )
 
 var (
+	symbols         string
+	instrumentsFile string
 	orderQueueSize  int
 	tradeQueueSize  int
 	cancelQueueSize int
+	statusQueueSize int
+	reportQueueSize int
+	mdQueueSize     int
+	mdLevels        int
+	streamBuffer    int
+	recentTrades    int
+	dayClose        time.Duration
+	postOnlyReprice bool
+	stpMode         string
+	marketRemainder string
+	auctions        string
+	haltCooldown    time.Duration
+	resumeAuction   time.Duration
+	haltReject      bool
+	apiKeysFile     string
+	feesFile        string
+	journalDir      string
+	journalSync     string
+	journalSyncIntv time.Duration
+	snapshotIntv    uint64
 )
 
+var stpModes = map[string]engine.SelfTradePreventionMode{
+	"none":                 engine.SelfTradePreventionNone,
+	"cancel-newest":        engine.SelfTradePreventionCancelNewest,
+	"cancel-oldest":        engine.SelfTradePreventionCancelOldest,
+	"cancel-both":          engine.SelfTradePreventionCancelBoth,
+	"decrement-and-cancel": engine.SelfTradePreventionDecrementAndCancel,
+}
+
+var journalSyncPolicies = map[string]journalsvc.SyncPolicy{
+	"always":   journalsvc.SyncPolicyAlways,
+	"interval": journalsvc.SyncPolicyInterval,
+	"none":     journalsvc.SyncPolicyNone,
+}
+
+var marketOrderPolicies = map[string]engine.MarketOrderPolicy{
+	"rest":   engine.MarketOrderPolicyRest,
+	"cancel": engine.MarketOrderPolicyCancel,
+	"limit":  engine.MarketOrderPolicyConvertToLimit,
+	"reject": engine.MarketOrderPolicyReject,
+}
+
 func init() {
+	flag.StringVar(&symbols, "symbols", "BTC-USD,ETH-USD", "comma separated list of tradable symbols, ignored if -instruments is set")
+	flag.StringVar(&instrumentsFile, "instruments", "", "path to a json file listing tradable instruments")
 	flag.IntVar(&orderQueueSize, "order-q-size", 1000000, "order queue size")
 	flag.IntVar(&tradeQueueSize, "trade-q-size", 100000, "trade queue size")
 	flag.IntVar(&cancelQueueSize, "cancel-q-size", 100000, "cancel queue size")
+	flag.IntVar(&statusQueueSize, "status-q-size", 10000, "status queue size")
+	flag.IntVar(&reportQueueSize, "report-q-size", 10000, "execution report queue size")
+	flag.IntVar(&mdQueueSize, "market-data-q-size", 100000, "market data queue size")
+	flag.IntVar(&mdLevels, "market-data-levels", 20, "number of price levels of each side of a book streamed as depth")
+	flag.IntVar(&streamBuffer, "stream-buffer", 1000, "number of market data events buffered for a stream client before it is disconnected as a slow consumer")
+	flag.IntVar(&recentTrades, "stream-recent-trades", 50, "number of recent trades sent as the snapshot of a trades subscription")
+	flag.DurationVar(&dayClose, "day-close", 0, "time of day in UTC at which day orders expire, e.g. 21h")
+	flag.BoolVar(&postOnlyReprice, "post-only-reprice", false, "reprice post only orders crossing the book instead of rejecting them")
+	flag.StringVar(&stpMode, "stp", "cancel-newest", "self trade prevention mode: none, cancel-newest, cancel-oldest, cancel-both or decrement-and-cancel")
+	flag.StringVar(&marketRemainder, "market-remainder", "cancel", "how the unfilled remainder of a market order is handled: rest, cancel, limit or reject")
+	flag.StringVar(&auctions, "auctions", "", "comma separated list of daily call auction periods in UTC, e.g. 8h-8h30m,16h25m-16h30m")
+	flag.DurationVar(&haltCooldown, "halt-cooldown", 5*time.Minute, "how long a book halted by its price band waits before resuming through an auction")
+	flag.DurationVar(&resumeAuction, "resume-auction", time.Minute, "how long the auction resuming a halted book lasts")
+	flag.BoolVar(&haltReject, "halt-reject", false, "reject new orders while a book is halted instead of collecting them for the resuming auction")
+	flag.StringVar(&feesFile, "fees", "", "path to a json file of fee schedules and account tiers, no fee is charged if not set")
+	flag.StringVar(&journalDir, "journal-dir", "", "directory of the journal and snapshots of the match engine, nothing is kept across restarts if not set")
+	flag.StringVar(&journalSync, "journal-sync", "always", "when journal writes are flushed to disk: always, interval or none")
+	flag.DurationVar(&journalSyncIntv, "journal-sync-interval", 100*time.Millisecond, "how often journal writes are flushed to disk with the interval policy")
+	flag.Uint64Var(&snapshotIntv, "snapshot-interval", 10000, "how many inbound messages apart the match engine saves snapshots, 0 for none")
+	flag.StringVar(&apiKeysFile, "api-keys", "", "path to a json file mapping api keys to account ids, requests are anonymous if not set")
 }
 
 // @title Trading Matching Service API
@@ -42,11 +116,77 @@ func init() {
 func main() {
 	flag.Parse()
 
+	instruments, err := getInstruments()
+	if err != nil {
+		panic(err.Error())
+	}
+
+	postOnlyPolicy := engine.PostOnlyPolicyReject
+	if postOnlyReprice {
+		postOnlyPolicy = engine.PostOnlyPolicyReprice
+	}
+
+	stp, ok := stpModes[stpMode]
+	if !ok {
+		panic(fmt.Sprintf("invalid self trade prevention mode: %s", stpMode))
+	}
+
+	marketOrderPolicy, ok := marketOrderPolicies[marketRemainder]
+	if !ok {
+		panic(fmt.Sprintf("invalid market order policy: %s", marketRemainder))
+	}
+
+	auctionWindows, err := getAuctionWindows()
+	if err != nil {
+		panic(err.Error())
+	}
+
+	haltOrderPolicy := engine.HaltOrderPolicyAccept
+	if haltReject {
+		haltOrderPolicy = engine.HaltOrderPolicyReject
+	}
+
+	syncPolicy, ok := journalSyncPolicies[journalSync]
+	if !ok {
+		panic(fmt.Sprintf("invalid journal sync policy: %s", journalSync))
+	}
+
+	apiKeys, err := getAPIKeys()
+	if err != nil {
+		panic(err.Error())
+	}
+
+	fees, err := getFees()
+	if err != nil {
+		panic(err.Error())
+	}
+
 	cfg := app.ApplicationConfig{
-		ServicePort:     servicePort,
-		OrderQueueSize:  orderQueueSize,
-		TradeQueueSize:  tradeQueueSize,
-		CancelQueueSize: cancelQueueSize,
+		ServicePort:         servicePort,
+		Instruments:         instruments,
+		OrderQueueSize:      orderQueueSize,
+		TradeQueueSize:      tradeQueueSize,
+		CancelQueueSize:     cancelQueueSize,
+		StatusQueueSize:     statusQueueSize,
+		ReportQueueSize:     reportQueueSize,
+		MarketDataQueueSize: mdQueueSize,
+		MarketDataLevels:    mdLevels,
+		StreamBufferSize:    streamBuffer,
+		StreamRecentTrades:  recentTrades,
+		DayClose:            dayClose,
+		PostOnlyPolicy:      postOnlyPolicy,
+		SelfTradePrevention: stp,
+		MarketOrderPolicy:   marketOrderPolicy,
+		Auctions:            auctionWindows,
+		HaltCooldown:        haltCooldown,
+		ResumeAuction:       resumeAuction,
+		HaltOrderPolicy:     haltOrderPolicy,
+		Fees:                fees,
+		JournalDir:          journalDir,
+		JournalSyncPolicy:   syncPolicy,
+		JournalSyncInterval: journalSyncIntv,
+		SnapshotInterval:    snapshotIntv,
+		APIKeys:             apiKeys,
 	}
 	application, err := app.NewApplication(cfg)
 	if err != nil {
@@ -58,3 +198,82 @@ func main() {
 		panic(err.Error())
 	}
 }
+
+func getInstruments() ([]instrumentsvc.Instrument, error) {
+	if instrumentsFile == "" {
+		instruments := []instrumentsvc.Instrument{}
+		for _, symbol := range strings.Split(symbols, ",") {
+			instruments = append(instruments, instrumentsvc.Instrument{Symbol: symbol})
+		}
+		return instruments, nil
+	}
+
+	bs, err := os.ReadFile(instrumentsFile)
+	if err != nil {
+		return nil, err
+	}
+
+	instruments := []instrumentsvc.Instrument{}
+	if err := json.Unmarshal(bs, &instruments); err != nil {
+		return nil, err
+	}
+	return instruments, nil
+}
+
+func getAPIKeys() (map[string]string, error) {
+	if apiKeysFile == "" {
+		return nil, nil
+	}
+
+	bs, err := os.ReadFile(apiKeysFile)
+	if err != nil {
+		return nil, err
+	}
+
+	apiKeys := map[string]string{}
+	if err := json.Unmarshal(bs, &apiKeys); err != nil {
+		return nil, err
+	}
+	return apiKeys, nil
+}
+
+func getFees() (feesvc.Config, error) {
+	fees := feesvc.Config{}
+	if feesFile == "" {
+		return fees, nil
+	}
+
+	bs, err := os.ReadFile(feesFile)
+	if err != nil {
+		return fees, err
+	}
+
+	if err := json.Unmarshal(bs, &fees); err != nil {
+		return fees, err
+	}
+	return fees, nil
+}
+
+func getAuctionWindows() ([]engine.AuctionWindow, error) {
+	windows := []engine.AuctionWindow{}
+	if auctions == "" {
+		return windows, nil
+	}
+
+	for _, period := range strings.Split(auctions, ",") {
+		bounds := strings.Split(period, "-")
+		if len(bounds) != 2 {
+			return nil, fmt.Errorf("invalid auction period: %s", period)
+		}
+		start, err := time.ParseDuration(bounds[0])
+		if err != nil {
+			return nil, err
+		}
+		end, err := time.ParseDuration(bounds[1])
+		if err != nil {
+			return nil, err
+		}
+		windows = append(windows, engine.AuctionWindow{Start: start, End: end})
+	}
+	return windows, nil
+}
@@ -0,0 +1,47 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+)
+
+const (
+	apiKeyHeader = "X-API-Key"
+)
+
+// Authenticator identifies the account sending a request.
+type Authenticator interface {
+	// Authenticate returns the account id of the request.
+	Authenticate(r *http.Request) (string, error)
+}
+
+type apiKeyAuthenticator struct {
+	accounts map[string]string
+}
+
+// NewAPIKeyAuthenticator returns an authenticator looking up the account by the api key header.
+func NewAPIKeyAuthenticator(accounts map[string]string) Authenticator {
+	return &apiKeyAuthenticator{
+		accounts: accounts,
+	}
+}
+
+func (a *apiKeyAuthenticator) Authenticate(r *http.Request) (string, error) {
+	aid, ok := a.accounts[r.Header.Get(apiKeyHeader)]
+	if !ok {
+		return "", errors.New("invalid api key")
+	}
+	return aid, nil
+}
+
+type anonymousAuthenticator struct {
+}
+
+// NewAnonymousAuthenticator returns an authenticator treating every request as anonymous.
+func NewAnonymousAuthenticator() Authenticator {
+	return &anonymousAuthenticator{}
+}
+
+func (a *anonymousAuthenticator) Authenticate(r *http.Request) (string, error) {
+	return "", nil
+}
@@ -0,0 +1,172 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	booksvc "trading-matching-service/pkg/service/book"
+	ordersvc "trading-matching-service/pkg/service/order"
+	"trading-matching-service/util/decimal"
+)
+
+const defaultDepthLevels = 10
+
+// priceLevelResponse model info
+type priceLevelResponse struct {
+	Price decimal.Decimal `json:"price" swaggertype:"number"`
+	// Quantity is the total quantity of the level, without the hidden quantity of iceberg orders.
+	Quantity   decimal.Decimal `json:"quantity" swaggertype:"number"`
+	OrderCount int             `json:"order_count"`
+}
+
+// getDepthResponse model info
+type getDepthResponse struct {
+	Symbol string `json:"symbol"`
+	// Sequence is the sequence number of the last inbound message the match engine handled.
+	Sequence uint64 `json:"sequence"`
+	// Bids are the buy price levels, the highest price first.
+	Bids []priceLevelResponse `json:"bids"`
+	// Asks are the sell price levels, the lowest price first.
+	Asks []priceLevelResponse `json:"asks"`
+}
+
+// bookOrderResponse model info
+type bookOrderResponse struct {
+	OrderID string `json:"order_id"`
+	// PriceType:
+	// * 1 - market price, ahead of every limit order.
+	// * 2 - limit price.
+	PriceType ordersvc.PriceType `json:"price_type"`
+	Price     decimal.Decimal    `json:"price" swaggertype:"number"`
+	// Quantity is the remaining quantity shown in book, without the hidden quantity of an iceberg order.
+	Quantity decimal.Decimal `json:"quantity" swaggertype:"number"`
+	// Timestamp ranks the orders at the same price, the earliest first.
+	Timestamp int64 `json:"timestamp"`
+}
+
+// getBookSnapshotResponse model info
+type getBookSnapshotResponse struct {
+	Symbol string `json:"symbol"`
+	// Sequence is the sequence number of the last inbound message the match engine handled.
+	Sequence uint64 `json:"sequence"`
+	// Bids are the buy orders in priority order.
+	Bids []bookOrderResponse `json:"bids"`
+	// Asks are the sell orders in priority order.
+	Asks []bookOrderResponse `json:"asks"`
+}
+
+// GetDepth returns the aggregated price levels of each side of a book.
+// @Summary GetDepth
+// @Tags Book
+// @version 1.0
+// @produce application/json
+// @param X-API-Key header string false "api key"
+// @param symbol path string true "symbol"
+// @param levels query int false "number of price levels of each side, 10 by default"
+// @Router /books/{symbol}/depth [get]
+// @Success 200 {object} getDepthResponse
+func (c *Controller) GetDepth(w http.ResponseWriter, r *http.Request) {
+	if _, err := c.authenticator.Authenticate(r); err != nil {
+		writeUnauthorizedResponse(w, err)
+		return
+	}
+
+	symbol := mux.Vars(r)["symbol"]
+	if _, err := c.instrumentStore.GetInstrument(r.Context(), symbol); err != nil {
+		writeBadRequestResponse(w, errors.New("invalid symbol"))
+		return
+	}
+
+	levels := defaultDepthLevels
+	if v := r.URL.Query().Get("levels"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			writeBadRequestResponse(w, errors.New("invalid levels"))
+			return
+		}
+		levels = n
+	}
+
+	depth, err := c.bookReader.GetDepth(r.Context(), symbol, levels)
+	if err != nil {
+		writeErrorResponse(w, err)
+		return
+	}
+
+	resp := &getDepthResponse{
+		Symbol:   depth.Symbol,
+		Sequence: depth.Sequence,
+		Bids:     toPriceLevelResponses(depth.Bids),
+		Asks:     toPriceLevelResponses(depth.Asks),
+	}
+	writeOKResponse(w, resp)
+}
+
+// GetBookSnapshot returns every order resting in a book in priority order.
+// @Summary GetBookSnapshot
+// @Tags Book
+// @version 1.0
+// @produce application/json
+// @param X-API-Key header string false "api key"
+// @param symbol path string true "symbol"
+// @Router /books/{symbol}/orders [get]
+// @Success 200 {object} getBookSnapshotResponse
+func (c *Controller) GetBookSnapshot(w http.ResponseWriter, r *http.Request) {
+	if _, err := c.authenticator.Authenticate(r); err != nil {
+		writeUnauthorizedResponse(w, err)
+		return
+	}
+
+	symbol := mux.Vars(r)["symbol"]
+	if _, err := c.instrumentStore.GetInstrument(r.Context(), symbol); err != nil {
+		writeBadRequestResponse(w, errors.New("invalid symbol"))
+		return
+	}
+
+	snap, err := c.bookReader.GetSnapshot(r.Context(), symbol)
+	if err != nil {
+		writeErrorResponse(w, err)
+		return
+	}
+
+	resp := &getBookSnapshotResponse{
+		Symbol:   snap.Symbol,
+		Sequence: snap.Sequence,
+		Bids:     toBookOrderResponses(snap.Bids),
+		Asks:     toBookOrderResponses(snap.Asks),
+	}
+	writeOKResponse(w, resp)
+}
+
+func toPriceLevelResponses(lvls []booksvc.PriceLevel) []priceLevelResponse {
+	resps := make([]priceLevelResponse, 0, len(lvls))
+	for _, lvl := range lvls {
+		resps = append(resps, toPriceLevelResponse(lvl))
+	}
+	return resps
+}
+
+func toPriceLevelResponse(lvl booksvc.PriceLevel) priceLevelResponse {
+	return priceLevelResponse{
+		Price:      lvl.Price,
+		Quantity:   lvl.Quantity,
+		OrderCount: lvl.OrderCount,
+	}
+}
+
+func toBookOrderResponses(ords []booksvc.Order) []bookOrderResponse {
+	resps := make([]bookOrderResponse, 0, len(ords))
+	for _, ord := range ords {
+		resps = append(resps, bookOrderResponse{
+			OrderID:   ord.OrderID,
+			PriceType: ord.PriceType,
+			Price:     ord.Price,
+			Quantity:  ord.Quantity,
+			Timestamp: ord.Timestamp,
+		})
+	}
+	return resps
+}
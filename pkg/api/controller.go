@@ -1,20 +1,37 @@
 package api
 
 import (
+	booksvc "trading-matching-service/pkg/service/book"
+	feesvc "trading-matching-service/pkg/service/fee"
+	instrumentsvc "trading-matching-service/pkg/service/instrument"
+	marketdatasvc "trading-matching-service/pkg/service/marketdata"
 	msgsvc "trading-matching-service/pkg/service/message"
 	ordersvc "trading-matching-service/pkg/service/order"
+	"trading-matching-service/util/clock"
 )
 
 // Controller is a controller controlling API behaviors.
 type Controller struct {
-	orderQ     msgsvc.Queue
-	orderStore ordersvc.Store
+	orderQ          msgsvc.Queue
+	orderStore      ordersvc.Store
+	instrumentStore instrumentsvc.Store
+	feeStore        feesvc.Store
+	bookReader      booksvc.Reader
+	marketData      marketdatasvc.Hub
+	authenticator   Authenticator
+	clock           clock.Clock
 }
 
 // NewController creates a controller.
-func NewController(orderQ msgsvc.Queue, pool ordersvc.Store) *Controller {
+func NewController(orderQ msgsvc.Queue, pool ordersvc.Store, instrumentStore instrumentsvc.Store, feeStore feesvc.Store, bookReader booksvc.Reader, marketData marketdatasvc.Hub, authenticator Authenticator, clk clock.Clock) *Controller {
 	return &Controller{
-		orderQ:     orderQ,
-		orderStore: pool,
+		orderQ:          orderQ,
+		orderStore:      pool,
+		instrumentStore: instrumentStore,
+		feeStore:        feeStore,
+		bookReader:      bookReader,
+		marketData:      marketData,
+		authenticator:   authenticator,
+		clock:           clk,
 	}
 }
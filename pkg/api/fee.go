@@ -0,0 +1,80 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	feesvc "trading-matching-service/pkg/service/fee"
+	"trading-matching-service/util/decimal"
+)
+
+// getFeesResponse model info
+type getFeesResponse struct {
+	AccountID string `json:"account_id"`
+	// Tier is the fee tier of the account.
+	Tier string `json:"tier"`
+	// Schedules are the fee schedules of the tier. Rates are fractions of the traded notional,
+	// and a negative rate is a rebate.
+	Schedules []feesvc.Schedule `json:"schedules"`
+	// Total is the sum of the fees paid over the period, negative for a net rebate.
+	Total decimal.Decimal `json:"total" swaggertype:"number"`
+	// Fees are the fees paid over the period. Liquidity is 1 for maker, 2 for taker and 3 for
+	// auction fills.
+	Fees []feesvc.Fee `json:"fees"`
+}
+
+// GetFees returns the fee tier of the account and the fees it paid over a period. Anonymous
+// requests are unauthorized, as their fees would be those of every anonymous trader.
+// @Summary GetFees
+// @Tags Fee
+// @version 1.0
+// @produce application/json
+// @param X-API-Key header string false "api key"
+// @param from query int false "start of the period in unix nanoseconds, inclusive"
+// @param to query int false "end of the period in unix nanoseconds, exclusive, now by default"
+// @Router /fees [get]
+// @Success 200 {object} getFeesResponse
+func (c *Controller) GetFees(w http.ResponseWriter, r *http.Request) {
+	aid, err := c.authenticator.Authenticate(r)
+	if err != nil {
+		writeUnauthorizedResponse(w, err)
+		return
+	}
+	if aid == "" {
+		writeUnauthorizedResponse(w, errors.New("fees require an api key"))
+		return
+	}
+
+	from, to := int64(0), c.clock.Now().UnixNano()
+	if v := r.URL.Query().Get("from"); v != "" {
+		if from, err = strconv.ParseInt(v, 10, 64); err != nil {
+			writeBadRequestResponse(w, errors.New("invalid from"))
+			return
+		}
+	}
+	if v := r.URL.Query().Get("to"); v != "" {
+		if to, err = strconv.ParseInt(v, 10, 64); err != nil {
+			writeBadRequestResponse(w, errors.New("invalid to"))
+			return
+		}
+	}
+
+	fees, err := c.feeStore.ListFees(r.Context(), aid, from, to)
+	if err != nil {
+		writeErrorResponse(w, err)
+		return
+	}
+
+	tier := c.feeStore.GetTier(r.Context(), aid)
+	resp := &getFeesResponse{
+		AccountID: aid,
+		Tier:      tier,
+		Schedules: c.feeStore.ListSchedules(r.Context(), tier),
+		Fees:      fees,
+	}
+	for _, fee := range fees {
+		resp.Total = resp.Total.Add(fee.Amount)
+	}
+	writeOKResponse(w, resp)
+}
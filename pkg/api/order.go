@@ -1,20 +1,25 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"net/http"
-	"time"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 
+	cancelsvc "trading-matching-service/pkg/service/cancel"
+	instrumentsvc "trading-matching-service/pkg/service/instrument"
 	msgsvc "trading-matching-service/pkg/service/message"
 	ordersvc "trading-matching-service/pkg/service/order"
+	"trading-matching-service/util/decimal"
 )
 
 // placeOrderRequest model info
 type placeOrderRequest struct {
+	// Symbol is the instrument to trade, e.g. BTC-USD.
+	Symbol string `json:"symbol"`
 	// OrderKind:
 	// * 1 - buy order.
 	// * 2 - sell order.
@@ -22,9 +27,44 @@ type placeOrderRequest struct {
 	// PriceType:
 	// * 1 - market price.
 	// * 2 - limit price.
+	// * 3 - stop, a market order placed when the last trade price reaches trigger_price.
+	// * 4 - stop limit, a limit order placed when the last trade price reaches trigger_price.
+	// * 5 - trailing stop, a stop order whose trigger price trails the best price seen since entry.
+	// * 6 - trailing stop limit, a trailing stop order placing a limit order at the trigger price moved by limit_offset.
 	PriceType ordersvc.PriceType `json:"price_type"`
-	Price     float64            `json:"price"`
-	Quantity  int                `json:"quantity"`
+	// Price must be a multiple of the tick size of the instrument.
+	Price decimal.Decimal `json:"price" swaggertype:"number"`
+	// TriggerPrice is required by stop and stop limit orders.
+	TriggerPrice decimal.Decimal `json:"trigger_price" swaggertype:"number"`
+	// TrailAmount is the distance of the trigger price of a trailing stop order from the best price seen.
+	TrailAmount decimal.Decimal `json:"trail_amount" swaggertype:"number"`
+	// TrailPercent is the distance of the trigger price of a trailing stop order from the best price seen in percent.
+	TrailPercent decimal.Decimal `json:"trail_percent" swaggertype:"number"`
+	// LimitOffset moves the limit price of a triggered trailing stop limit order from its trigger price, in its favor.
+	LimitOffset decimal.Decimal `json:"limit_offset" swaggertype:"number"`
+	// ProtectionPrice is the worst price a market or stop order accepts to trade at, 0 for no limit.
+	ProtectionPrice decimal.Decimal `json:"protection_price" swaggertype:"number"`
+	// Quantity must be a multiple of the lot size of the instrument, which may be fractional.
+	Quantity decimal.Decimal `json:"quantity" swaggertype:"number"`
+	// DisplayQuantity makes an iceberg order showing only this quantity in book, 0 shows the whole order.
+	DisplayQuantity decimal.Decimal `json:"display_quantity" swaggertype:"number"`
+	// MinQuantity is the smallest quantity the order accepts to fill on arrival, and in each fill
+	// while it rests in book. An order that can not fill it on arrival does not trade at all.
+	MinQuantity decimal.Decimal `json:"min_quantity" swaggertype:"number"`
+	// AllOrNone makes the order only fill its whole remaining quantity at once.
+	AllOrNone bool `json:"all_or_none"`
+	// PostOnly makes sure a limit order only adds liquidity to book. If it would match on arrival,
+	// it is rejected or repriced depending on server config.
+	PostOnly bool `json:"post_only"`
+	// TimeInForce:
+	// * 1 - good till canceled (default).
+	// * 2 - immediate or cancel.
+	// * 3 - fill or kill.
+	// * 4 - good till date, requires expire_at.
+	// * 5 - day.
+	TimeInForce ordersvc.TimeInForce `json:"time_in_force"`
+	// ExpireAt is the unix timestamp in nanoseconds at which a good till date order expires.
+	ExpireAt int64 `json:"expire_at"`
 }
 
 // placeOrderResponse model info
@@ -38,10 +78,17 @@ type placeOrderResponse struct {
 // @version 1.0
 // @produce application/json
 // @accept application/json
+// @param X-API-Key header string false "api key"
 // @param Body body placeOrderRequest true "Body"
 // @Router /orders [post]
 // @Success 200 {object} placeOrderResponse
 func (c *Controller) PlaceOrder(w http.ResponseWriter, r *http.Request) {
+	aid, err := c.authenticator.Authenticate(r)
+	if err != nil {
+		writeUnauthorizedResponse(w, err)
+		return
+	}
+
 	req := &placeOrderRequest{}
 	decoder := json.NewDecoder(r.Body)
 	if err := decoder.Decode(req); err != nil {
@@ -49,20 +96,13 @@ func (c *Controller) PlaceOrder(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := c.checkPlaceOrderRequest(req); err != nil {
+	if err := c.checkPlaceOrderRequest(r.Context(), req); err != nil {
 		writeBadRequestResponse(w, err)
 		return
 	}
 
 	// push a buy/sell order to order queue
-	ord := ordersvc.Order{
-		ID:        uuid.NewString(),
-		Kind:      ordersvc.OrderKind(req.OrderKind),
-		PriceType: ordersvc.PriceType(req.PriceType),
-		Price:     req.Price,
-		Quantity:  req.Quantity,
-		CreatedAt: time.Now().UnixNano(),
-	}
+	ord := c.newOrder(aid, req)
 
 	if _, err := c.orderStore.CreateOrder(r.Context(), ord); err != nil {
 		writeErrorResponse(w, err)
@@ -81,36 +121,319 @@ func (c *Controller) PlaceOrder(w http.ResponseWriter, r *http.Request) {
 	writeOKResponse(w, resp)
 }
 
-func (c *Controller) checkPlaceOrderRequest(req *placeOrderRequest) error {
+// newOrder returns a new order of the account from a checked request.
+func (c *Controller) newOrder(aid string, req *placeOrderRequest) ordersvc.Order {
+	return ordersvc.Order{
+		ID:              uuid.NewString(),
+		AccountID:       aid,
+		Symbol:          req.Symbol,
+		Kind:            ordersvc.OrderKind(req.OrderKind),
+		PriceType:       ordersvc.PriceType(req.PriceType),
+		Price:           req.Price,
+		TriggerPrice:    req.TriggerPrice,
+		TrailAmount:     req.TrailAmount,
+		TrailPercent:    req.TrailPercent,
+		LimitOffset:     req.LimitOffset,
+		ProtectionPrice: req.ProtectionPrice,
+		Quantity:        req.Quantity,
+		DisplayQuantity: req.DisplayQuantity,
+		MinQuantity:     req.MinQuantity,
+		AllOrNone:       req.AllOrNone,
+		PostOnly:        req.PostOnly,
+		TimeInForce:     req.TimeInForce,
+		ExpireAt:        req.ExpireAt,
+		CreatedAt:       c.clock.Now().UnixNano(),
+	}
+}
+
+func (c *Controller) checkPlaceOrderRequest(ctx context.Context, req *placeOrderRequest) error {
+	inst, err := c.instrumentStore.GetInstrument(ctx, req.Symbol)
+	if err != nil {
+		return errors.New("invalid symbol")
+	}
+
 	if req.OrderKind != ordersvc.OrderKindBuy && req.OrderKind != ordersvc.OrderKindSell {
 		return errors.New("invalid order kind")
 	}
 
-	if req.Quantity == 0 {
+	if req.Quantity.Sign() <= 0 {
 		return errors.New("invalid quantity")
 	}
 
-	if req.PriceType != ordersvc.PriceTypeLimit && req.PriceType != ordersvc.PriceTypeMarket {
+	if !req.Quantity.IsMultipleOf(inst.QuantityStep()) || !req.DisplayQuantity.IsMultipleOf(inst.QuantityStep()) {
+		return errors.New("quantity is not a multiple of lot size")
+	}
+
+	if req.Price.Sign() < 0 || !req.Price.IsMultipleOf(inst.TickSize) || !req.TriggerPrice.IsMultipleOf(inst.TickSize) {
+		return errors.New("price is not a multiple of tick size")
+	}
+
+	switch req.PriceType {
+	case ordersvc.PriceTypeMarket, ordersvc.PriceTypeLimit, ordersvc.PriceTypeStop, ordersvc.PriceTypeStopLimit,
+		ordersvc.PriceTypeTrailingStop, ordersvc.PriceTypeTrailingStopLimit:
+	default:
 		return errors.New("invalid price type")
 	}
 
-	if ordersvc.PriceType(req.PriceType) != ordersvc.PriceTypeMarket && req.Price == 0 {
+	if err := checkTrailingStop(inst, req); err != nil {
+		return err
+	}
+
+	if (req.PriceType == ordersvc.PriceTypeLimit || req.PriceType == ordersvc.PriceTypeStopLimit) && req.Price.IsZero() {
 		return errors.New("invalid limit price")
 	}
 
+	if (req.PriceType == ordersvc.PriceTypeStop || req.PriceType == ordersvc.PriceTypeStopLimit) && req.TriggerPrice.Sign() <= 0 {
+		return errors.New("invalid trigger price")
+	}
+
+	if req.ProtectionPrice.Sign() < 0 || !req.ProtectionPrice.IsMultipleOf(inst.TickSize) {
+		return errors.New("invalid protection price")
+	}
+
+	if !req.ProtectionPrice.IsZero() && req.PriceType != ordersvc.PriceTypeMarket && req.PriceType != ordersvc.PriceTypeStop && req.PriceType != ordersvc.PriceTypeTrailingStop {
+		return errors.New("protection price is only valid for market orders")
+	}
+
+	if req.DisplayQuantity.Sign() < 0 || req.DisplayQuantity.GreaterThan(req.Quantity) {
+		return errors.New("invalid display quantity")
+	}
+
+	if req.DisplayQuantity.Sign() > 0 && req.PriceType != ordersvc.PriceTypeLimit && req.PriceType != ordersvc.PriceTypeStopLimit && req.PriceType != ordersvc.PriceTypeTrailingStopLimit {
+		return errors.New("display quantity is only valid for limit orders")
+	}
+
+	if err := checkFillConstraint(inst, req); err != nil {
+		return err
+	}
+
+	if err := checkOrderSize(req.Quantity, req.Price, req.TriggerPrice, req.ProtectionPrice, req.TrailAmount, req.LimitOffset); err != nil {
+		return err
+	}
+
+	if req.TimeInForce == ordersvc.TimeInForceNone {
+		req.TimeInForce = ordersvc.TimeInForceGTC
+	}
+
+	if req.PostOnly {
+		if req.PriceType != ordersvc.PriceTypeLimit && req.PriceType != ordersvc.PriceTypeStopLimit && req.PriceType != ordersvc.PriceTypeTrailingStopLimit {
+			return errors.New("post only is only valid for limit orders")
+		}
+		if req.TimeInForce == ordersvc.TimeInForceIOC || req.TimeInForce == ordersvc.TimeInForceFOK {
+			return errors.New("post only is not valid for immediate orders")
+		}
+	}
+
+	switch req.TimeInForce {
+	case ordersvc.TimeInForceGTC, ordersvc.TimeInForceIOC, ordersvc.TimeInForceFOK, ordersvc.TimeInForceDay:
+		if req.ExpireAt != 0 {
+			return errors.New("expire_at is only valid for good till date orders")
+		}
+	case ordersvc.TimeInForceGTD:
+		if req.ExpireAt <= c.clock.Now().UnixNano() {
+			return errors.New("invalid expire_at")
+		}
+	default:
+		return errors.New("invalid time in force")
+	}
+
 	return nil
 }
 
-// CancelOrder cancels an order.
+// checkTrailingStop checks the trail of a trailing stop order, and that other orders have none.
+func checkTrailingStop(inst instrumentsvc.Instrument, req *placeOrderRequest) error {
+	isTrailingStop := req.PriceType == ordersvc.PriceTypeTrailingStop || req.PriceType == ordersvc.PriceTypeTrailingStopLimit
+	if !isTrailingStop {
+		if !req.TrailAmount.IsZero() || !req.TrailPercent.IsZero() || !req.LimitOffset.IsZero() {
+			return errors.New("trail is only valid for trailing stop orders")
+		}
+		return nil
+	}
+
+	if !req.Price.IsZero() || !req.TriggerPrice.IsZero() {
+		return errors.New("trailing stop orders take no price or trigger price")
+	}
+
+	if req.TrailAmount.IsZero() == req.TrailPercent.IsZero() {
+		return errors.New("either trail_amount or trail_percent is required")
+	}
+
+	if req.TrailAmount.Sign() < 0 || !req.TrailAmount.IsMultipleOf(inst.TickSize) {
+		return errors.New("invalid trail amount")
+	}
+
+	if req.TrailPercent.Sign() < 0 || !req.TrailPercent.LessThan(decimal.New(100)) {
+		return errors.New("invalid trail percent")
+	}
+
+	if req.LimitOffset.Sign() < 0 || !req.LimitOffset.IsMultipleOf(inst.TickSize) {
+		return errors.New("invalid limit offset")
+	}
+
+	if !req.LimitOffset.IsZero() && req.PriceType != ordersvc.PriceTypeTrailingStopLimit {
+		return errors.New("limit offset is only valid for trailing stop limit orders")
+	}
+
+	return nil
+}
+
+// checkFillConstraint checks the minimum quantity and the all or none flag of an order.
+func checkFillConstraint(inst instrumentsvc.Instrument, req *placeOrderRequest) error {
+	if req.MinQuantity.Sign() < 0 || req.MinQuantity.GreaterThan(req.Quantity) || !req.MinQuantity.IsMultipleOf(inst.QuantityStep()) {
+		return errors.New("invalid min quantity")
+	}
+
+	if req.AllOrNone && !req.MinQuantity.IsZero() {
+		return errors.New("min quantity is not valid for all or none orders")
+	}
+
+	// the visible slice of an iceberg order is all that can be filled at once
+	if req.DisplayQuantity.Sign() > 0 && (req.AllOrNone || req.MinQuantity.GreaterThan(req.DisplayQuantity)) {
+		return errors.New("fill constraint exceeds display quantity")
+	}
+
+	return nil
+}
+
+var (
+	// maxQuantity, maxPrice and maxNotional keep orders far enough from the range of decimals,
+	// so the notionals and the fees of their fills can not overflow.
+	maxQuantity = decimal.New(1000000000)
+	maxPrice    = decimal.New(1000000000)
+	maxNotional = decimal.New(10000000000)
+)
+
+// checkOrderSize checks the quantity and the prices of an order, and its notional at the highest
+// of the prices.
+func checkOrderSize(qty decimal.Decimal, prices ...decimal.Decimal) error {
+	if qty.GreaterThan(maxQuantity) {
+		return errors.New("quantity is too large")
+	}
+
+	highest := decimal.Zero
+	for _, price := range prices {
+		highest = decimal.Max(highest, price)
+	}
+	if highest.GreaterThan(maxPrice) {
+		return errors.New("price is too large")
+	}
+
+	if highest.Mul(qty).GreaterThan(maxNotional) {
+		return errors.New("notional is too large")
+	}
+
+	return nil
+}
+
+// getOrderResponse model info
+type getOrderResponse struct {
+	OrderID      string               `json:"order_id"`
+	GroupID      string               `json:"group_id,omitempty"`
+	Symbol       string               `json:"symbol"`
+	OrderKind    ordersvc.OrderKind   `json:"order_kind"`
+	PriceType    ordersvc.PriceType   `json:"price_type"`
+	Price        decimal.Decimal      `json:"price" swaggertype:"number"`
+	TriggerPrice decimal.Decimal      `json:"trigger_price" swaggertype:"number"`
+	Quantity     decimal.Decimal      `json:"quantity" swaggertype:"number"`
+	TimeInForce  ordersvc.TimeInForce `json:"time_in_force"`
+	ExpireAt     int64                `json:"expire_at"`
+	// Status:
+	// * 1 - pending, accepted but not yet confirmed by the match engine.
+	// * 2 - new, in book without any fill.
+	// * 3 - partially filled, in book with some fills.
+	// * 4 - filled.
+	// * 5 - canceled.
+	// * 6 - rejected by the match engine without any fill.
+	// * 7 - expired.
+	Status            ordersvc.OrderStatus `json:"status"`
+	FilledQuantity    decimal.Decimal      `json:"filled_quantity" swaggertype:"number"`
+	RemainingQuantity decimal.Decimal      `json:"remaining_quantity" swaggertype:"number"`
+	AverageFillPrice  decimal.Decimal      `json:"average_fill_price" swaggertype:"number"`
+	// CancelRejectReason is why the last cancel request of the order was rejected:
+	// * 0 - none.
+	// * 1 - too late to cancel, the order was already filled or closed.
+	// * 2 - unknown order.
+	CancelRejectReason cancelsvc.CancelRejectReason `json:"cancel_reject_reason"`
+	// AmendRejectReason is why the last amend request of the order was rejected, with the same
+	// values as cancel_reject_reason.
+	AmendRejectReason cancelsvc.CancelRejectReason `json:"amend_reject_reason"`
+	// CreatedAt, ConfirmedAt and UpdatedAt are unix timestamps in nanoseconds.
+	CreatedAt   int64 `json:"created_at"`
+	ConfirmedAt int64 `json:"confirmed_at"`
+	UpdatedAt   int64 `json:"updated_at"`
+}
+
+// GetOrder returns an order with its lifecycle status.
+// @Summary GetOrder
+// @Tags Order
+// @version 1.0
+// @produce application/json
+// @param X-API-Key header string false "api key"
+// @param oid path string true "oid"
+// @Router /orders/{oid} [get]
+// @Success 200 {object} getOrderResponse
+func (c *Controller) GetOrder(w http.ResponseWriter, r *http.Request) {
+	aid, err := c.authenticator.Authenticate(r)
+	if err != nil {
+		writeUnauthorizedResponse(w, err)
+		return
+	}
+
+	oid := mux.Vars(r)["oid"]
+
+	if oid == "" {
+		writeBadRequestResponse(w, errors.New("empty order id"))
+		return
+	}
+
+	ord, err := c.orderStore.GetOrder(r.Context(), oid)
+	if err != nil || ord.AccountID != aid {
+		writeBadRequestResponse(w, errors.New("invalid order id"))
+		return
+	}
+
+	resp := &getOrderResponse{
+		OrderID:            ord.ID,
+		GroupID:            ord.GroupID,
+		Symbol:             ord.Symbol,
+		OrderKind:          ord.Kind,
+		PriceType:          ord.PriceType,
+		Price:              ord.Price,
+		TriggerPrice:       ord.TriggerPrice,
+		Quantity:           ord.Quantity,
+		TimeInForce:        ord.TimeInForce,
+		ExpireAt:           ord.ExpireAt,
+		Status:             ord.Status,
+		FilledQuantity:     ord.FilledQuantity,
+		RemainingQuantity:  ord.RemainingQuantity,
+		AverageFillPrice:   ord.AverageFillPrice,
+		CancelRejectReason: ord.CancelRejectReason,
+		AmendRejectReason:  ord.AmendRejectReason,
+		CreatedAt:          ord.CreatedAt,
+		ConfirmedAt:        ord.ConfirmedAt,
+		UpdatedAt:          ord.UpdatedAt,
+	}
+	writeOKResponse(w, resp)
+}
+
+// CancelOrder requests to cancel an order. The match engine decides whether the order can still be
+// canceled, and a rejected request shows up in the cancel_reject_reason of the order.
 // @Summary CancelOrder
 // @Tags Order
 // @version 1.0
 // @produce application/json
 // @accept application/json
+// @param X-API-Key header string false "api key"
 // @param oid path string true "oid"
 // @Router /orders/{oid} [delete]
 // @Success 200 {object} GeneralResponse
 func (c *Controller) CancelOrder(w http.ResponseWriter, r *http.Request) {
+	aid, err := c.authenticator.Authenticate(r)
+	if err != nil {
+		writeUnauthorizedResponse(w, err)
+		return
+	}
+
 	oid := mux.Vars(r)["oid"]
 
 	if oid == "" {
@@ -119,7 +442,7 @@ func (c *Controller) CancelOrder(w http.ResponseWriter, r *http.Request) {
 	}
 
 	ord, err := c.orderStore.GetOrder(r.Context(), oid)
-	if err != nil {
+	if err != nil || ord.AccountID != aid {
 		writeBadRequestResponse(w, errors.New("invalid order id"))
 		return
 	}
@@ -127,8 +450,9 @@ func (c *Controller) CancelOrder(w http.ResponseWriter, r *http.Request) {
 	// push a cancel order to order queue
 	cancel := ordersvc.Cancel{
 		OrderID:   oid,
+		Symbol:    ord.Symbol,
 		OrderKind: ord.Kind,
-		CreatedAt: time.Now().Unix(),
+		CreatedAt: c.clock.Now().UnixNano(),
 	}
 
 	msg := msgsvc.NewMessage(msgsvc.MessageKindOrderCancel, &cancel)
@@ -139,3 +463,131 @@ func (c *Controller) CancelOrder(w http.ResponseWriter, r *http.Request) {
 
 	writeSuccessResponse(w)
 }
+
+// amendOrderRequest model info
+type amendOrderRequest struct {
+	// Price is the new limit price, 0 keeps the current one.
+	Price decimal.Decimal `json:"price" swaggertype:"number"`
+	// TriggerPrice is the new trigger price of a stop order, 0 keeps the current one.
+	TriggerPrice decimal.Decimal `json:"trigger_price" swaggertype:"number"`
+	// Quantity is the new unfilled quantity, 0 keeps the current one.
+	Quantity decimal.Decimal `json:"quantity" swaggertype:"number"`
+}
+
+// AmendOrder amends the price or the quantity of an order.
+// @Summary AmendOrder
+// @Tags Order
+// @version 1.0
+// @produce application/json
+// @accept application/json
+// @param X-API-Key header string false "api key"
+// @param oid path string true "oid"
+// @param Body body amendOrderRequest true "Body"
+// @Router /orders/{oid} [patch]
+// @Success 200 {object} GeneralResponse
+func (c *Controller) AmendOrder(w http.ResponseWriter, r *http.Request) {
+	aid, err := c.authenticator.Authenticate(r)
+	if err != nil {
+		writeUnauthorizedResponse(w, err)
+		return
+	}
+
+	oid := mux.Vars(r)["oid"]
+
+	if oid == "" {
+		writeBadRequestResponse(w, errors.New("empty order id"))
+		return
+	}
+
+	req := &amendOrderRequest{}
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(req); err != nil {
+		writeErrorResponse(w, err)
+		return
+	}
+
+	ord, err := c.orderStore.GetOrder(r.Context(), oid)
+	if err != nil || ord.AccountID != aid {
+		writeBadRequestResponse(w, errors.New("invalid order id"))
+		return
+	}
+
+	if err := c.checkAmendOrderRequest(r.Context(), &ord, req); err != nil {
+		writeBadRequestResponse(w, err)
+		return
+	}
+
+	// push an amend order to order queue
+	amend := ordersvc.Amend{
+		OrderID:      oid,
+		Symbol:       ord.Symbol,
+		OrderKind:    ord.Kind,
+		Price:        req.Price,
+		TriggerPrice: req.TriggerPrice,
+		Quantity:     req.Quantity,
+		CreatedAt:    c.clock.Now().UnixNano(),
+	}
+
+	msg := msgsvc.NewMessage(msgsvc.MessageKindOrderAmend, &amend)
+	if err := c.orderQ.Push(r.Context(), msg); err != nil {
+		writeErrorResponse(w, err)
+		return
+	}
+
+	writeSuccessResponse(w)
+}
+
+func (c *Controller) checkAmendOrderRequest(ctx context.Context, ord *ordersvc.Order, req *amendOrderRequest) error {
+	if req.Price.Sign() < 0 || req.TriggerPrice.Sign() < 0 || req.Quantity.Sign() < 0 {
+		return errors.New("invalid amend")
+	}
+
+	if req.Price.IsZero() && req.TriggerPrice.IsZero() && req.Quantity.IsZero() {
+		return errors.New("nothing to amend")
+	}
+
+	inst, err := c.instrumentStore.GetInstrument(ctx, ord.Symbol)
+	if err != nil {
+		return errors.New("invalid symbol")
+	}
+
+	if !req.Quantity.IsMultipleOf(inst.QuantityStep()) {
+		return errors.New("quantity is not a multiple of lot size")
+	}
+
+	if !req.Quantity.IsZero() && req.Quantity.LessThan(ord.MinQuantity) {
+		return errors.New("quantity is less than min quantity")
+	}
+
+	if !req.Quantity.IsZero() && req.Quantity.LessThan(ord.DisplayQuantity) {
+		return errors.New("quantity is less than display quantity")
+	}
+
+	price, triggerPrice, qty := ord.Price, ord.TriggerPrice, ord.RemainingQuantity
+	if !req.Price.IsZero() {
+		price = req.Price
+	}
+	if !req.TriggerPrice.IsZero() {
+		triggerPrice = req.TriggerPrice
+	}
+	if !req.Quantity.IsZero() {
+		qty = req.Quantity
+	}
+	if err := checkOrderSize(ord.FilledQuantity.Add(qty), price, triggerPrice, ord.ProtectionPrice); err != nil {
+		return err
+	}
+
+	if !req.Price.IsMultipleOf(inst.TickSize) || !req.TriggerPrice.IsMultipleOf(inst.TickSize) {
+		return errors.New("price is not a multiple of tick size")
+	}
+
+	if !req.Price.IsZero() && ord.PriceType != ordersvc.PriceTypeLimit && ord.PriceType != ordersvc.PriceTypeStopLimit {
+		return errors.New("price is only amendable for limit orders")
+	}
+
+	if !req.TriggerPrice.IsZero() && (!ord.IsStop() || ord.IsTrailingStop()) {
+		return errors.New("trigger price is only amendable for stop orders")
+	}
+
+	return nil
+}
@@ -0,0 +1,158 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+
+	msgsvc "trading-matching-service/pkg/service/message"
+	ordersvc "trading-matching-service/pkg/service/order"
+)
+
+// placeOrderGroupRequest model info
+type placeOrderGroupRequest struct {
+	// Symbol is the instrument of every order of the group, e.g. BTC-USD.
+	Symbol string `json:"symbol"`
+	// GroupKind:
+	// * 1 - one cancels other, a fill or a cancel of one order cancels the others.
+	// * 2 - bracket, an entry order followed by a take profit limit order and a stop loss stop order,
+	// which are placed as a one cancels other group once the entry order is filled.
+	GroupKind ordersvc.OrderGroupKind `json:"group_kind"`
+	// Orders of the group. The orders of a bracket are the entry, the take profit and the stop
+	// loss orders in that order.
+	Orders []placeOrderRequest `json:"orders"`
+}
+
+// placeOrderGroupResponse model info
+type placeOrderGroupResponse struct {
+	GroupID  string   `json:"group_id"`
+	OrderIDs []string `json:"order_ids"`
+}
+
+// PlaceOrderGroup places a group of linked orders.
+// @Summary PlaceOrderGroup
+// @Tags Order
+// @version 1.0
+// @produce application/json
+// @accept application/json
+// @param X-API-Key header string false "api key"
+// @param Body body placeOrderGroupRequest true "Body"
+// @Router /order-groups [post]
+// @Success 200 {object} placeOrderGroupResponse
+func (c *Controller) PlaceOrderGroup(w http.ResponseWriter, r *http.Request) {
+	aid, err := c.authenticator.Authenticate(r)
+	if err != nil {
+		writeUnauthorizedResponse(w, err)
+		return
+	}
+
+	req := &placeOrderGroupRequest{}
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(req); err != nil {
+		writeErrorResponse(w, err)
+		return
+	}
+
+	if err := c.checkPlaceOrderGroupRequest(r.Context(), req); err != nil {
+		writeBadRequestResponse(w, err)
+		return
+	}
+
+	// push the orders to order queue at once, so the engine links them before any of them trades
+	grp := ordersvc.OrderGroup{
+		ID:        uuid.NewString(),
+		Symbol:    req.Symbol,
+		Kind:      req.GroupKind,
+		CreatedAt: c.clock.Now().UnixNano(),
+	}
+	resp := &placeOrderGroupResponse{
+		GroupID: grp.ID,
+	}
+	for i := range req.Orders {
+		ord := c.newOrder(aid, &req.Orders[i])
+		ord.GroupID = grp.ID
+		if _, err := c.orderStore.CreateOrder(r.Context(), ord); err != nil {
+			writeErrorResponse(w, err)
+			return
+		}
+		grp.Orders = append(grp.Orders, ord)
+		resp.OrderIDs = append(resp.OrderIDs, ord.ID)
+	}
+
+	msg := msgsvc.NewMessage(msgsvc.MessageKindOrderGroupCreate, &grp)
+	if err := c.orderQ.Push(r.Context(), msg); err != nil {
+		writeErrorResponse(w, err)
+		return
+	}
+
+	writeOKResponse(w, resp)
+}
+
+func (c *Controller) checkPlaceOrderGroupRequest(ctx context.Context, req *placeOrderGroupRequest) error {
+	switch req.GroupKind {
+	case ordersvc.OrderGroupKindOCO:
+		if len(req.Orders) < 2 {
+			return errors.New("one cancels other group needs at least 2 orders")
+		}
+	case ordersvc.OrderGroupKindBracket:
+		if len(req.Orders) != 3 {
+			return errors.New("bracket needs an entry, a take profit and a stop loss order")
+		}
+	default:
+		return errors.New("invalid group kind")
+	}
+
+	for i := range req.Orders {
+		ord := &req.Orders[i]
+		if ord.Symbol != "" && ord.Symbol != req.Symbol {
+			return fmt.Errorf("order %d: symbol differs from group", i+1)
+		}
+		ord.Symbol = req.Symbol
+		if err := c.checkPlaceOrderRequest(ctx, ord); err != nil {
+			return fmt.Errorf("order %d: %w", i+1, err)
+		}
+	}
+
+	// linked orders wait for each other, so none of them may be canceled right after its arrival
+	linked := req.Orders
+	if req.GroupKind == ordersvc.OrderGroupKindBracket {
+		linked = req.Orders[1:]
+	}
+	for _, ord := range linked {
+		if ord.TimeInForce == ordersvc.TimeInForceIOC || ord.TimeInForce == ordersvc.TimeInForceFOK {
+			return errors.New("linked orders can not be immediate orders")
+		}
+	}
+
+	if req.GroupKind == ordersvc.OrderGroupKindBracket {
+		return checkBracket(req.Orders[0], req.Orders[1], req.Orders[2])
+	}
+	return nil
+}
+
+// checkBracket checks that the exit orders of a bracket close the position opened by its entry order.
+func checkBracket(entry, takeProfit, stopLoss placeOrderRequest) error {
+	if takeProfit.OrderKind == entry.OrderKind || stopLoss.OrderKind == entry.OrderKind {
+		return errors.New("exit orders must be on the opposite side of the entry order")
+	}
+
+	if takeProfit.PriceType != ordersvc.PriceTypeLimit {
+		return errors.New("take profit must be a limit order")
+	}
+
+	switch stopLoss.PriceType {
+	case ordersvc.PriceTypeStop, ordersvc.PriceTypeStopLimit, ordersvc.PriceTypeTrailingStop, ordersvc.PriceTypeTrailingStopLimit:
+	default:
+		return errors.New("stop loss must be a stop order")
+	}
+
+	if takeProfit.Quantity != entry.Quantity || stopLoss.Quantity != entry.Quantity {
+		return errors.New("exit orders must have the quantity of the entry order")
+	}
+
+	return nil
+}
@@ -0,0 +1,238 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	marketdatasvc "trading-matching-service/pkg/service/marketdata"
+	ordersvc "trading-matching-service/pkg/service/order"
+	"trading-matching-service/util/decimal"
+)
+
+const (
+	streamOpSubscribe   = "subscribe"
+	streamOpUnsubscribe = "unsubscribe"
+
+	streamWriteWait      = 10 * time.Second
+	streamPongWait       = 60 * time.Second
+	streamPingInterval   = streamPongWait * 9 / 10
+	streamMaxRequestSize = 1024
+)
+
+var streamUpgrader = websocket.Upgrader{}
+
+// streamRequest model info
+type streamRequest struct {
+	// Op is subscribe or unsubscribe.
+	Op string `json:"op"`
+	// Channel is trades, depth or ticker.
+	Channel marketdatasvc.Channel `json:"channel"`
+	Symbol  string                `json:"symbol"`
+}
+
+// marketTradeResponse model info
+type marketTradeResponse struct {
+	ID string `json:"id"`
+	// Sequence numbers the trades of a book in order, starting at 1.
+	Sequence uint64 `json:"sequence"`
+	// AggressorSide:
+	// * 0 - none, a trade of an auction uncross.
+	// * 1 - buy order.
+	// * 2 - sell order.
+	AggressorSide ordersvc.OrderKind `json:"aggressor_side"`
+	Price         decimal.Decimal    `json:"price" swaggertype:"number"`
+	Quantity      decimal.Decimal    `json:"quantity" swaggertype:"number"`
+	Timestamp     int64              `json:"timestamp"`
+}
+
+// tickerResponse model info
+type tickerResponse struct {
+	LastPrice decimal.Decimal `json:"last_price" swaggertype:"number"`
+	// BestBid and BestAsk have 0 quantity if the side has no limit order.
+	BestBid priceLevelResponse `json:"best_bid"`
+	BestAsk priceLevelResponse `json:"best_ask"`
+}
+
+// marketDataEventResponse model info
+type marketDataEventResponse struct {
+	Channel marketdatasvc.Channel `json:"channel"`
+	Symbol  string                `json:"symbol"`
+	// Type is snapshot or update.
+	Type marketdatasvc.EventType `json:"type"`
+	// Sequence is the sequence number of the inbound message of the match engine the event is up to date with.
+	Sequence uint64 `json:"sequence"`
+	// PrevSequence is the sequence of the previous event of the channel, 0 for a snapshot. A client
+	// missed an event if it is not the sequence of the last event it received.
+	PrevSequence uint64                `json:"prev_sequence"`
+	Trades       []marketTradeResponse `json:"trades,omitempty"`
+	// Bids and Asks are the price levels of a snapshot, or the changed levels of an update in which
+	// a level with 0 quantity is removed. An empty side is left out.
+	Bids   []priceLevelResponse `json:"bids,omitempty"`
+	Asks   []priceLevelResponse `json:"asks,omitempty"`
+	Ticker *tickerResponse      `json:"ticker,omitempty"`
+}
+
+// streamErrorResponse model info
+type streamErrorResponse struct {
+	// Type is always error.
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+// StreamMarketData streams the trades, depth and ticker of books over a websocket. Each
+// subscription starts with a snapshot of the channel followed by its updates. A client falling
+// behind is disconnected and has to subscribe again.
+// @Summary StreamMarketData
+// @Tags Book
+// @version 1.0
+// @param X-API-Key header string false "api key"
+// @Router /stream [get]
+// @Success 101
+func (c *Controller) StreamMarketData(w http.ResponseWriter, r *http.Request) {
+	if _, err := c.authenticator.Authenticate(r); err != nil {
+		writeUnauthorizedResponse(w, err)
+		return
+	}
+
+	conn, err := streamUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// the upgrader has replied with the error
+		return
+	}
+	defer conn.Close()
+
+	client := c.marketData.NewClient()
+	defer client.Close()
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	errs := make(chan error)
+	go func() {
+		defer cancel()
+		c.readStreamRequests(ctx, conn, client, errs)
+	}()
+	writeStreamEvents(ctx, conn, client, errs)
+}
+
+// readStreamRequests handles the requests of the websocket until it fails, sending the errors of
+// invalid requests to errs.
+func (c *Controller) readStreamRequests(ctx context.Context, conn *websocket.Conn, client *marketdatasvc.Client, errs chan<- error) {
+	conn.SetReadLimit(streamMaxRequestSize)
+	_ = conn.SetReadDeadline(time.Now().Add(streamPongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(streamPongWait))
+	})
+
+	for {
+		_, bs, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		if err := c.handleStreamRequest(ctx, client, bs); err != nil {
+			select {
+			case errs <- err:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+func (c *Controller) handleStreamRequest(ctx context.Context, client *marketdatasvc.Client, bs []byte) error {
+	req := streamRequest{}
+	if err := json.Unmarshal(bs, &req); err != nil {
+		return errors.New("invalid request")
+	}
+
+	switch req.Channel {
+	case marketdatasvc.ChannelTrades, marketdatasvc.ChannelDepth, marketdatasvc.ChannelTicker:
+	default:
+		return errors.New("invalid channel")
+	}
+	if _, err := c.instrumentStore.GetInstrument(ctx, req.Symbol); err != nil {
+		return errors.New("invalid symbol")
+	}
+
+	switch req.Op {
+	case streamOpSubscribe:
+		client.Subscribe(req.Channel, req.Symbol)
+	case streamOpUnsubscribe:
+		client.Unsubscribe(req.Channel, req.Symbol)
+	default:
+		return errors.New("invalid op")
+	}
+	return nil
+}
+
+// writeStreamEvents writes the events of the client and the errors of its requests to the
+// websocket, and pings it to keep it alive, until either side is done.
+func writeStreamEvents(ctx context.Context, conn *websocket.Conn, client *marketdatasvc.Client, errs <-chan error) {
+	ticker := time.NewTicker(streamPingInterval)
+	defer ticker.Stop()
+
+	for {
+		var err error
+		select {
+		case <-ctx.Done():
+			return
+		case ev, ok := <-client.Events():
+			if !ok {
+				// the hub dropped the client for falling behind
+				msg := websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "slow consumer")
+				_ = conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(streamWriteWait))
+				return
+			}
+			err = writeStreamMessage(conn, toMarketDataEventResponse(ev))
+		case e := <-errs:
+			err = writeStreamMessage(conn, &streamErrorResponse{Type: "error", Message: e.Error()})
+		case <-ticker.C:
+			err = conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(streamWriteWait))
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func writeStreamMessage(conn *websocket.Conn, resp interface{}) error {
+	if err := conn.SetWriteDeadline(time.Now().Add(streamWriteWait)); err != nil {
+		return err
+	}
+	return conn.WriteJSON(resp)
+}
+
+func toMarketDataEventResponse(ev marketdatasvc.Event) *marketDataEventResponse {
+	resp := &marketDataEventResponse{
+		Channel:      ev.Channel,
+		Symbol:       ev.Symbol,
+		Type:         ev.Type,
+		Sequence:     ev.Sequence,
+		PrevSequence: ev.PrevSequence,
+		Bids:         toPriceLevelResponses(ev.Bids),
+		Asks:         toPriceLevelResponses(ev.Asks),
+	}
+	for _, td := range ev.Trades {
+		resp.Trades = append(resp.Trades, marketTradeResponse{
+			ID:            td.ID,
+			Sequence:      td.Sequence,
+			AggressorSide: td.AggressorSide,
+			Price:         td.Price,
+			Quantity:      td.Quantity,
+			Timestamp:     td.Timestamp,
+		})
+	}
+	if ev.Ticker != nil {
+		resp.Ticker = &tickerResponse{
+			LastPrice: ev.Ticker.LastPrice,
+			BestBid:   toPriceLevelResponse(ev.Ticker.BestBid),
+			BestAsk:   toPriceLevelResponse(ev.Ticker.BestAsk),
+		}
+	}
+	return resp
+}
@@ -23,6 +23,10 @@ func writeErrorResponse(w http.ResponseWriter, err error) {
 	WriteResponse(w, http.StatusInternalServerError, GeneralResponse{Message: err.Error()})
 }
 
+func writeUnauthorizedResponse(w http.ResponseWriter, err error) {
+	WriteResponse(w, http.StatusUnauthorized, GeneralResponse{Message: err.Error()})
+}
+
 func writeBadRequestResponse(w http.ResponseWriter, err error) {
 	WriteResponse(w, http.StatusBadRequest, GeneralResponse{Message: err.Error()})
 }
@@ -0,0 +1,320 @@
+package engine
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"trading-matching-service/pkg/engine/pqueue"
+	cancelsvc "trading-matching-service/pkg/service/cancel"
+	msgsvc "trading-matching-service/pkg/service/message"
+	ordersvc "trading-matching-service/pkg/service/order"
+	statussvc "trading-matching-service/pkg/service/status"
+	"trading-matching-service/util/decimal"
+)
+
+// AuctionWindow is a daily call auction period, given as times of day in UTC. A window whose end
+// is before its start spans midnight.
+type AuctionWindow struct {
+	Start time.Duration
+	End   time.Duration
+}
+
+// contains reports whether the time of day of ts is within the window.
+func (w AuctionWindow) contains(ts int64) bool {
+	t := time.Unix(0, ts).UTC()
+	offset := time.Duration(ts - time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC).UnixNano())
+	if w.Start <= w.End {
+		return w.Start <= offset && offset < w.End
+	}
+	return w.Start <= offset || offset < w.End
+}
+
+// isAuctionTime reports whether ts is within any auction window.
+func (e *matchEngine) isAuctionTime(ts int64) bool {
+	for _, w := range e.auctions {
+		if w.contains(ts) {
+			return true
+		}
+	}
+	return false
+}
+
+// updateTradingPhases starts the auctions that are due, resumes the halted books whose cooldown
+// is over through an auction, and uncrosses the books whose auction is over.
+func (e *matchEngine) updateTradingPhases(ctx context.Context, ts int64) {
+	inAuction := e.isAuctionTime(ts)
+	for _, book := range e.sortedBooks() {
+		switch book.phase {
+		case statussvc.TradingPhaseContinuous:
+			if inAuction {
+				e.setTradingPhase(ctx, book, statussvc.TradingPhaseAuction, statussvc.StatusReasonSchedule, 0)
+			}
+		case statussvc.TradingPhaseHalted:
+			if ts >= book.phaseEndAt {
+				e.setTradingPhase(ctx, book, statussvc.TradingPhaseAuction, statussvc.StatusReasonCooldown, ts+int64(e.resumeAuction))
+			}
+		case statussvc.TradingPhaseAuction:
+			if !inAuction && ts >= book.phaseEndAt {
+				e.uncrossAuction(ctx, book)
+			}
+		}
+	}
+}
+
+// setTradingPhase moves the book to the phase until endAt, 0 if the phase has no end time, and
+// publishes the change on the status queue.
+func (e *matchEngine) setTradingPhase(ctx context.Context, book *orderBook, phase statussvc.TradingPhase, reason statussvc.StatusReason, endAt int64) {
+	book.phase = phase
+	book.phaseEndAt = endAt
+
+	if e.statusQ == nil {
+		return
+	}
+	st := statussvc.Status{
+		Symbol:    book.symbol,
+		Phase:     phase,
+		Reason:    reason,
+		Timestamp: e.now(),
+	}
+	out := msgsvc.NewMessage(msgsvc.MessageKindStatus, &st)
+	_ = e.statusQ.Push(ctx, out)
+}
+
+// sortedBooks returns the order books sorted by symbol, so time driven events are handled in
+// the same order on every run.
+func (e *matchEngine) sortedBooks() []*orderBook {
+	books := make([]*orderBook, 0, len(e.books))
+	for _, book := range e.books {
+		books = append(books, book)
+	}
+	sort.Slice(books, func(i, j int) bool { return books[i].symbol < books[j].symbol })
+	return books
+}
+
+// haltBook stops matching in the book after a trade would print outside its price band. The book
+// resumes through an auction after the cooldown.
+func (e *matchEngine) haltBook(ctx context.Context, book *orderBook) {
+	e.setTradingPhase(ctx, book, statussvc.TradingPhaseHalted, statussvc.StatusReasonPriceBand, e.now()+int64(e.haltCooldown))
+}
+
+// collectAuctionOrder puts an order into a book in auction without matching it. Immediate orders
+// can not wait for the auction and are canceled.
+func (e *matchEngine) collectAuctionOrder(ctx context.Context, book *orderBook, ord *ordersvc.Order) {
+	switch ord.TimeInForce {
+	case ordersvc.TimeInForceIOC:
+		e.cancelOrder(ctx, ord, cancelsvc.CancelReasonImmediateOrCancel)
+		return
+	case ordersvc.TimeInForceFOK:
+		e.cancelOrder(ctx, ord, cancelsvc.CancelReasonFillOrKill)
+		return
+	}
+
+	if ord.DisplayQuantity.Sign() > 0 {
+		ord.VisibleQuantity = decimal.Min(ord.DisplayQuantity, ord.Quantity)
+	}
+	book.queue(ord.Kind).Push(ord)
+	e.expiries.schedule(ord)
+}
+
+// uncrossAuction ends the auction of a book. Every crossing order is filled at the uncrossing
+// price in price time priority, and then the book returns to continuous trading.
+func (e *matchEngine) uncrossAuction(ctx context.Context, book *orderBook) {
+	e.setTradingPhase(ctx, book, statussvc.TradingPhaseContinuous, statussvc.StatusReasonUncross, 0)
+
+	// orders with a minimum quantity or all or none sit the uncross out, as its fills can not
+	// meet their constraints, and arrive again once the book trades continuously
+	constrained := takeConstrainedOrders(book)
+
+	price, ok := uncrossingPrice(book)
+	if ok {
+		e.fillAuction(ctx, book, price)
+		book.marketPrice = price
+		book.trailStopOrders(price, price)
+	}
+
+	// market orders left from the auction are handled as if they had just arrived
+	lastPrice := decimal.Zero
+	if ok {
+		lastPrice = price
+	}
+	if e.marketOrderPolicy != MarketOrderPolicyRest {
+		for _, q := range []pqueue.PriorityQueue{book.buyQ, book.sellQ} {
+			for ord := q.Peek(); ord != nil && ord.PriceType == ordersvc.PriceTypeMarket; ord = q.Peek() {
+				q.Delete(ord.ID)
+				e.restOrder(ctx, book, ord, lastPrice)
+			}
+		}
+	}
+	for _, ord := range constrained {
+		e.processOrder(ctx, book, ord)
+	}
+
+	e.triggerOrders(ctx, book)
+}
+
+// takeConstrainedOrders removes the orders with a minimum quantity or all or none from the book
+// and returns them in the order they were confirmed.
+func takeConstrainedOrders(book *orderBook) []*ordersvc.Order {
+	ords := []*ordersvc.Order{}
+	for _, q := range []pqueue.PriorityQueue{book.buyQ, book.sellQ} {
+		for _, ord := range auctionOrders(q) {
+			if ord.MinQuantity.Sign() > 0 || ord.AllOrNone {
+				ords = append(ords, ord)
+			}
+		}
+	}
+	for _, ord := range ords {
+		book.queue(ord.Kind).Delete(ord.ID)
+	}
+	sort.SliceStable(ords, func(i, j int) bool { return ords[i].ConfirmedAt < ords[j].ConfirmedAt })
+	return ords
+}
+
+// fillAuction matches the crossing orders of the book at the uncrossing price.
+func (e *matchEngine) fillAuction(ctx context.Context, book *orderBook, price decimal.Decimal) {
+	for {
+		bOrd, sOrd := book.buyQ.Peek(), book.sellQ.Peek()
+		if bOrd == nil || sOrd == nil || !crossesAuctionPrice(bOrd, price) || !crossesAuctionPrice(sOrd, price) {
+			return
+		}
+
+		// the order confirmed later is treated as the incoming one
+		ord, resting := bOrd, sOrd
+		if sOrd.ConfirmedAt > bOrd.ConfirmedAt {
+			ord, resting = sOrd, bOrd
+		}
+		if e.isSelfTrade(ord, resting) {
+			e.preventSelfTrade(ctx, book.queue(resting.Kind), ord, resting)
+			if ord.Quantity.IsZero() {
+				book.queue(ord.Kind).Delete(ord.ID)
+			}
+			continue
+		}
+
+		qty := decimal.Min(bOrd.Quantity, sOrd.Quantity)
+		// an uncross has no aggressor
+		td := book.newTrade(bOrd, sOrd, price, qty, e.now())
+		e.publishTrade(ctx, td, bOrd, sOrd)
+
+		e.fillRestingOrder(book.buyQ, bOrd, qty)
+		e.fillRestingOrder(book.sellQ, sOrd, qty)
+		e.fillGroupOrder(ctx, bOrd)
+		e.fillGroupOrder(ctx, sOrd)
+	}
+}
+
+// crossesAuctionPrice reports whether the order accepts to trade at the auction price.
+func crossesAuctionPrice(ord *ordersvc.Order, price decimal.Decimal) bool {
+	if ord.PriceType == ordersvc.PriceTypeMarket {
+		return isWithinProtectionPrice(ord, price)
+	}
+	if ord.Kind == ordersvc.OrderKindBuy {
+		return !ord.Price.LessThan(price)
+	}
+	return !ord.Price.GreaterThan(price)
+}
+
+// uncrossingPrice returns the auction price of the book. It is the price executing the most
+// volume, then leaving the least imbalance. If the remaining prices all leave a surplus on one
+// side, the price is pushed toward that side, otherwise the price closest to the last trade price
+// is taken. It returns false if nothing can be executed.
+func uncrossingPrice(book *orderBook) (decimal.Decimal, bool) {
+	buys, sells := auctionOrders(book.buyQ), auctionOrders(book.sellQ)
+
+	candidates := []decimal.Decimal{}
+	for _, ord := range append(append([]*ordersvc.Order{}, buys...), sells...) {
+		if ord.PriceType != ordersvc.PriceTypeMarket {
+			candidates = append(candidates, ord.Price)
+		}
+	}
+	if len(candidates) == 0 && !book.marketPrice.IsZero() {
+		candidates = append(candidates, book.marketPrice)
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].LessThan(candidates[j]) })
+
+	type candidate struct {
+		price     decimal.Decimal
+		volume    decimal.Decimal
+		imbalance decimal.Decimal
+	}
+	best := []candidate{}
+	for i, price := range candidates {
+		if i > 0 && price == candidates[i-1] {
+			continue
+		}
+		buyVol, sellVol := auctionVolume(buys, price), auctionVolume(sells, price)
+		c := candidate{price: price, volume: decimal.Min(buyVol, sellVol), imbalance: buyVol.Sub(sellVol)}
+		if c.volume.IsZero() {
+			continue
+		}
+		if len(best) > 0 {
+			cmp := c.volume.Cmp(best[0].volume)
+			if cmp == 0 {
+				cmp = absDecimal(best[0].imbalance).Cmp(absDecimal(c.imbalance))
+			}
+			if cmp < 0 {
+				continue
+			}
+			if cmp > 0 {
+				best = best[:0]
+			}
+		}
+		best = append(best, c)
+	}
+	if len(best) == 0 {
+		return decimal.Zero, false
+	}
+
+	allBuySurplus, allSellSurplus := true, true
+	for _, c := range best {
+		allBuySurplus = allBuySurplus && c.imbalance.Sign() > 0
+		allSellSurplus = allSellSurplus && c.imbalance.Sign() < 0
+	}
+	switch {
+	case allBuySurplus:
+		return best[len(best)-1].price, true
+	case allSellSurplus:
+		return best[0].price, true
+	}
+
+	ref := book.marketPrice
+	price := best[0].price
+	if ref.IsZero() {
+		return price, true
+	}
+	for _, c := range best[1:] {
+		if absDecimal(c.price.Sub(ref)).LessThan(absDecimal(price.Sub(ref))) {
+			price = c.price
+		}
+	}
+	return price, true
+}
+
+// auctionOrders returns the orders of the queue.
+func auctionOrders(q pqueue.PriorityQueue) []*ordersvc.Order {
+	ords := []*ordersvc.Order{}
+	q.Iterate(func(ord *ordersvc.Order) bool {
+		ords = append(ords, ord)
+		return true
+	})
+	return ords
+}
+
+// auctionVolume returns the quantity of the orders accepting to trade at the price.
+func auctionVolume(ords []*ordersvc.Order, price decimal.Decimal) decimal.Decimal {
+	vol := decimal.Zero
+	for _, ord := range ords {
+		if crossesAuctionPrice(ord, price) {
+			vol = vol.Add(ord.Quantity)
+		}
+	}
+	return vol
+}
+
+func absDecimal(d decimal.Decimal) decimal.Decimal {
+	if d.Sign() < 0 {
+		return d.Neg()
+	}
+	return d
+}
@@ -0,0 +1,76 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	instrumentsvc "trading-matching-service/pkg/service/instrument"
+	ordersvc "trading-matching-service/pkg/service/order"
+	"trading-matching-service/util/decimal"
+)
+
+func Test_uncrossingPrice(t *testing.T) {
+	limit := func(id string, kind ordersvc.OrderKind, price string, qty int64) *ordersvc.Order {
+		return &ordersvc.Order{ID: id, Kind: kind, PriceType: ordersvc.PriceTypeLimit, Price: decimal.MustParse(price), Quantity: decimal.New(qty)}
+	}
+	buy, sell := ordersvc.OrderKindBuy, ordersvc.OrderKindSell
+
+	tests := []struct {
+		name        string
+		ords        []*ordersvc.Order
+		marketPrice string
+		exp         string
+		expOK       bool
+	}{
+		{
+			name: "maxVolume",
+			ords: []*ordersvc.Order{
+				limit("B1", buy, "101", 10), limit("B2", buy, "100", 20), limit("B3", buy, "99", 30),
+				limit("S1", sell, "98", 15), limit("S2", sell, "100", 25), limit("S3", sell, "102", 10),
+			},
+			exp:   "100",
+			expOK: true,
+		},
+		{
+			name:  "buySurplus",
+			ords:  []*ordersvc.Order{limit("B1", buy, "101", 20), limit("S1", sell, "99", 10)},
+			exp:   "101",
+			expOK: true,
+		},
+		{
+			name:  "sellSurplus",
+			ords:  []*ordersvc.Order{limit("B1", buy, "101", 10), limit("S1", sell, "99", 20)},
+			exp:   "99",
+			expOK: true,
+		},
+		{
+			name:        "referencePrice",
+			ords:        []*ordersvc.Order{limit("B1", buy, "101", 10), limit("S1", sell, "99", 10)},
+			marketPrice: "100.5",
+			exp:         "101",
+			expOK:       true,
+		},
+		{
+			name: "noCross",
+			ords: []*ordersvc.Order{limit("B1", buy, "98", 10), limit("S1", sell, "99", 10)},
+			exp:  "0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			book := newOrderBook(instrumentsvc.Instrument{})
+			if tt.marketPrice != "" {
+				book.marketPrice = decimal.MustParse(tt.marketPrice)
+			}
+			for _, ord := range tt.ords {
+				book.queue(ord.Kind).Push(ord)
+			}
+
+			price, ok := uncrossingPrice(book)
+			assert.Equal(t, tt.expOK, ok)
+			assert.Equal(t, decimal.MustParse(tt.exp), price)
+		})
+	}
+}
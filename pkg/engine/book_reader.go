@@ -0,0 +1,74 @@
+package engine
+
+import (
+	"context"
+
+	"trading-matching-service/pkg/engine/pqueue"
+	booksvc "trading-matching-service/pkg/service/book"
+	ordersvc "trading-matching-service/pkg/service/order"
+)
+
+func (e *matchEngine) GetDepth(ctx context.Context, symbol string, levels int) (booksvc.Depth, error) {
+	e.mux.RLock()
+	defer e.mux.RUnlock()
+
+	depth := booksvc.Depth{Symbol: symbol, Sequence: e.seq, Bids: []booksvc.PriceLevel{}, Asks: []booksvc.PriceLevel{}}
+	book, ok := e.books[symbol]
+	if !ok {
+		return depth, nil
+	}
+	depth.Bids = priceLevels(book.buyQ, levels)
+	depth.Asks = priceLevels(book.sellQ, levels)
+	return depth, nil
+}
+
+func (e *matchEngine) GetSnapshot(ctx context.Context, symbol string) (booksvc.Snapshot, error) {
+	e.mux.RLock()
+	defer e.mux.RUnlock()
+
+	snap := booksvc.Snapshot{Symbol: symbol, Sequence: e.seq, Bids: []booksvc.Order{}, Asks: []booksvc.Order{}}
+	book, ok := e.books[symbol]
+	if !ok {
+		return snap, nil
+	}
+	snap.Bids = bookOrders(book.buyQ)
+	snap.Asks = bookOrders(book.sellQ)
+	return snap, nil
+}
+
+// bookOrders returns the orders of the queue in priority order.
+func bookOrders(q pqueue.PriorityQueue) []booksvc.Order {
+	ords := []booksvc.Order{}
+	q.Iterate(func(ord *ordersvc.Order) bool {
+		ords = append(ords, booksvc.Order{
+			OrderID:   ord.ID,
+			PriceType: ord.PriceType,
+			Price:     ord.Price,
+			Quantity:  ord.BookQuantity(),
+			Timestamp: ord.ConfirmedAt,
+		})
+		return true
+	})
+	return ords
+}
+
+// priceLevels aggregates up to the number of price levels of the queue in priority order.
+func priceLevels(q pqueue.PriorityQueue, levels int) []booksvc.PriceLevel {
+	lvls := []booksvc.PriceLevel{}
+	q.Iterate(func(ord *ordersvc.Order) bool {
+		if ord.PriceType == ordersvc.PriceTypeMarket {
+			return true
+		}
+		if n := len(lvls); n > 0 && lvls[n-1].Price == ord.Price {
+			lvls[n-1].Quantity = lvls[n-1].Quantity.Add(ord.BookQuantity())
+			lvls[n-1].OrderCount++
+			return true
+		}
+		if len(lvls) == levels {
+			return false
+		}
+		lvls = append(lvls, booksvc.PriceLevel{Price: ord.Price, Quantity: ord.BookQuantity(), OrderCount: 1})
+		return true
+	})
+	return lvls
+}
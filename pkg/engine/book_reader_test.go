@@ -0,0 +1,91 @@
+package engine
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	booksvc "trading-matching-service/pkg/service/book"
+	instrumentsvc "trading-matching-service/pkg/service/instrument"
+	ordersvc "trading-matching-service/pkg/service/order"
+	"trading-matching-service/util/decimal"
+)
+
+func Test_matchEngineGetDepth(t *testing.T) {
+	e := NewMatchEngine(ordersvc.NewMemoryStore(), nil, nil, nil).(*matchEngine)
+	e.seq = 7
+	book := newOrderBook(instrumentsvc.Instrument{Symbol: "BTC-USD"})
+	e.books["BTC-USD"] = book
+
+	limit := func(id string, kind ordersvc.OrderKind, price string, qty int64, ts int64) *ordersvc.Order {
+		return &ordersvc.Order{ID: id, Kind: kind, PriceType: ordersvc.PriceTypeLimit, Price: decimal.MustParse(price), Quantity: decimal.New(qty), ConfirmedAt: ts}
+	}
+	iceberg := limit("B4", ordersvc.OrderKindBuy, "99", 50, 4)
+	iceberg.DisplayQuantity, iceberg.VisibleQuantity = decimal.New(5), decimal.New(5)
+	for _, ord := range []*ordersvc.Order{
+		{ID: "B0", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeMarket, Quantity: decimal.New(3), ConfirmedAt: 1},
+		limit("B1", ordersvc.OrderKindBuy, "100", 10, 1),
+		limit("B2", ordersvc.OrderKindBuy, "99", 20, 2),
+		limit("B3", ordersvc.OrderKindBuy, "98", 30, 3),
+		iceberg,
+	} {
+		book.buyQ.Push(ord)
+	}
+	for _, ord := range []*ordersvc.Order{
+		limit("S1", ordersvc.OrderKindSell, "101.5", 1, 1),
+		limit("S2", ordersvc.OrderKindSell, "101.5", 2, 2),
+	} {
+		book.sellQ.Push(ord)
+	}
+
+	depth, err := e.GetDepth(context.Background(), "BTC-USD", 2)
+	assert.NoError(t, err)
+	assert.Equal(t, booksvc.Depth{
+		Symbol:   "BTC-USD",
+		Sequence: 7,
+		Bids: []booksvc.PriceLevel{
+			{Price: decimal.New(100), Quantity: decimal.New(10), OrderCount: 1},
+			{Price: decimal.New(99), Quantity: decimal.New(25), OrderCount: 2},
+		},
+		Asks: []booksvc.PriceLevel{
+			{Price: decimal.MustParse("101.5"), Quantity: decimal.New(3), OrderCount: 2},
+		},
+	}, depth)
+
+	depth, err = e.GetDepth(context.Background(), "ETH-USD", 2)
+	assert.NoError(t, err)
+	assert.Empty(t, depth.Bids)
+	assert.Empty(t, depth.Asks)
+}
+
+func Test_matchEngineGetSnapshot(t *testing.T) {
+	e := NewMatchEngine(ordersvc.NewMemoryStore(), nil, nil, nil).(*matchEngine)
+	e.seq = 3
+	book := newOrderBook(instrumentsvc.Instrument{Symbol: "BTC-USD"})
+	e.books["BTC-USD"] = book
+
+	iceberg := &ordersvc.Order{ID: "S2", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(101), Quantity: decimal.New(50), DisplayQuantity: decimal.New(5), VisibleQuantity: decimal.New(5), ConfirmedAt: 1}
+	for _, ord := range []*ordersvc.Order{
+		{ID: "S1", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(101), Quantity: decimal.New(2), ConfirmedAt: 2},
+		iceberg,
+		{ID: "S3", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(100), Quantity: decimal.New(1), ConfirmedAt: 3},
+		{ID: "S4", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeMarket, Quantity: decimal.New(4), ConfirmedAt: 4},
+	} {
+		book.sellQ.Push(ord)
+	}
+
+	snap, err := e.GetSnapshot(context.Background(), "BTC-USD")
+	assert.NoError(t, err)
+	assert.Equal(t, booksvc.Snapshot{
+		Symbol:   "BTC-USD",
+		Sequence: 3,
+		Bids:     []booksvc.Order{},
+		Asks: []booksvc.Order{
+			{OrderID: "S4", PriceType: ordersvc.PriceTypeMarket, Quantity: decimal.New(4), Timestamp: 4},
+			{OrderID: "S3", PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(100), Quantity: decimal.New(1), Timestamp: 3},
+			{OrderID: "S2", PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(101), Quantity: decimal.New(5), Timestamp: 1},
+			{OrderID: "S1", PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(101), Quantity: decimal.New(2), Timestamp: 2},
+		},
+	}, snap)
+}
@@ -6,3 +6,9 @@ type Engine interface {
 	// Run starts running the engine.
 	Run(ctx context.Context) error
 }
+
+// Recoverer is an engine which can rebuild its state after a restart.
+type Recoverer interface {
+	// Recover rebuilds the state of the engine before it runs.
+	Recover(ctx context.Context) error
+}
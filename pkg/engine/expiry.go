@@ -0,0 +1,82 @@
+package engine
+
+import (
+	"container/heap"
+	"context"
+	"time"
+
+	cancelsvc "trading-matching-service/pkg/service/cancel"
+	ordersvc "trading-matching-service/pkg/service/order"
+)
+
+// expiry is an order waiting for its expiry time.
+type expiry struct {
+	expireAt  int64
+	orderID   string
+	symbol    string
+	orderKind ordersvc.OrderKind
+}
+
+// expiryQueue is a min heap of expiries ordered by expiry time.
+type expiryQueue []*expiry
+
+func (q expiryQueue) Len() int           { return len(q) }
+func (q expiryQueue) Less(i, j int) bool { return q[i].expireAt < q[j].expireAt }
+func (q expiryQueue) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
+
+func (q *expiryQueue) Push(x interface{}) {
+	*q = append(*q, x.(*expiry))
+}
+
+func (q *expiryQueue) Pop() interface{} {
+	old := *q
+	n := len(old)
+	item := old[n-1]
+	old[n-1] = nil
+	*q = old[:n-1]
+	return item
+}
+
+// schedule adds the order to the queue if it has an expiry time.
+func (q *expiryQueue) schedule(ord *ordersvc.Order) {
+	if ord.ExpireAt == 0 {
+		return
+	}
+	heap.Push(q, &expiry{
+		expireAt:  ord.ExpireAt,
+		orderID:   ord.ID,
+		symbol:    ord.Symbol,
+		orderKind: ord.Kind,
+	})
+}
+
+// popExpired pops the next expiry that is due at ts.
+func (q *expiryQueue) popExpired(ts int64) (*expiry, bool) {
+	if q.Len() == 0 || (*q)[0].expireAt > ts {
+		return nil, false
+	}
+	return heap.Pop(q).(*expiry), true
+}
+
+// nextDayClose returns the first day close after ts.
+func nextDayClose(ts int64, dayClose time.Duration) int64 {
+	t := time.Unix(0, ts).UTC()
+	closeAt := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC).Add(dayClose)
+	if !closeAt.After(t) {
+		closeAt = closeAt.Add(24 * time.Hour)
+	}
+	return closeAt.UnixNano()
+}
+
+// expireOrders cancels the orders whose expiry time is due at ts.
+func (e *matchEngine) expireOrders(ctx context.Context, ts int64) {
+	for exp, ok := e.expiries.popExpired(ts); ok; exp, ok = e.expiries.popExpired(ts) {
+		ord, q := e.getOrderBook(ctx, exp.symbol).findOrder(exp.orderID, exp.orderKind)
+		if ord == nil {
+			// already filled or canceled
+			continue
+		}
+		q.Delete(exp.orderID)
+		e.cancelOrder(ctx, ord, cancelsvc.CancelReasonExpired)
+	}
+}
@@ -0,0 +1,121 @@
+package engine
+
+import (
+	"context"
+	"sort"
+
+	booksvc "trading-matching-service/pkg/service/book"
+	marketdatasvc "trading-matching-service/pkg/service/marketdata"
+	msgsvc "trading-matching-service/pkg/service/message"
+	tradesvc "trading-matching-service/pkg/service/trade"
+	"trading-matching-service/util/decimal"
+)
+
+// publishMarketData pushes an update for each book the inbound message changed to market data
+// queue. It only reads the books, so it runs outside the lock.
+func (e *matchEngine) publishMarketData(ctx context.Context) {
+	for _, book := range sortedBooks(e.touched) {
+		bids, asks := priceLevels(book.buyQ, e.marketDataLevels), priceLevels(book.sellQ, e.marketDataLevels)
+		ticker := newTicker(book, bids, asks)
+		upd := marketdatasvc.Update{
+			Symbol:   book.symbol,
+			Sequence: e.seq,
+			Trades:   book.mdTrades,
+			Bids:     diffLevels(book.mdBids, bids),
+			Asks:     diffLevels(book.mdAsks, asks),
+		}
+		if ticker != book.mdTicker {
+			upd.Ticker = &ticker
+		}
+		book.mdBids, book.mdAsks, book.mdTicker, book.mdTrades = bids, asks, ticker, nil
+
+		if len(upd.Trades) == 0 && len(upd.Bids) == 0 && len(upd.Asks) == 0 && upd.Ticker == nil {
+			continue
+		}
+		out := msgsvc.NewMessage(msgsvc.MessageKindMarketData, &upd)
+		_ = e.marketDataQ.Push(ctx, out)
+	}
+	e.touched = map[string]*orderBook{}
+}
+
+// publishMarketDataSnapshots pushes a snapshot update of every book to market data queue, so the
+// subscribers start from the books as they are after recovery.
+func (e *matchEngine) publishMarketDataSnapshots(ctx context.Context) {
+	for _, book := range sortedBooks(e.books) {
+		bids, asks := priceLevels(book.buyQ, e.marketDataLevels), priceLevels(book.sellQ, e.marketDataLevels)
+		ticker := newTicker(book, bids, asks)
+		book.mdBids, book.mdAsks, book.mdTicker, book.mdTrades = bids, asks, ticker, nil
+
+		upd := marketdatasvc.Update{
+			Symbol:   book.symbol,
+			Sequence: e.seq,
+			Snapshot: true,
+			Bids:     bids,
+			Asks:     asks,
+			Ticker:   &ticker,
+		}
+		out := msgsvc.NewMessage(msgsvc.MessageKindMarketData, &upd)
+		_ = e.marketDataQ.Push(ctx, out)
+	}
+	e.touched = map[string]*orderBook{}
+}
+
+// sortedBooks returns the books in symbol order, so the updates are published in the same order
+// on every run.
+func sortedBooks(books map[string]*orderBook) []*orderBook {
+	out := make([]*orderBook, 0, len(books))
+	for _, book := range books {
+		out = append(out, book)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].symbol < out[j].symbol
+	})
+	return out
+}
+
+// newTicker returns the ticker of the book with its top price levels.
+func newTicker(book *orderBook, bids, asks []booksvc.PriceLevel) marketdatasvc.Ticker {
+	ticker := marketdatasvc.Ticker{LastPrice: book.marketPrice}
+	if len(bids) > 0 {
+		ticker.BestBid = bids[0]
+	}
+	if len(asks) > 0 {
+		ticker.BestAsk = asks[0]
+	}
+	return ticker
+}
+
+// diffLevels returns the levels of cur that are not the same in prev, and the levels of prev
+// whose price is not in cur with 0 quantity.
+func diffLevels(prev, cur []booksvc.PriceLevel) []booksvc.PriceLevel {
+	left := map[decimal.Decimal]booksvc.PriceLevel{}
+	for _, lvl := range prev {
+		left[lvl.Price] = lvl
+	}
+
+	changes := []booksvc.PriceLevel{}
+	for _, lvl := range cur {
+		if p, ok := left[lvl.Price]; !ok || p != lvl {
+			changes = append(changes, lvl)
+		}
+		delete(left, lvl.Price)
+	}
+	for _, lvl := range prev {
+		if _, ok := left[lvl.Price]; ok {
+			changes = append(changes, booksvc.PriceLevel{Price: lvl.Price})
+		}
+	}
+	return changes
+}
+
+// publicTrade returns the part of the trade published as market data.
+func publicTrade(td *tradesvc.Trade) marketdatasvc.Trade {
+	return marketdatasvc.Trade{
+		ID:            td.ID,
+		Sequence:      td.Sequence,
+		AggressorSide: td.AggressorSide,
+		Price:         td.Price,
+		Quantity:      td.Quantity,
+		Timestamp:     td.Timestamp,
+	}
+}
@@ -0,0 +1,53 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+
+	marketdatasvc "trading-matching-service/pkg/service/marketdata"
+	msgsvc "trading-matching-service/pkg/service/message"
+)
+
+type marketDataEngine struct {
+	marketDataQ msgsvc.Queue
+	publisher   marketdatasvc.Publisher
+}
+
+// NewMarketDataEngine returns a market data engine publishing the book updates of the match engine.
+func NewMarketDataEngine(marketDataQ msgsvc.Queue, publisher marketdatasvc.Publisher) Engine {
+	return &marketDataEngine{
+		marketDataQ: marketDataQ,
+		publisher:   publisher,
+	}
+}
+
+func (e *marketDataEngine) Run(ctx context.Context) error {
+	for {
+		msg, err := e.marketDataQ.Pop(ctx)
+		if err != nil {
+			return err
+		}
+		e.handle(ctx, msg)
+	}
+}
+
+func (e *marketDataEngine) handle(ctx context.Context, msg msgsvc.AcknowledgementMessage) {
+	if msg.GetKind() != msgsvc.MessageKindMarketData {
+		return
+	}
+
+	bs := msg.GetData()
+	upd := marketdatasvc.Update{}
+	if err := json.Unmarshal(bs, &upd); err != nil {
+		// not a valid message, drop it
+		msg.Ack()
+		return
+	}
+
+	if err := e.publisher.Publish(ctx, upd); err != nil {
+		msg.Nack()
+		return
+	}
+
+	msg.Ack()
+}
@@ -0,0 +1,74 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	booksvc "trading-matching-service/pkg/service/book"
+	marketdatasvc "trading-matching-service/pkg/service/marketdata"
+	msgsvc "trading-matching-service/pkg/service/message"
+	ordersvc "trading-matching-service/pkg/service/order"
+	"trading-matching-service/util/decimal"
+)
+
+func Test_matchEnginePublishMarketData(t *testing.T) {
+	ctx := context.Background()
+	orderQ, mdQ := msgsvc.NewQueue(10), msgsvc.NewQueue(10)
+	e := NewMatchEngine(ordersvc.NewMemoryStore(), orderQ, msgsvc.NewQueue(10), msgsvc.NewQueue(10),
+		WithDeterministic(), WithMarketDataQueue(mdQ, 1)).(*matchEngine)
+
+	handle := func(ord ordersvc.Order) {
+		ord.Symbol, ord.PriceType = "BTC-USD", ordersvc.PriceTypeLimit
+		_ = orderQ.Push(ctx, msgsvc.NewMessage(msgsvc.MessageKindOrderCreate, &ord))
+		msg, _ := orderQ.Pop(ctx)
+		_ = e.handle(ctx, msg)
+	}
+	nextUpdate := func() marketdatasvc.Update {
+		msg, _ := mdQ.Pop(ctx)
+		upd := marketdatasvc.Update{}
+		assert.Equal(t, msgsvc.MessageKindMarketData, msg.GetKind())
+		assert.NoError(t, json.Unmarshal(msg.GetData(), &upd))
+		return upd
+	}
+	level := func(price, qty int64, count int) booksvc.PriceLevel {
+		return booksvc.PriceLevel{Price: decimal.New(price), Quantity: decimal.New(qty), OrderCount: count}
+	}
+
+	handle(ordersvc.Order{ID: "S1", AccountID: "A", Kind: ordersvc.OrderKindSell, Price: decimal.New(101), Quantity: decimal.New(2)})
+	assert.Equal(t, marketdatasvc.Update{
+		Symbol:   "BTC-USD",
+		Sequence: 1,
+		Bids:     []booksvc.PriceLevel{},
+		Asks:     []booksvc.PriceLevel{level(101, 2, 1)},
+		Ticker:   &marketdatasvc.Ticker{BestAsk: level(101, 2, 1)},
+	}, nextUpdate())
+
+	// a level below the top of the book does not change the depth
+	handle(ordersvc.Order{ID: "S2", AccountID: "A", Kind: ordersvc.OrderKindSell, Price: decimal.New(102), Quantity: decimal.New(1)})
+	handle(ordersvc.Order{ID: "B1", AccountID: "B", Kind: ordersvc.OrderKindBuy, Price: decimal.New(101), Quantity: decimal.New(2)})
+	upd := nextUpdate()
+	assert.Equal(t, uint64(3), upd.Sequence)
+	if assert.Len(t, upd.Trades, 1) {
+		assert.Equal(t, "BTC-USD-1", upd.Trades[0].ID)
+		assert.Equal(t, ordersvc.OrderKindBuy, upd.Trades[0].AggressorSide)
+		assert.Equal(t, decimal.New(101), upd.Trades[0].Price)
+		assert.Equal(t, decimal.New(2), upd.Trades[0].Quantity)
+	}
+	assert.Equal(t, []booksvc.PriceLevel{}, upd.Bids)
+	assert.Equal(t, []booksvc.PriceLevel{level(102, 1, 1), {Price: decimal.New(101)}}, upd.Asks)
+	assert.Equal(t, &marketdatasvc.Ticker{LastPrice: decimal.New(101), BestAsk: level(102, 1, 1)}, upd.Ticker)
+
+	// a restarted match engine starts its subscribers over from snapshots
+	e.publishMarketDataSnapshots(ctx)
+	assert.Equal(t, marketdatasvc.Update{
+		Symbol:   "BTC-USD",
+		Sequence: 3,
+		Snapshot: true,
+		Bids:     []booksvc.PriceLevel{},
+		Asks:     []booksvc.PriceLevel{level(102, 1, 1)},
+		Ticker:   &marketdatasvc.Ticker{LastPrice: decimal.New(101), BestAsk: level(102, 1, 1)},
+	}, nextUpdate())
+}
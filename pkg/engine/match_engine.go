@@ -3,15 +3,21 @@ package engine
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"sync"
 	"time"
 
 	"trading-matching-service/pkg/engine/pqueue"
 	cancelsvc "trading-matching-service/pkg/service/cancel"
+	instrumentsvc "trading-matching-service/pkg/service/instrument"
+	journalsvc "trading-matching-service/pkg/service/journal"
 	msgsvc "trading-matching-service/pkg/service/message"
 	"trading-matching-service/pkg/service/order"
 	ordersvc "trading-matching-service/pkg/service/order"
+	statussvc "trading-matching-service/pkg/service/status"
 	tradesvc "trading-matching-service/pkg/service/trade"
-	"trading-matching-service/util/minmax"
+	"trading-matching-service/util/clock"
+	"trading-matching-service/util/decimal"
 )
 
 const (
@@ -20,58 +26,228 @@ const (
 
 	lowerPriceFirst  = true
 	higherPriceFirst = false
+
+	// journalRetries is how many more times a failed journal append is tried, journalRetryBackoff
+	// apart at first.
+	journalRetries      = 5
+	journalRetryBackoff = 10 * time.Millisecond
 )
 
 type matchEngine struct {
+	// mux guards the state of the match engine against readers of its books while it handles a
+	// message.
+	mux sync.RWMutex
+
 	orderStore ordersvc.Store
 	orderQ     msgsvc.Queue
 	tradeQ     msgsvc.Queue
 	cancelQ    msgsvc.Queue
-	sellQ      pqueue.PriorityQueue
-	buyQ       pqueue.PriorityQueue
-
-	marketPrice float64
+	statusQ    msgsvc.Queue
+	reportQ    msgsvc.Queue
+	books      map[string]*orderBook
+	expiries   expiryQueue
+
+	instrumentStore instrumentsvc.Store
+	timerInterval   time.Duration
+	dayClose        time.Duration
+	postOnlyPolicy  PostOnlyPolicy
+	stpMode         SelfTradePreventionMode
+
+	marketOrderPolicy MarketOrderPolicy
+	auctions          []AuctionWindow
+	haltCooldown      time.Duration
+	resumeAuction     time.Duration
+	haltOrderPolicy   HaltOrderPolicy
+
+	clock         clock.Clock
+	deterministic bool
+	// seq is the sequence number of the inbound message being handled.
+	seq uint64
+	// at is the time at which the inbound message is handled.
+	at int64
+	// epoch is the time in nanoseconds at which the match engine started, put in trade IDs so
+	// they stay unique across restarts. It is 0 in deterministic mode.
+	epoch int64
+
+	journal          journalsvc.Journal
+	snapshotInterval uint64
+	// snapshots are the snapshots waiting to be written while the match engine runs.
+	snapshots chan *snapshot
+
+	marketDataQ      msgsvc.Queue
+	marketDataLevels int
+	// touched are the books the inbound message being handled may have changed.
+	touched map[string]*orderBook
+
+	// groups maps the pending orders of order groups to their group.
+	groups map[string]*orderGroup
+	// activations are the exit orders of filled bracket entry orders waiting to be placed.
+	activations []*ordersvc.Order
 }
 
 // NewMatchEngined return a match engine.
-func NewMatchEngine(orderStore order.Store, orderQ, tradeQ, cancelQ msgsvc.Queue) Engine {
-	sellQ := pqueue.NewRedBlackTreeQueue(lowerPriceFirst)
-	buyQ := pqueue.NewRedBlackTreeQueue(higherPriceFirst)
+func NewMatchEngine(orderStore order.Store, orderQ, tradeQ, cancelQ msgsvc.Queue, opts ...MatchEngineOption) Engine {
+	e := &matchEngine{
+		orderStore:    orderStore,
+		orderQ:        orderQ,
+		tradeQ:        tradeQ,
+		cancelQ:       cancelQ,
+		books:         map[string]*orderBook{},
+		groups:        map[string]*orderGroup{},
+		touched:       map[string]*orderBook{},
+		timerInterval: defaultTimerInterval,
+		haltCooldown:  defaultHaltCooldown,
+		resumeAuction: defaultResumeAuction,
+		clock:         clock.NewSystemClock(),
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	if !e.deterministic {
+		e.epoch = e.clock.Now().UnixNano()
+	}
+	return e
+}
 
-	return &matchEngine{
-		orderStore: orderStore,
-		orderQ:     orderQ,
-		tradeQ:     tradeQ,
-		cancelQ:    cancelQ,
-		sellQ:      sellQ,
-		buyQ:       buyQ,
+// getOrderBook returns the order book of the symbol, creating it on first use.
+func (e *matchEngine) getOrderBook(ctx context.Context, symbol string) *orderBook {
+	book, ok := e.books[symbol]
+	if !ok {
+		inst := instrumentsvc.Instrument{Symbol: symbol}
+		if e.instrumentStore != nil {
+			if v, err := e.instrumentStore.GetInstrument(ctx, symbol); err == nil {
+				inst = v
+			}
+		}
+		book = newOrderBook(inst)
+		book.epoch = e.epoch
+		if e.isAuctionTime(e.now()) {
+			book.phase = statussvc.TradingPhaseAuction
+		}
+		e.books[symbol] = book
 	}
+	e.touched[symbol] = book
+	return book
+}
+
+// now returns the time in nanoseconds at which the match engine handles the inbound message, or
+// its sequence number in deterministic mode.
+func (e *matchEngine) now() int64 {
+	return e.at
 }
 
 func (e *matchEngine) Run(ctx context.Context) error {
+	if !e.deterministic {
+		go e.runTimer(ctx)
+	}
+	if e.marketDataQ != nil {
+		e.publishMarketDataSnapshots(ctx)
+	}
+	if e.journal != nil && e.snapshotInterval > 0 {
+		// snapshots are written off the match loop, one at a time
+		e.snapshots = make(chan *snapshot, 1)
+		done := make(chan struct{})
+		go func() {
+			e.writeSnapshots(e.snapshots)
+			close(done)
+		}()
+		defer func() {
+			close(e.snapshots)
+			<-done
+		}()
+	}
+
 	for {
 		msg, err := e.orderQ.Pop(ctx)
 		if err != nil {
 			return err
 		}
-		e.handle(ctx, msg)
+		if err := e.handle(ctx, msg); err != nil {
+			return err
+		}
+	}
+}
+
+// handle journals and handles an inbound message. It only fails if the message can not be
+// journaled, which stops the match engine, since requeuing the message would let later messages
+// be handled before it.
+func (e *matchEngine) handle(ctx context.Context, msg msgsvc.AcknowledgementMessage) error {
+	if !json.Valid(msg.GetData()) {
+		// not a valid message, drop it
+		msg.Ack()
+		return nil
+	}
+	if msg.GetKind() == msgsvc.MessageKindTimer && !e.isTimerDue(msg.GetData()) {
+		// an idle tick changes nothing, so it is neither journaled nor handled
+		msg.Ack()
+		return nil
+	}
+
+	seq, at := e.seq+1, e.clock.Now().UnixNano()
+	if e.deterministic {
+		at = int64(seq)
+	}
+	if e.journal != nil {
+		entry := journalsvc.Entry{Sequence: seq, Timestamp: at, Kind: msg.GetKind(), Data: msg.GetData()}
+		if err := e.appendJournal(ctx, entry); err != nil {
+			// a message is only handled once it is journaled
+			return fmt.Errorf("failed to journal message %d: %v", seq, err)
+		}
+	}
+
+	e.mux.Lock()
+	e.seq, e.at = seq, at
+	e.dispatch(ctx, msg)
+	if e.snapshots != nil && e.seq%e.snapshotInterval == 0 && len(e.snapshots) < cap(e.snapshots) {
+		// a snapshot due while the previous one is still waiting is skipped, the journal covers it
+		if snap, err := e.takeSnapshot(ctx); err == nil {
+			e.snapshots <- snap
+		}
+	}
+	e.mux.Unlock()
+	msg.Ack()
+
+	if e.marketDataQ != nil {
+		e.publishMarketData(ctx)
+	}
+	return nil
+}
+
+// appendJournal appends an entry to the journal, retrying in place with a doubling backoff.
+func (e *matchEngine) appendJournal(ctx context.Context, entry journalsvc.Entry) error {
+	backoff := journalRetryBackoff
+	for i := 0; ; i++ {
+		err := e.journal.Append(entry)
+		if err == nil || i == journalRetries {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
 	}
 }
 
-func (e *matchEngine) handle(ctx context.Context, msg msgsvc.AcknowledgementMessage) {
-	defer msg.Ack()
+func (e *matchEngine) dispatch(ctx context.Context, msg msgsvc.Message) {
 	switch msg.GetKind() {
 	case msgsvc.MessageKindOrderCreate:
 		e.handleOrderCreate(ctx, msg)
 	case msgsvc.MessageKindOrderCancel:
 		e.handleOrderCancel(ctx, msg)
+	case msgsvc.MessageKindOrderAmend:
+		e.handleOrderAmend(ctx, msg)
+	case msgsvc.MessageKindOrderGroupCreate:
+		e.handleOrderGroupCreate(ctx, msg)
+	case msgsvc.MessageKindTimer:
+		e.handleTimer(ctx, msg)
 	default:
 		return
 	}
-
 }
 
-func (e *matchEngine) handleOrderCreate(ctx context.Context, msg msgsvc.AcknowledgementMessage) {
+func (e *matchEngine) handleOrderCreate(ctx context.Context, msg msgsvc.Message) {
 	bs := msg.GetData()
 	ord := &ordersvc.Order{}
 	if err := json.Unmarshal(bs, ord); err != nil {
@@ -79,22 +255,136 @@ func (e *matchEngine) handleOrderCreate(ctx context.Context, msg msgsvc.Acknowle
 		return
 	}
 
-	now := time.Now().UnixNano()
+	if !e.confirmOrder(ctx, ord, e.now()) {
+		return
+	}
+
+	book := e.getOrderBook(ctx, ord.Symbol)
+	e.placeOrder(ctx, book, ord)
+	e.triggerOrders(ctx, book)
+}
+
+// confirmOrder confirms an incoming order at now and sets its expiry. It returns false if the
+// order is dropped or already expired.
+func (e *matchEngine) confirmOrder(ctx context.Context, ord *ordersvc.Order, now int64) bool {
 	_ = e.orderStore.ConfirmOrderAt(ctx, ord.ID, now)
 	ord.ConfirmedAt = now
 
-	switch ord.Kind {
-	case ordersvc.OrderKindBuy:
-		e.handleBuyOrder(ctx, ord)
-	case ordersvc.OrderKindSell:
-		e.handleSellOrder(ctx, ord)
-	default:
-		// not a valid order, drop it
+	if ord.TimeInForce == ordersvc.TimeInForceDay {
+		ord.ExpireAt = nextDayClose(now, e.dayClose)
+	}
+	if ord.ExpireAt != 0 && ord.ExpireAt <= now {
+		e.cancelOrder(ctx, ord, cancelsvc.CancelReasonExpired)
+		return false
+	}
+
+	// not a valid order otherwise, drop it
+	return ord.Kind == ordersvc.OrderKindBuy || ord.Kind == ordersvc.OrderKindSell
+}
+
+// placeOrder puts a confirmed order into the book, parking it as a stop order until it is
+// triggered or matching it right away.
+func (e *matchEngine) placeOrder(ctx context.Context, book *orderBook, ord *ordersvc.Order) {
+	if book.phase == statussvc.TradingPhaseHalted && e.haltOrderPolicy == HaltOrderPolicyReject {
+		e.cancelOrder(ctx, ord, cancelsvc.CancelReasonHalted)
+		return
+	}
+
+	if ord.IsTrailingStop() {
+		book.moveTrailAnchor(ord, book.marketPrice)
+	}
+	if ord.IsStop() {
+		if !book.isTriggered(ord) {
+			book.stopQueue(ord.Kind).Push(ord)
+			e.expiries.schedule(ord)
+			return
+		}
+		activateStopOrder(ord, ord.ConfirmedAt)
+	}
+
+	e.processOrder(ctx, book, ord)
+}
+
+// processOrder matches an incoming order against the book.
+func (e *matchEngine) processOrder(ctx context.Context, book *orderBook, ord *ordersvc.Order) {
+	if book.phase != statussvc.TradingPhaseContinuous {
+		e.collectAuctionOrder(ctx, book, ord)
+		return
+	}
+
+	if ord.PostOnly && e.crossesBook(book, ord) {
+		if e.postOnlyPolicy != PostOnlyPolicyReprice || !repricePostOnlyOrder(book, ord) {
+			e.cancelOrder(ctx, ord, cancelsvc.CancelReasonPostOnly)
+			return
+		}
+	}
+
+	if ord.TimeInForce == ordersvc.TimeInForceFOK && e.fillableQuantity(book, ord).LessThan(ord.Quantity) {
+		e.cancelOrder(ctx, ord, cancelsvc.CancelReasonFillOrKill)
 		return
 	}
+
+	if ord.PriceType == ordersvc.PriceTypeMarket && e.marketOrderPolicy == MarketOrderPolicyReject && e.fillableQuantity(book, ord).IsZero() {
+		e.cancelOrder(ctx, ord, cancelsvc.CancelReasonNoLiquidity)
+		return
+	}
+
+	if minQty := ord.MinFillQuantity(); minQty.Sign() > 0 && e.fillableQuantity(book, ord).LessThan(minQty) {
+		// the order does not trade at all rather than filling less than its minimum
+		e.restOrder(ctx, book, ord, decimal.Zero)
+		return
+	}
+
+	e.handleOrder(ctx, book, ord)
+}
+
+// triggerOrders places the exit orders of the filled bracket entry orders and processes the stop
+// orders triggered by the market price, until no more order is pending, since each placed order
+// may trade and move the market price again.
+func (e *matchEngine) triggerOrders(ctx context.Context, book *orderBook) {
+	for {
+		if len(e.activations) > 0 {
+			ord := e.activations[0]
+			e.activations = e.activations[1:]
+			if _, ok := e.groups[ord.ID]; ok && e.confirmOrder(ctx, ord, e.now()) {
+				e.placeOrder(ctx, book, ord)
+			}
+			continue
+		}
+
+		ord := book.buyStopQ.Peek()
+		if ord == nil || !book.isTriggered(ord) {
+			ord = book.sellStopQ.Peek()
+		}
+		if ord == nil || !book.isTriggered(ord) {
+			return
+		}
+
+		book.stopQueue(ord.Kind).Pop()
+		activateStopOrder(ord, e.now())
+		e.processOrder(ctx, book, ord)
+	}
 }
 
-func (e *matchEngine) handleOrderCancel(ctx context.Context, msg msgsvc.AcknowledgementMessage) {
+// activateStopOrder turns a triggered stop order into a market or limit order.
+func activateStopOrder(ord *ordersvc.Order, ts int64) {
+	switch ord.PriceType {
+	case ordersvc.PriceTypeStop, ordersvc.PriceTypeTrailingStop:
+		ord.PriceType = ordersvc.PriceTypeMarket
+	case ordersvc.PriceTypeTrailingStopLimit:
+		ord.PriceType = ordersvc.PriceTypeLimit
+		if ord.Kind == ordersvc.OrderKindBuy {
+			ord.Price = ord.TriggerPrice.Add(ord.LimitOffset)
+		} else {
+			ord.Price = ord.TriggerPrice.Sub(ord.LimitOffset)
+		}
+	default:
+		ord.PriceType = ordersvc.PriceTypeLimit
+	}
+	ord.ConfirmedAt = ts
+}
+
+func (e *matchEngine) handleOrderCancel(ctx context.Context, msg msgsvc.Message) {
 	bs := msg.GetData()
 	cancel := &ordersvc.Cancel{}
 	if err := json.Unmarshal(bs, cancel); err != nil {
@@ -102,122 +392,604 @@ func (e *matchEngine) handleOrderCancel(ctx context.Context, msg msgsvc.Acknowle
 		return
 	}
 
-	cancel.ConfirmedAt = time.Now().Unix()
+	cancel.ConfirmedAt = e.now()
 	e.handleCancelOrder(ctx, cancel)
 }
 
-func (e *matchEngine) handleBuyOrder(ctx context.Context, bOrd *ordersvc.Order) {
-	for sOrd := e.sellQ.Peek(); sOrd != nil && bOrd.Quantity > 0; sOrd = e.sellQ.Peek() {
-		td, ok := e.match(bOrd, sOrd, matchAtMinPrice)
+func (e *matchEngine) handleOrderAmend(ctx context.Context, msg msgsvc.Message) {
+	bs := msg.GetData()
+	amend := &ordersvc.Amend{}
+	if err := json.Unmarshal(bs, amend); err != nil {
+		// not a valid message, drop it
+		return
+	}
+
+	amend.ConfirmedAt = e.now()
+	e.handleAmendOrder(ctx, amend)
+}
+
+// handleOrder matches an incoming order against the opposite queue level by level until it is
+// filled or no resting order matches it, and then rests the remainder. The quantity traded at a
+// price level is allocated among its orders by the matching algorithm of the book. Resting orders
+// whose minimum fill quantity can not be met are skipped.
+func (e *matchEngine) handleOrder(ctx context.Context, book *orderBook, ord *ordersvc.Order) {
+	oppositeQ := book.oppositeQueue(ord.Kind)
+	lastPrice, lowPrice, highPrice := decimal.Zero, decimal.Zero, decimal.Zero
+	skipped := map[string]bool{}
+	for best := e.bestOrder(oppositeQ, ord, skipped); best != nil && ord.Quantity.Sign() > 0; best = e.bestOrder(oppositeQ, ord, skipped) {
+		price, ok := e.tradePrice(book, ord, best)
 		if !ok {
 			break
 		}
-		defer func() {
-			e.marketPrice = td.Price
-		}()
+		if !book.isWithinPriceBand(price) {
+			e.haltBook(ctx, book)
+			break
+		}
 
-		out := msgsvc.NewMessage(msgsvc.MessageKindTrade, td)
-		_ = e.tradeQ.Push(ctx, out)
+		level, allocs := e.allocateLevel(ctx, book, oppositeQ, ord, e.priceLevel(oppositeQ, ord, best, skipped, book.algorithm))
+		if len(level) == 0 {
+			continue
+		}
 
-		// update quantity for buy order
-		if bOrd.Quantity > td.Quantity {
-			bOrd.Quantity -= td.Quantity
-		} else {
-			bOrd.Quantity = 0
+		for i, other := range level {
+			// an order of the level may have been canceled by a fill of a linked order
+			if allocs[i].IsZero() || other.Quantity.IsZero() || ord.Quantity.IsZero() {
+				continue
+			}
+			if allocs[i].LessThan(other.MinFillQuantity()) {
+				skipped[other.ID] = true
+				continue
+			}
+			td := e.newTrade(book, ord, other, price, allocs[i])
+			if lastPrice.IsZero() {
+				lowPrice, highPrice = td.Price, td.Price
+			}
+			lastPrice = td.Price
+			lowPrice, highPrice = decimal.Min(lowPrice, td.Price), decimal.Max(highPrice, td.Price)
+
+			e.publishTrade(ctx, td, ord, other)
+
+			ord.Quantity = ord.Quantity.Sub(td.Quantity)
+			e.fillRestingOrder(oppositeQ, other, td.Quantity)
+			e.fillGroupOrder(ctx, other)
+			e.fillGroupOrder(ctx, ord)
 		}
+	}
 
-		// update quantity for sell order
-		if sOrd.Quantity > td.Quantity {
-			sOrd.Quantity -= td.Quantity
-		} else {
-			e.sellQ.Pop()
+	// the market price only moves after the whole incoming order is handled
+	if !lastPrice.IsZero() {
+		book.marketPrice = lastPrice
+		book.trailStopOrders(lowPrice, highPrice)
+	}
+
+	if ord.Quantity.Sign() <= 0 {
+		return
+	}
+	if book.phase == statussvc.TradingPhaseHalted {
+		e.collectAuctionOrder(ctx, book, ord)
+		return
+	}
+	e.restOrder(ctx, book, ord, lastPrice)
+}
+
+// fillRestingOrder reduces the quantity of a resting order by a fill. When the visible part of an
+// iceberg order is used up, it is refilled from the hidden quantity and loses its time priority.
+func (e *matchEngine) fillRestingOrder(q pqueue.PriorityQueue, ord *ordersvc.Order, qty decimal.Decimal) {
+	ord.Quantity = ord.Quantity.Sub(qty)
+	if ord.Quantity.Sign() <= 0 {
+		q.Delete(ord.ID)
+		return
+	}
+
+	if ord.DisplayQuantity.IsZero() {
+		return
+	}
+
+	ord.VisibleQuantity = ord.VisibleQuantity.Sub(qty)
+	if ord.VisibleQuantity.Sign() <= 0 {
+		q.Delete(ord.ID)
+		ord.VisibleQuantity = decimal.Min(ord.DisplayQuantity, ord.Quantity)
+		ord.ConfirmedAt = e.now()
+		q.Push(ord)
+	}
+}
+
+// restOrder puts the unfilled remainder of an order into the book, unless its time in force or
+// the market order policy does not allow it to rest. lastPrice is the last fill price of the order.
+func (e *matchEngine) restOrder(ctx context.Context, book *orderBook, ord *ordersvc.Order, lastPrice decimal.Decimal) {
+	switch ord.TimeInForce {
+	case ordersvc.TimeInForceIOC:
+		e.cancelOrder(ctx, ord, cancelsvc.CancelReasonImmediateOrCancel)
+		return
+	case ordersvc.TimeInForceFOK:
+		e.cancelOrder(ctx, ord, cancelsvc.CancelReasonFillOrKill)
+		return
+	}
+
+	if ord.PriceType == ordersvc.PriceTypeMarket {
+		switch e.marketOrderPolicy {
+		case MarketOrderPolicyCancel, MarketOrderPolicyReject:
+			e.cancelOrder(ctx, ord, cancelsvc.CancelReasonMarketRemainder)
+			return
+		case MarketOrderPolicyConvertToLimit:
+			if lastPrice.IsZero() {
+				e.cancelOrder(ctx, ord, cancelsvc.CancelReasonMarketRemainder)
+				return
+			}
+			ord.PriceType = ordersvc.PriceTypeLimit
+			ord.Price = lastPrice
 		}
 	}
 
-	if bOrd.Quantity > 0 {
-		e.buyQ.Push(bOrd)
+	if ord.DisplayQuantity.Sign() > 0 {
+		ord.VisibleQuantity = decimal.Min(ord.DisplayQuantity, ord.Quantity)
 	}
+	book.queue(ord.Kind).Push(ord)
+	e.expiries.schedule(ord)
 }
 
-func (e *matchEngine) handleSellOrder(ctx context.Context, sOrd *ordersvc.Order) {
-	for bOrd := e.buyQ.Peek(); bOrd != nil && sOrd.Quantity > 0; bOrd = e.buyQ.Peek() {
-		td, ok := e.match(bOrd, sOrd, matchAtMaxPrice)
-		if !ok {
-			break
+// bestOrder returns the first resting order in priority whose minimum fill quantity the incoming
+// order can meet, leaving out the skipped orders.
+func (e *matchEngine) bestOrder(q pqueue.PriorityQueue, ord *ordersvc.Order, skipped map[string]bool) *ordersvc.Order {
+	var best *ordersvc.Order
+	q.Iterate(func(other *ordersvc.Order) bool {
+		if skipped[other.ID] || other.MinFillQuantity().GreaterThan(ord.Quantity) {
+			return true
 		}
-		defer func() {
-			e.marketPrice = td.Price
-		}()
+		best = other
+		return false
+	})
+	return best
+}
 
-		out := msgsvc.NewMessage(msgsvc.MessageKindTrade, td)
-		_ = e.tradeQ.Push(ctx, out)
+// priceLevel returns the resting orders at the price level of the best resting order in time
+// priority, leaving out the skipped orders and the orders whose minimum fill quantity the incoming
+// order can not meet. The algorithms filling in time priority only get the orders up to the
+// quantity of the incoming order.
+func (e *matchEngine) priceLevel(q pqueue.PriorityQueue, ord, best *ordersvc.Order, skipped map[string]bool, algorithm MatchingAlgorithm) []*ordersvc.Order {
+	_, fifo := algorithm.(*fifoAlgorithm)
+	level, total := []*ordersvc.Order{}, decimal.Zero
+	q.Iterate(func(other *ordersvc.Order) bool {
+		if !isSamePriceLevel(best, other) {
+			// the orders ahead of the level are the ones passed over by bestOrder
+			return len(level) == 0
+		}
+		if !skipped[other.ID] && !other.MinFillQuantity().GreaterThan(ord.Quantity) {
+			level = append(level, other)
+			total = total.Add(other.BookQuantity())
+		}
+		return !fifo || total.LessThan(ord.Quantity)
+	})
+	return level
+}
 
-		// update quantity for sell order
-		if sOrd.Quantity > td.Quantity {
-			sOrd.Quantity -= td.Quantity
-		} else {
-			sOrd.Quantity = 0
+// allocateLevel allocates the incoming quantity among the orders of a price level by the matching
+// algorithm of the book. Self trade prevention applies to the orders of the same account as the
+// incoming order which are allocated a fill, and the level is allocated again without them.
+func (e *matchEngine) allocateLevel(ctx context.Context, book *orderBook, q pqueue.PriorityQueue, ord *ordersvc.Order, level []*ordersvc.Order) ([]*ordersvc.Order, []decimal.Decimal) {
+	for len(level) > 0 {
+		allocs := book.algorithm.Allocate(ord.Quantity, level)
+		self := -1
+		for i, other := range level {
+			if allocs[i].Sign() > 0 && e.isSelfTrade(ord, other) {
+				self = i
+				break
+			}
+		}
+		if self < 0 {
+			return level, allocs
 		}
 
-		// update quantity for buy order
-		if bOrd.Quantity > td.Quantity {
-			bOrd.Quantity -= td.Quantity
-		} else {
-			e.buyQ.Pop()
+		e.preventSelfTrade(ctx, q, ord, level[self])
+		if ord.Quantity.IsZero() {
+			return nil, nil
 		}
+		level = append(level[:self:self], level[self+1:]...)
+	}
+	return nil, nil
+}
+
+// isSamePriceLevel reports whether two resting orders of the same side are at the same price level.
+func isSamePriceLevel(a, b *ordersvc.Order) bool {
+	if a.PriceType == ordersvc.PriceTypeMarket || b.PriceType == ordersvc.PriceTypeMarket {
+		return a.PriceType == b.PriceType
+	}
+	return a.Price == b.Price
+}
+
+// tradePrice returns the price at which an incoming order trades with a resting order, or false if
+// they do not match.
+func (e *matchEngine) tradePrice(book *orderBook, ord, resting *ordersvc.Order) (decimal.Decimal, bool) {
+	bOrd, sOrd, isMatchAtMinPrice := ord, resting, matchAtMinPrice
+	if ord.Kind == ordersvc.OrderKindSell {
+		bOrd, sOrd, isMatchAtMinPrice = resting, ord, matchAtMaxPrice
 	}
 
-	if sOrd.Quantity > 0 {
-		e.sellQ.Push(sOrd)
+	price, ok := e.matchPrice(book, bOrd, sOrd, isMatchAtMinPrice)
+	if !ok || !isWithinProtectionPrice(bOrd, price) || !isWithinProtectionPrice(sOrd, price) {
+		return decimal.Zero, false
 	}
+	return price, true
+}
+
+// isWithinProtectionPrice reports whether a market order accepts to trade at the price.
+func isWithinProtectionPrice(ord *ordersvc.Order, price decimal.Decimal) bool {
+	if ord.PriceType != ordersvc.PriceTypeMarket || ord.ProtectionPrice.IsZero() {
+		return true
+	}
+	if ord.Kind == ordersvc.OrderKindBuy {
+		return !price.GreaterThan(ord.ProtectionPrice)
+	}
+	return !price.LessThan(ord.ProtectionPrice)
+}
+
+// newTrade returns a trade between an incoming order, the taker, and a resting order, the maker.
+func (e *matchEngine) newTrade(book *orderBook, ord, resting *ordersvc.Order, price, qty decimal.Decimal) *tradesvc.Trade {
+	bOrd, sOrd := ord, resting
+	if ord.Kind == ordersvc.OrderKindSell {
+		bOrd, sOrd = resting, ord
+	}
+
+	td := book.newTrade(bOrd, sOrd, price, qty, e.now())
+	td.AggressorSide = ord.Kind
+	td.MakerOrderID = resting.ID
+	td.TakerOrderID = ord.ID
+	return td
 }
 
-func (e *matchEngine) match(bOrd, sOrd *ordersvc.Order, isMatchAtMinPrice bool) (*tradesvc.Trade, bool) {
-	if bOrd.PriceType != ordersvc.PriceTypeMarket && sOrd.PriceType != ordersvc.PriceTypeMarket && bOrd.Price < sOrd.Price {
-		return nil, false
+// publishTrade pushes a trade to the trade queue, records the fills of its orders in store and
+// publishes an execution report for each of them.
+func (e *matchEngine) publishTrade(ctx context.Context, td *tradesvc.Trade, ord, other *ordersvc.Order) {
+	out := msgsvc.NewMessage(msgsvc.MessageKindTrade, td)
+	_ = e.tradeQ.Push(ctx, out)
+	if e.marketDataQ != nil {
+		book := e.books[td.Symbol]
+		book.mdTrades = append(book.mdTrades, publicTrade(td))
 	}
 
-	td := &tradesvc.Trade{
-		BuyOrderID:  bOrd.ID,
-		SellOrderID: sOrd.ID,
-		Timestamp:   time.Now().Unix(),
+	_ = e.orderStore.FillOrder(ctx, td.BuyOrderID, td.Price, td.Quantity, td.Timestamp)
+	_ = e.orderStore.FillOrder(ctx, td.SellOrderID, td.Price, td.Quantity, td.Timestamp)
+
+	if e.reportQ == nil {
+		return
+	}
+	for _, ord := range []*ordersvc.Order{ord, other} {
+		rpt := tradesvc.ExecutionReport{
+			TradeID:           td.ID,
+			Sequence:          td.Sequence,
+			OrderID:           ord.ID,
+			AccountID:         ord.AccountID,
+			Symbol:            td.Symbol,
+			Side:              ord.Kind,
+			Liquidity:         tradesvc.LiquidityAuction,
+			Price:             td.Price,
+			Quantity:          td.Quantity,
+			RemainingQuantity: td.BuyRemainingQuantity,
+			Timestamp:         td.Timestamp,
+		}
+		if ord.Kind == ordersvc.OrderKindSell {
+			rpt.RemainingQuantity = td.SellRemainingQuantity
+		}
+		switch ord.ID {
+		case td.MakerOrderID:
+			rpt.Liquidity = tradesvc.LiquidityMaker
+		case td.TakerOrderID:
+			rpt.Liquidity = tradesvc.LiquidityTaker
+		}
+		out := msgsvc.NewMessage(msgsvc.MessageKindExecutionReport, &rpt)
+		_ = e.reportQ.Push(ctx, out)
+	}
+}
+
+// matchPrice returns the price at which the buy and sell orders trade, or false if they do not match.
+func (e *matchEngine) matchPrice(book *orderBook, bOrd, sOrd *ordersvc.Order, isMatchAtMinPrice bool) (decimal.Decimal, bool) {
+	if bOrd.PriceType != ordersvc.PriceTypeMarket && sOrd.PriceType != ordersvc.PriceTypeMarket && bOrd.Price.LessThan(sOrd.Price) {
+		return decimal.Zero, false
 	}
 
 	switch {
 	case bOrd.PriceType == ordersvc.PriceTypeMarket && sOrd.PriceType == ordersvc.PriceTypeMarket:
-		if e.marketPrice == 0 {
-			return nil, false
+		if book.marketPrice.IsZero() {
+			return decimal.Zero, false
 		}
-		td.Price = e.marketPrice
+		return book.marketPrice, true
 	case bOrd.PriceType == ordersvc.PriceTypeMarket:
-		td.Price = sOrd.Price
+		return sOrd.Price, true
 	case sOrd.PriceType == ordersvc.PriceTypeMarket:
-		td.Price = bOrd.Price
-	case bOrd.Price >= sOrd.Price:
-		if isMatchAtMinPrice {
-			td.Price = minmax.MinFloat64(bOrd.Price, sOrd.Price)
-		} else {
-			td.Price = minmax.MaxFloat64(bOrd.Price, sOrd.Price)
+		return bOrd.Price, true
+	case isMatchAtMinPrice:
+		return decimal.Min(bOrd.Price, sOrd.Price), true
+	default:
+		return decimal.Max(bOrd.Price, sOrd.Price), true
+	}
+}
+
+// crossesBook reports whether the order would match the best order in the opposite queue.
+func (e *matchEngine) crossesBook(book *orderBook, ord *ordersvc.Order) bool {
+	best := book.oppositeQueue(ord.Kind).Peek()
+	if best == nil {
+		return false
+	}
+	_, ok := e.tradePrice(book, ord, best)
+	return ok
+}
+
+// repricePostOnlyOrder moves a crossing post only order one tick behind the best opposite price.
+// It returns false if the order can not be repriced.
+func repricePostOnlyOrder(book *orderBook, ord *ordersvc.Order) bool {
+	best := book.oppositeQueue(ord.Kind).Peek()
+	tick := book.instrument.TickSize
+	if best.PriceType == ordersvc.PriceTypeMarket || tick.Sign() <= 0 {
+		return false
+	}
+
+	if ord.Kind == ordersvc.OrderKindBuy {
+		ord.Price = best.Price.Sub(tick)
+	} else {
+		ord.Price = best.Price.Add(tick)
+	}
+	return ord.Price.Sign() > 0
+}
+
+// fillableQuantity returns how much of the order can be filled immediately by the opposite queue.
+// It plays out handleOrder on copies of the resting orders, level by level, so the matching
+// algorithm of the book, the minimum fill quantities and self trade prevention are taken into
+// account without changing the book.
+func (e *matchEngine) fillableQuantity(book *orderBook, ord *ordersvc.Order) decimal.Decimal {
+	qty, more := ord.Quantity, true
+	level := []*ordersvc.Order{}
+	book.oppositeQueue(ord.Kind).Iterate(func(other *ordersvc.Order) bool {
+		if len(level) > 0 && !isSamePriceLevel(level[0], other) {
+			qty, more = e.fillableAtLevel(book, ord, level, qty)
+			level = level[:0]
+			if !more || qty.Sign() <= 0 {
+				return false
+			}
 		}
+		if price, ok := e.tradePrice(book, ord, other); !ok || !book.isWithinPriceBand(price) {
+			more = false
+			return false
+		}
+		cp := *other
+		level = append(level, &cp)
+		return true
+	})
+	if len(level) > 0 && qty.Sign() > 0 {
+		qty, _ = e.fillableAtLevel(book, ord, level, qty)
 	}
+	return ord.Quantity.Sub(qty)
+}
 
-	td.Quantity = minmax.MinInt(bOrd.Quantity, sOrd.Quantity)
+// fillableAtLevel fills qty of the incoming order with copies of the orders of a price level the
+// way handleOrder does, and returns the quantity left and whether matching goes on to the next
+// level.
+func (e *matchEngine) fillableAtLevel(book *orderBook, ord *ordersvc.Order, level []*ordersvc.Order, qty decimal.Decimal) (decimal.Decimal, bool) {
+	skipped := map[string]bool{}
+	for qty.Sign() > 0 {
+		eligible := []*ordersvc.Order{}
+		for _, other := range level {
+			if !skipped[other.ID] && other.Quantity.Sign() > 0 && !other.MinFillQuantity().GreaterThan(qty) {
+				eligible = append(eligible, other)
+			}
+		}
+		if len(eligible) == 0 {
+			return qty, true
+		}
+
+		allocs := book.algorithm.Allocate(qty, eligible)
+		for i, other := range eligible {
+			if allocs[i].Sign() > 0 && e.isSelfTrade(ord, other) {
+				if e.stpMode != SelfTradePreventionCancelOldest {
+					// the incoming order is canceled or decremented instead of filled
+					return qty, false
+				}
+				// only canceling the resting order lets the incoming order go on
+				other.Quantity = decimal.Zero
+				allocs = nil
+				break
+			}
+		}
+		if allocs == nil {
+			continue
+		}
 
-	return td, true
+		progressed, refilled := false, map[string]bool{}
+		for i, other := range eligible {
+			if allocs[i].IsZero() {
+				continue
+			}
+			progressed = true
+			if allocs[i].LessThan(other.MinFillQuantity()) {
+				skipped[other.ID] = true
+				continue
+			}
+			qty = qty.Sub(allocs[i])
+			other.Quantity = other.Quantity.Sub(allocs[i])
+			if other.DisplayQuantity.Sign() > 0 {
+				other.VisibleQuantity = other.VisibleQuantity.Sub(allocs[i])
+				if other.VisibleQuantity.Sign() <= 0 && other.Quantity.Sign() > 0 {
+					other.VisibleQuantity = decimal.Min(other.DisplayQuantity, other.Quantity)
+					refilled[other.ID] = true
+				}
+			}
+		}
+		if !progressed {
+			return qty, true
+		}
+		level = requeueRefilled(level, refilled)
+	}
+	return qty, true
 }
 
-func (e *matchEngine) handleCancelOrder(ctx context.Context, cancel *ordersvc.Cancel) {
-	if cancel.OrderKind == ordersvc.OrderKindBuy {
-		e.buyQ.Delete(cancel.OrderID)
-	} else {
-		e.sellQ.Delete(cancel.OrderID)
+// requeueRefilled moves the refilled iceberg orders of a price level to its end, as they lose
+// their time priority.
+func requeueRefilled(level []*ordersvc.Order, refilled map[string]bool) []*ordersvc.Order {
+	if len(refilled) == 0 {
+		return level
+	}
+	out := make([]*ordersvc.Order, 0, len(level))
+	for _, other := range level {
+		if !refilled[other.ID] {
+			out = append(out, other)
+		}
 	}
+	for _, other := range level {
+		if refilled[other.ID] {
+			out = append(out, other)
+		}
+	}
+	return out
+}
 
+func (e *matchEngine) handleCancelOrder(ctx context.Context, cancel *ordersvc.Cancel) {
+	book := e.getOrderBook(ctx, cancel.Symbol)
 	ccl := cancelsvc.Cancel{
 		OrderID:     cancel.OrderID,
+		Symbol:      cancel.Symbol,
+		Reason:      cancelsvc.CancelReasonUser,
 		CreatedAt:   cancel.CreatedAt,
 		ConfirmedAt: cancel.ConfirmedAt,
 	}
+	if ord, q := book.findOrder(cancel.OrderID, cancel.OrderKind); q != nil && q.Delete(cancel.OrderID) {
+		ccl.Quantity = ord.Quantity
+	} else if ord := e.waitingGroupOrder(cancel.OrderID); ord != nil {
+		ccl.Quantity = ord.Quantity
+	} else {
+		ccl.RejectReason = e.cancelRejectReason(ctx, cancel.OrderID)
+	}
+
+	out := msgsvc.NewMessage(msgsvc.MessageKindCancel, &ccl)
+	_ = e.cancelQ.Push(ctx, out)
+	if ccl.IsRejected() {
+		_ = e.orderStore.RejectCancel(ctx, cancel.OrderID, ccl.RejectReason, e.now())
+		return
+	}
+	_ = e.orderStore.CloseOrder(ctx, cancel.OrderID, ordersvc.OrderStatusCanceled, e.now())
+
+	e.cancelGroupOrder(ctx, cancel.OrderID)
+}
+
+// cancelRejectReason returns why an order neither in book nor waiting in an order group can not
+// be canceled.
+func (e *matchEngine) cancelRejectReason(ctx context.Context, oid string) cancelsvc.CancelRejectReason {
+	ord, err := e.orderStore.GetOrder(ctx, oid)
+	if err == nil && ord.Status.IsClosed() {
+		return cancelsvc.CancelRejectReasonTooLateToCancel
+	}
+	return cancelsvc.CancelRejectReasonUnknownOrder
+}
+
+// handleAmendOrder applies an amend to a pending order. Decreasing the quantity keeps the time
+// priority of the order, while any other change puts the order back to the book as a new order.
+// An exit order waiting for its bracket entry order is amended in place, and an amend of any other
+// order which is not in book is rejected.
+func (e *matchEngine) handleAmendOrder(ctx context.Context, amend *ordersvc.Amend) {
+	book := e.getOrderBook(ctx, amend.Symbol)
+	ord, q := book.findOrder(amend.OrderID, amend.OrderKind)
+	if ord == nil {
+		if ord := e.waitingGroupOrder(amend.OrderID); ord != nil {
+			e.amendWaitingOrder(ctx, ord, amend)
+			return
+		}
+		e.rejectAmend(ctx, amend)
+		return
+	}
+
+	price, triggerPrice, qty := ord.Price, ord.TriggerPrice, ord.Quantity
+	if !amend.Price.IsZero() {
+		price = amend.Price
+	}
+	if !amend.TriggerPrice.IsZero() {
+		triggerPrice = amend.TriggerPrice
+	}
+	if !amend.Quantity.IsZero() {
+		qty = amend.Quantity
+	}
+	_ = e.orderStore.AmendOrder(ctx, *amend, e.now())
+
+	if price == ord.Price && triggerPrice == ord.TriggerPrice && !qty.GreaterThan(ord.Quantity) {
+		ord.Quantity = qty
+		if ord.DisplayQuantity.Sign() > 0 {
+			ord.VisibleQuantity = decimal.Min(ord.VisibleQuantity, qty)
+		}
+		return
+	}
+
+	q.Delete(ord.ID)
+	ord.Price, ord.TriggerPrice, ord.Quantity = price, triggerPrice, qty
+	ord.ConfirmedAt = amend.ConfirmedAt
+
+	if ord.IsStop() {
+		if !book.isTriggered(ord) {
+			q.Push(ord)
+			return
+		}
+		activateStopOrder(ord, amend.ConfirmedAt)
+	}
+
+	e.processOrder(ctx, book, ord)
+	e.triggerOrders(ctx, book)
+}
+
+// amendWaitingOrder applies an amend to an exit order waiting for its bracket entry order, which
+// is placed in book as amended once the entry order is filled.
+func (e *matchEngine) amendWaitingOrder(ctx context.Context, ord *ordersvc.Order, amend *ordersvc.Amend) {
+	if !amend.Price.IsZero() {
+		ord.Price = amend.Price
+	}
+	if !amend.TriggerPrice.IsZero() {
+		ord.TriggerPrice = amend.TriggerPrice
+	}
+	if !amend.Quantity.IsZero() {
+		ord.Quantity = amend.Quantity
+	}
+	_ = e.orderStore.AmendOrder(ctx, *amend, e.now())
+}
+
+// rejectAmend emits a rejected cancel record for an amend of an order which is not in book, and
+// records the reject in store.
+func (e *matchEngine) rejectAmend(ctx context.Context, amend *ordersvc.Amend) {
+	ccl := cancelsvc.Cancel{
+		OrderID:      amend.OrderID,
+		Symbol:       amend.Symbol,
+		Reason:       cancelsvc.CancelReasonUser,
+		RejectReason: e.cancelRejectReason(ctx, amend.OrderID),
+		Amend:        true,
+		CreatedAt:    amend.CreatedAt,
+		ConfirmedAt:  amend.ConfirmedAt,
+	}
 	out := msgsvc.NewMessage(msgsvc.MessageKindCancel, &ccl)
 	_ = e.cancelQ.Push(ctx, out)
+	_ = e.orderStore.RejectAmend(ctx, amend.OrderID, ccl.RejectReason, e.now())
+}
+
+// cancelOrder emits a cancel record for an order canceled by the engine itself.
+func (e *matchEngine) cancelOrder(ctx context.Context, ord *ordersvc.Order, reason cancelsvc.CancelReason) {
+	now := e.now()
+	ccl := cancelsvc.Cancel{
+		OrderID:     ord.ID,
+		Symbol:      ord.Symbol,
+		Reason:      reason,
+		Quantity:    ord.Quantity,
+		CreatedAt:   now,
+		ConfirmedAt: now,
+	}
+	out := msgsvc.NewMessage(msgsvc.MessageKindCancel, &ccl)
+	_ = e.cancelQ.Push(ctx, out)
+	_ = e.orderStore.CloseOrder(ctx, ord.ID, closedOrderStatus(reason), now)
+
+	e.cancelGroupOrder(ctx, ord.ID)
+}
+
+// closedOrderStatus returns the status of an order canceled by the engine for the reason.
+func closedOrderStatus(reason cancelsvc.CancelReason) ordersvc.OrderStatus {
+	switch reason {
+	case cancelsvc.CancelReasonExpired:
+		return ordersvc.OrderStatusExpired
+	case cancelsvc.CancelReasonFillOrKill, cancelsvc.CancelReasonPostOnly, cancelsvc.CancelReasonNoLiquidity, cancelsvc.CancelReasonHalted:
+		// these orders never trade before they are canceled
+		return ordersvc.OrderStatusRejected
+	default:
+		return ordersvc.OrderStatusCanceled
+	}
 }
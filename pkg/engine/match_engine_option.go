@@ -0,0 +1,188 @@
+package engine
+
+import (
+	"time"
+
+	instrumentsvc "trading-matching-service/pkg/service/instrument"
+	journalsvc "trading-matching-service/pkg/service/journal"
+	msgsvc "trading-matching-service/pkg/service/message"
+	"trading-matching-service/util/clock"
+)
+
+const (
+	defaultTimerInterval = time.Second
+	defaultHaltCooldown  = 5 * time.Minute
+	defaultResumeAuction = time.Minute
+)
+
+// PostOnlyPolicy defines how a post only order crossing the book is handled.
+type PostOnlyPolicy uint32
+
+const (
+	// PostOnlyPolicyReject rejects the order.
+	PostOnlyPolicyReject = PostOnlyPolicy(iota)
+	// PostOnlyPolicyReprice moves the order one tick behind the best opposite price.
+	PostOnlyPolicyReprice = PostOnlyPolicy(iota)
+)
+
+// SelfTradePreventionMode defines what happens when an incoming order would match a resting order
+// of the same account.
+type SelfTradePreventionMode uint32
+
+const (
+	// SelfTradePreventionNone allows orders of the same account to trade.
+	SelfTradePreventionNone = SelfTradePreventionMode(iota)
+	// SelfTradePreventionCancelNewest cancels the incoming order.
+	SelfTradePreventionCancelNewest = SelfTradePreventionMode(iota)
+	// SelfTradePreventionCancelOldest cancels the resting order.
+	SelfTradePreventionCancelOldest = SelfTradePreventionMode(iota)
+	// SelfTradePreventionCancelBoth cancels both orders.
+	SelfTradePreventionCancelBoth = SelfTradePreventionMode(iota)
+	// SelfTradePreventionDecrementAndCancel decreases both orders by the smaller quantity and cancels
+	// the ones left empty.
+	SelfTradePreventionDecrementAndCancel = SelfTradePreventionMode(iota)
+)
+
+// MarketOrderPolicy defines how the unfilled remainder of a market order is handled.
+type MarketOrderPolicy uint32
+
+const (
+	// MarketOrderPolicyRest keeps the remainder in book as a market order.
+	MarketOrderPolicyRest = MarketOrderPolicy(iota)
+	// MarketOrderPolicyCancel cancels the remainder.
+	MarketOrderPolicyCancel = MarketOrderPolicy(iota)
+	// MarketOrderPolicyConvertToLimit rests the remainder as a limit order at the last fill price,
+	// or cancels it if the order has no fill.
+	MarketOrderPolicyConvertToLimit = MarketOrderPolicy(iota)
+	// MarketOrderPolicyReject rejects the order if nothing can fill it on arrival, and cancels the
+	// remainder otherwise.
+	MarketOrderPolicyReject = MarketOrderPolicy(iota)
+)
+
+// HaltOrderPolicy defines how new orders are handled while a book is halted.
+type HaltOrderPolicy uint32
+
+const (
+	// HaltOrderPolicyAccept collects new orders for the auction resuming the book.
+	HaltOrderPolicyAccept = HaltOrderPolicy(iota)
+	// HaltOrderPolicyReject rejects new orders.
+	HaltOrderPolicyReject = HaltOrderPolicy(iota)
+)
+
+// MatchEngineOption configures a match engine.
+type MatchEngineOption func(e *matchEngine)
+
+// WithTimerInterval sets how often the match engine checks time driven events such as order expiry.
+func WithTimerInterval(interval time.Duration) MatchEngineOption {
+	return func(e *matchEngine) {
+		e.timerInterval = interval
+	}
+}
+
+// WithDayClose sets the time of day in UTC at which day orders expire.
+func WithDayClose(offset time.Duration) MatchEngineOption {
+	return func(e *matchEngine) {
+		e.dayClose = offset
+	}
+}
+
+// WithInstrumentStore sets where the match engine looks up the instrument of a symbol.
+func WithInstrumentStore(store instrumentsvc.Store) MatchEngineOption {
+	return func(e *matchEngine) {
+		e.instrumentStore = store
+	}
+}
+
+// WithPostOnlyPolicy sets how post only orders crossing the book are handled.
+func WithPostOnlyPolicy(policy PostOnlyPolicy) MatchEngineOption {
+	return func(e *matchEngine) {
+		e.postOnlyPolicy = policy
+	}
+}
+
+// WithSelfTradePrevention sets how orders of the same account are prevented from trading.
+func WithSelfTradePrevention(mode SelfTradePreventionMode) MatchEngineOption {
+	return func(e *matchEngine) {
+		e.stpMode = mode
+	}
+}
+
+// WithMarketOrderPolicy sets how the unfilled remainders of market orders are handled.
+func WithMarketOrderPolicy(policy MarketOrderPolicy) MatchEngineOption {
+	return func(e *matchEngine) {
+		e.marketOrderPolicy = policy
+	}
+}
+
+// WithAuctions sets the daily periods in which order books collect orders for a call auction
+// instead of matching them.
+func WithAuctions(windows ...AuctionWindow) MatchEngineOption {
+	return func(e *matchEngine) {
+		e.auctions = windows
+	}
+}
+
+// WithCircuitBreaker sets how long a book halted by its price band waits before resuming, and how
+// long the auction resuming it lasts.
+func WithCircuitBreaker(cooldown, resumeAuction time.Duration) MatchEngineOption {
+	return func(e *matchEngine) {
+		e.haltCooldown = cooldown
+		e.resumeAuction = resumeAuction
+	}
+}
+
+// WithHaltOrderPolicy sets how new orders are handled while a book is halted.
+func WithHaltOrderPolicy(policy HaltOrderPolicy) MatchEngineOption {
+	return func(e *matchEngine) {
+		e.haltOrderPolicy = policy
+	}
+}
+
+// WithStatusQueue sets the queue on which trading phase changes are published.
+func WithStatusQueue(statusQ msgsvc.Queue) MatchEngineOption {
+	return func(e *matchEngine) {
+		e.statusQ = statusQ
+	}
+}
+
+// WithReportQueue sets the queue on which the execution reports of the orders are published.
+func WithReportQueue(reportQ msgsvc.Queue) MatchEngineOption {
+	return func(e *matchEngine) {
+		e.reportQ = reportQ
+	}
+}
+
+// WithClock sets the clock the match engine reads the time from.
+func WithClock(clk clock.Clock) MatchEngineOption {
+	return func(e *matchEngine) {
+		e.clock = clk
+	}
+}
+
+// WithDeterministic makes the match engine stamp everything it publishes with the sequence number
+// of the inbound message being handled instead of the time, and stops its own timer, so replaying
+// the same messages produces the same output. Time driven events then only follow the timer
+// messages in the input, whose timestamps are in the same sequence units.
+func WithDeterministic() MatchEngineOption {
+	return func(e *matchEngine) {
+		e.deterministic = true
+	}
+}
+
+// WithJournal sets the journal in which the match engine keeps every inbound message before
+// handling it, and how many inbound messages apart it saves snapshots of its state, 0 for none.
+func WithJournal(j journalsvc.Journal, snapshotInterval uint64) MatchEngineOption {
+	return func(e *matchEngine) {
+		e.journal = j
+		e.snapshotInterval = snapshotInterval
+	}
+}
+
+// WithMarketDataQueue sets the queue to which the match engine publishes the trades and the
+// changes of up to the number of levels of each side of its books after each inbound message.
+func WithMarketDataQueue(marketDataQ msgsvc.Queue, levels int) MatchEngineOption {
+	return func(e *matchEngine) {
+		e.marketDataQ = marketDataQ
+		e.marketDataLevels = levels
+	}
+}
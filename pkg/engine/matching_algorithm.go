@@ -0,0 +1,127 @@
+package engine
+
+import (
+	instrumentsvc "trading-matching-service/pkg/service/instrument"
+	ordersvc "trading-matching-service/pkg/service/order"
+	"trading-matching-service/util/decimal"
+)
+
+// MatchingAlgorithm decides how an incoming quantity is shared by the orders of a price level.
+type MatchingAlgorithm interface {
+	// Allocate returns the quantity filled for each resting order of a price level, given in time
+	// priority. The allocations add up to qty, or to the whole level if it is smaller than qty.
+	Allocate(qty decimal.Decimal, level []*ordersvc.Order) []decimal.Decimal
+}
+
+// NewMatchingAlgorithm returns the matching algorithm configured for the instrument.
+func NewMatchingAlgorithm(inst instrumentsvc.Instrument) MatchingAlgorithm {
+	switch inst.MatchingAlgorithm {
+	case instrumentsvc.MatchingAlgorithmProRata:
+		return NewProRataAlgorithm(inst.QuantityStep())
+	case instrumentsvc.MatchingAlgorithmHybrid:
+		return NewHybridAlgorithm(inst.TopOrderRatio, inst.QuantityStep())
+	default:
+		return NewFIFOAlgorithm()
+	}
+}
+
+type fifoAlgorithm struct {
+}
+
+// NewFIFOAlgorithm returns an algorithm filling orders in time priority.
+func NewFIFOAlgorithm() MatchingAlgorithm {
+	return &fifoAlgorithm{}
+}
+
+func (a *fifoAlgorithm) Allocate(qty decimal.Decimal, level []*ordersvc.Order) []decimal.Decimal {
+	allocs := make([]decimal.Decimal, len(level))
+	for i := 0; i < len(level) && qty.Sign() > 0; i++ {
+		allocs[i] = decimal.Min(qty, level[i].BookQuantity())
+		qty = qty.Sub(allocs[i])
+	}
+	return allocs
+}
+
+type proRataAlgorithm struct {
+	lotSize decimal.Decimal
+}
+
+// NewProRataAlgorithm returns an algorithm filling orders in proportion to their quantities,
+// rounded down to the lot size.
+func NewProRataAlgorithm(lotSize decimal.Decimal) MatchingAlgorithm {
+	return &proRataAlgorithm{
+		lotSize: lotSize,
+	}
+}
+
+func (a *proRataAlgorithm) Allocate(qty decimal.Decimal, level []*ordersvc.Order) []decimal.Decimal {
+	return allocateProRata(qty, bookQuantities(level), a.lotSize)
+}
+
+type hybridAlgorithm struct {
+	topOrderRatio decimal.Decimal
+	lotSize       decimal.Decimal
+}
+
+// NewHybridAlgorithm returns an algorithm filling the oldest order up to topOrderRatio of the
+// incoming quantity first, and then the whole level in proportion to the remaining quantities.
+func NewHybridAlgorithm(topOrderRatio, lotSize decimal.Decimal) MatchingAlgorithm {
+	return &hybridAlgorithm{
+		topOrderRatio: topOrderRatio,
+		lotSize:       lotSize,
+	}
+}
+
+func (a *hybridAlgorithm) Allocate(qty decimal.Decimal, level []*ordersvc.Order) []decimal.Decimal {
+	capacities := bookQuantities(level)
+	if len(capacities) == 0 {
+		return capacities
+	}
+
+	top := decimal.Min(capacities[0], qty.Mul(a.topOrderRatio).Truncate(a.lotSize))
+	capacities[0] = capacities[0].Sub(top)
+
+	allocs := allocateProRata(qty.Sub(top), capacities, a.lotSize)
+	allocs[0] = allocs[0].Add(top)
+	return allocs
+}
+
+func bookQuantities(level []*ordersvc.Order) []decimal.Decimal {
+	qtys := make([]decimal.Decimal, len(level))
+	for i, ord := range level {
+		qtys[i] = ord.BookQuantity()
+	}
+	return qtys
+}
+
+// allocateProRata shares qty in proportion to the capacities, rounded down to the lot size. The
+// rounding remainder is given lot by lot in time priority.
+func allocateProRata(qty decimal.Decimal, capacities []decimal.Decimal, lotSize decimal.Decimal) []decimal.Decimal {
+	if lotSize.Sign() <= 0 {
+		lotSize = decimal.New(1)
+	}
+
+	allocs := make([]decimal.Decimal, len(capacities))
+	total := decimal.Zero
+	for _, c := range capacities {
+		total = total.Add(c)
+	}
+	if !qty.LessThan(total) {
+		copy(allocs, capacities)
+		return allocs
+	}
+
+	left := qty
+	for i, c := range capacities {
+		allocs[i] = qty.MulDiv(c, total).Truncate(lotSize)
+		left = left.Sub(allocs[i])
+	}
+	for left.Sign() > 0 {
+		for i := 0; i < len(allocs) && left.Sign() > 0; i++ {
+			add := decimal.Min(decimal.Min(lotSize, left), capacities[i].Sub(allocs[i]))
+			allocs[i] = allocs[i].Add(add)
+			left = left.Sub(add)
+		}
+	}
+	return allocs
+}
@@ -0,0 +1,48 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	ordersvc "trading-matching-service/pkg/service/order"
+	"trading-matching-service/util/decimal"
+)
+
+func Test_matchingAlgorithmAllocate(t *testing.T) {
+	level := []*ordersvc.Order{
+		{ID: "0", Quantity: decimal.New(30)},
+		{ID: "1", Quantity: decimal.New(60)},
+		{ID: "2", Quantity: decimal.New(10)},
+	}
+
+	tests := []struct {
+		name string
+		algo MatchingAlgorithm
+		qty  decimal.Decimal
+		exp  []decimal.Decimal
+	}{
+		{name: "fifo", algo: NewFIFOAlgorithm(), qty: decimal.New(50), exp: decs(30, 20, 0)},
+		{name: "fifoWholeLevel", algo: NewFIFOAlgorithm(), qty: decimal.New(150), exp: decs(30, 60, 10)},
+		{name: "proRata", algo: NewProRataAlgorithm(decimal.New(1)), qty: decimal.New(50), exp: decs(15, 30, 5)},
+		{name: "proRataRemainder", algo: NewProRataAlgorithm(decimal.New(1)), qty: decimal.New(7), exp: decs(3, 4, 0)},
+		{name: "proRataFractionalLot", algo: NewProRataAlgorithm(decimal.MustParse("0.1")), qty: decimal.MustParse("0.7"), exp: []decimal.Decimal{decimal.MustParse("0.3"), decimal.MustParse("0.4"), decimal.Zero}},
+		{name: "proRataWholeLevel", algo: NewProRataAlgorithm(decimal.New(1)), qty: decimal.New(150), exp: decs(30, 60, 10)},
+		{name: "hybrid", algo: NewHybridAlgorithm(decimal.MustParse("0.4"), decimal.New(1)), qty: decimal.New(50), exp: decs(24, 23, 3)},
+		{name: "hybridTopOrderFilled", algo: NewHybridAlgorithm(decimal.MustParse("1"), decimal.New(1)), qty: decimal.New(50), exp: decs(30, 18, 2)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.exp, tt.algo.Allocate(tt.qty, level))
+		})
+	}
+}
+
+func decs(vs ...int64) []decimal.Decimal {
+	ds := make([]decimal.Decimal, len(vs))
+	for i, v := range vs {
+		ds[i] = decimal.New(v)
+	}
+	return ds
+}
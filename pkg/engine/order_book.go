@@ -0,0 +1,137 @@
+package engine
+
+import (
+	"fmt"
+
+	"trading-matching-service/pkg/engine/pqueue"
+	booksvc "trading-matching-service/pkg/service/book"
+	instrumentsvc "trading-matching-service/pkg/service/instrument"
+	marketdatasvc "trading-matching-service/pkg/service/marketdata"
+	ordersvc "trading-matching-service/pkg/service/order"
+	statussvc "trading-matching-service/pkg/service/status"
+	tradesvc "trading-matching-service/pkg/service/trade"
+	"trading-matching-service/util/decimal"
+)
+
+// orderBook holds the pending orders and the last traded price of a symbol.
+type orderBook struct {
+	symbol     string
+	instrument instrumentsvc.Instrument
+	algorithm  MatchingAlgorithm
+	sellQ      pqueue.PriorityQueue
+	buyQ       pqueue.PriorityQueue
+
+	// stop orders waiting for the market price to reach their trigger prices
+	sellStopQ pqueue.PriorityQueue
+	buyStopQ  pqueue.PriorityQueue
+
+	marketPrice decimal.Decimal
+	phase       statussvc.TradingPhase
+	// phaseEndAt is when a halt or an auction started by it ends, 0 for no end time.
+	phaseEndAt int64
+	// tradeSeq is the sequence number of the last trade of the book.
+	tradeSeq uint64
+	// epoch tells apart the trade IDs of the match engine runs which do not keep tradeSeq, 0 for
+	// none.
+	epoch int64
+
+	// the market data last published for the book, and the trades not published yet
+	mdBids   []booksvc.PriceLevel
+	mdAsks   []booksvc.PriceLevel
+	mdTicker marketdatasvc.Ticker
+	mdTrades []marketdatasvc.Trade
+}
+
+func newOrderBook(inst instrumentsvc.Instrument) *orderBook {
+	return &orderBook{
+		symbol:     inst.Symbol,
+		instrument: inst,
+		algorithm:  NewMatchingAlgorithm(inst),
+		sellQ:      pqueue.NewRedBlackTreeQueue(lowerPriceFirst),
+		buyQ:       pqueue.NewRedBlackTreeQueue(higherPriceFirst),
+		sellStopQ:  pqueue.NewTriggerQueue(higherPriceFirst),
+		buyStopQ:   pqueue.NewTriggerQueue(lowerPriceFirst),
+	}
+}
+
+// queue returns the queue holding orders of the kind.
+func (b *orderBook) queue(kind ordersvc.OrderKind) pqueue.PriorityQueue {
+	if kind == ordersvc.OrderKindBuy {
+		return b.buyQ
+	}
+	return b.sellQ
+}
+
+// oppositeQueue returns the queue holding orders that can match orders of the kind.
+func (b *orderBook) oppositeQueue(kind ordersvc.OrderKind) pqueue.PriorityQueue {
+	if kind == ordersvc.OrderKindBuy {
+		return b.sellQ
+	}
+	return b.buyQ
+}
+
+// stopQueue returns the queue holding stop orders of the kind.
+func (b *orderBook) stopQueue(kind ordersvc.OrderKind) pqueue.PriorityQueue {
+	if kind == ordersvc.OrderKindBuy {
+		return b.buyStopQ
+	}
+	return b.sellStopQ
+}
+
+// findOrder returns the pending order of the id and the queue holding it.
+func (b *orderBook) findOrder(oid string, kind ordersvc.OrderKind) (*ordersvc.Order, pqueue.PriorityQueue) {
+	for _, q := range []pqueue.PriorityQueue{b.queue(kind), b.stopQueue(kind)} {
+		if ord := q.Get(oid); ord != nil {
+			return ord, q
+		}
+	}
+	return nil, nil
+}
+
+// isTriggered reports whether the market price has reached the trigger price of the stop order.
+func (b *orderBook) isTriggered(ord *ordersvc.Order) bool {
+	if b.marketPrice.IsZero() {
+		return false
+	}
+	if ord.Kind == ordersvc.OrderKindBuy {
+		return !b.marketPrice.LessThan(ord.TriggerPrice)
+	}
+	return !b.marketPrice.GreaterThan(ord.TriggerPrice)
+}
+
+// isWithinPriceBand reports whether a trade at the price stays within the price band around the
+// last trade price. Any price is within the band if the instrument has no band or nothing has
+// been traded yet.
+func (b *orderBook) isWithinPriceBand(price decimal.Decimal) bool {
+	band := b.instrument.PriceBandPercent
+	if band.Sign() <= 0 || b.marketPrice.IsZero() {
+		return true
+	}
+	limit := b.marketPrice.MulDiv(band, decimal.New(100))
+	diff := price.Sub(b.marketPrice)
+	return !diff.GreaterThan(limit) && !diff.Neg().GreaterThan(limit)
+}
+
+// newTrade returns the next trade of the book at ts between a buy order and a sell order, whose
+// quantities are not yet reduced by it.
+func (b *orderBook) newTrade(bOrd, sOrd *ordersvc.Order, price, qty decimal.Decimal, ts int64) *tradesvc.Trade {
+	b.tradeSeq++
+	id := fmt.Sprintf("%s-%d", b.symbol, b.tradeSeq)
+	if b.epoch != 0 {
+		id = fmt.Sprintf("%s-%d-%d", b.symbol, b.epoch, b.tradeSeq)
+	}
+	return &tradesvc.Trade{
+		ID:                    id,
+		Sequence:              b.tradeSeq,
+		Symbol:                b.symbol,
+		BuyOrderID:            bOrd.ID,
+		SellOrderID:           sOrd.ID,
+		BuyAccountID:          bOrd.AccountID,
+		SellAccountID:         sOrd.AccountID,
+		Price:                 price,
+		Quantity:              qty,
+		BuyRemainingQuantity:  bOrd.Quantity.Sub(qty),
+		SellRemainingQuantity: sOrd.Quantity.Sub(qty),
+		Timestamp:             ts,
+	}
+}
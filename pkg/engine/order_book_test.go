@@ -0,0 +1,33 @@
+package engine
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	ordersvc "trading-matching-service/pkg/service/order"
+	"trading-matching-service/util/decimal"
+)
+
+type fixedClock time.Time
+
+func (c fixedClock) Now() time.Time {
+	return time.Time(c)
+}
+
+func Test_orderBookNewTradeID(t *testing.T) {
+	bOrd := &ordersvc.Order{ID: "B1", Quantity: decimal.New(1)}
+	sOrd := &ordersvc.Order{ID: "S1", Quantity: decimal.New(1)}
+	newTradeID := func(opts ...MatchEngineOption) string {
+		e := NewMatchEngine(ordersvc.NewMemoryStore(), nil, nil, nil, opts...).(*matchEngine)
+		book := e.getOrderBook(context.Background(), "BTC-USD")
+		return book.newTrade(bOrd, sOrd, decimal.New(10), decimal.New(1), 0).ID
+	}
+
+	// a restarted match engine without a journal starts counting trades over, but not their IDs
+	assert.Equal(t, "BTC-USD-1000-1", newTradeID(WithClock(fixedClock(time.Unix(0, 1000)))))
+	assert.Equal(t, "BTC-USD-2000-1", newTradeID(WithClock(fixedClock(time.Unix(0, 2000)))))
+	assert.Equal(t, "BTC-USD-1", newTradeID(WithDeterministic()))
+}
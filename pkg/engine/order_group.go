@@ -0,0 +1,159 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+
+	cancelsvc "trading-matching-service/pkg/service/cancel"
+	msgsvc "trading-matching-service/pkg/service/message"
+	ordersvc "trading-matching-service/pkg/service/order"
+	"trading-matching-service/util/decimal"
+)
+
+// orderGroup tracks the pending orders of an order group.
+type orderGroup struct {
+	// entry is the entry order of a bracket, nil once it is filled.
+	entry *ordersvc.Order
+	// exits are the exit orders of a bracket waiting for the entry order to be filled.
+	exits []*ordersvc.Order
+	// linked are the orders of which a fill or a cancel cancels the others.
+	linked []*ordersvc.Order
+}
+
+func (e *matchEngine) handleOrderGroupCreate(ctx context.Context, msg msgsvc.Message) {
+	bs := msg.GetData()
+	grp := &ordersvc.OrderGroup{}
+	if err := json.Unmarshal(bs, grp); err != nil {
+		// not a valid message, drop it
+		return
+	}
+
+	ords := make([]*ordersvc.Order, len(grp.Orders))
+	for i := range grp.Orders {
+		ords[i] = &grp.Orders[i]
+		ords[i].Symbol = grp.Symbol
+		ords[i].GroupID = grp.ID
+	}
+
+	// the orders of an OCO group are all placed at once, while only the entry order of a bracket is
+	g, placed := &orderGroup{}, ords
+	switch {
+	case grp.Kind == ordersvc.OrderGroupKindOCO && len(ords) >= 2:
+		g.linked = ords
+	case grp.Kind == ordersvc.OrderGroupKindBracket && len(ords) == 3:
+		g.entry, g.exits = ords[0], ords[1:]
+		placed = ords[:1]
+	default:
+		// not a valid group, drop it
+		return
+	}
+	for _, ord := range ords {
+		e.groups[ord.ID] = g
+	}
+
+	book := e.getOrderBook(ctx, grp.Symbol)
+	now := e.now()
+	for _, ord := range placed {
+		// a fill or a cancel of an order placed before may have canceled the rest of the group
+		if _, ok := e.groups[ord.ID]; !ok {
+			continue
+		}
+		if e.confirmOrder(ctx, ord, now) {
+			e.placeOrder(ctx, book, ord)
+		}
+	}
+	e.triggerOrders(ctx, book)
+}
+
+// fillGroupOrder applies a fill of an order to its group. A fill cancels the other orders of an
+// OCO group, and the complete fill of a bracket entry order places its exit orders as an OCO group.
+func (e *matchEngine) fillGroupOrder(ctx context.Context, ord *ordersvc.Order) {
+	g, ok := e.groups[ord.ID]
+	if !ok {
+		return
+	}
+
+	if g.entry == nil {
+		e.cancelLinkedOrders(ctx, g, ord.ID)
+		return
+	}
+	if g.entry.ID != ord.ID || ord.Quantity.Sign() > 0 {
+		return
+	}
+
+	delete(e.groups, ord.ID)
+	g.entry, g.linked, g.exits = nil, g.exits, nil
+	e.activations = append(e.activations, g.linked...)
+}
+
+// cancelGroupOrder applies a cancel of an order to its group. The cancel of a bracket entry
+// order cancels its exit orders, and the cancel of an order of an OCO group cancels the others.
+func (e *matchEngine) cancelGroupOrder(ctx context.Context, oid string) {
+	g, ok := e.groups[oid]
+	if !ok {
+		return
+	}
+
+	if g.entry == nil {
+		e.cancelLinkedOrders(ctx, g, oid)
+		return
+	}
+
+	delete(e.groups, oid)
+	if g.entry.ID != oid {
+		// an exit order canceled before the entry order is filled is just left out
+		exits := g.exits[:0]
+		for _, ord := range g.exits {
+			if ord.ID != oid {
+				exits = append(exits, ord)
+			}
+		}
+		g.exits = exits
+		return
+	}
+
+	for _, ord := range g.exits {
+		delete(e.groups, ord.ID)
+	}
+	for _, ord := range g.exits {
+		e.cancelOrder(ctx, ord, cancelsvc.CancelReasonLinked)
+	}
+}
+
+// waitingGroupOrder returns the exit order of the id waiting for its bracket entry order to be
+// filled, or nil if there is none.
+func (e *matchEngine) waitingGroupOrder(oid string) *ordersvc.Order {
+	g, ok := e.groups[oid]
+	if !ok || g.entry == nil {
+		return nil
+	}
+	for _, ord := range g.exits {
+		if ord.ID == oid {
+			return ord
+		}
+	}
+	return nil
+}
+
+// cancelLinkedOrders dissolves an OCO group and cancels its pending orders other than oid.
+func (e *matchEngine) cancelLinkedOrders(ctx context.Context, g *orderGroup, oid string) {
+	pending := []*ordersvc.Order{}
+	for _, ord := range g.linked {
+		if e.groups[ord.ID] != g {
+			continue
+		}
+		delete(e.groups, ord.ID)
+		if ord.ID != oid {
+			pending = append(pending, ord)
+		}
+	}
+
+	for _, ord := range pending {
+		if _, q := e.getOrderBook(ctx, ord.Symbol).findOrder(ord.ID, ord.Kind); q != nil {
+			q.Delete(ord.ID)
+		}
+		e.cancelOrder(ctx, ord, cancelsvc.CancelReasonLinked)
+		// the order may be matching right now, so leaving it no quantity stops it
+		ord.Quantity = decimal.Zero
+	}
+}
@@ -8,5 +8,10 @@ type PriorityQueue interface {
 	Push(order *order.Order)
 	Pop() *order.Order
 	Peek() *order.Order
-	Delete(oid string)
+	// Delete removes the order of the id, and reports whether it was in the queue.
+	Delete(oid string) bool
+	// Get returns the order of the id, or nil if it is not in the queue.
+	Get(oid string) *order.Order
+	// Iterate calls fn for each order in priority order until fn returns false.
+	Iterate(fn func(ord *order.Order) bool)
 }
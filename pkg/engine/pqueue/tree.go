@@ -4,20 +4,39 @@ import (
 	rbt "github.com/emirpasic/gods/trees/redblacktree"
 
 	ordersvc "trading-matching-service/pkg/service/order"
+	"trading-matching-service/util/decimal"
 )
 
 type redBlackTree struct {
 	tree     *rbt.Tree
 	idKeyMap map[string]*treeKey
+	seq      uint64
+	// priceOf returns the price an order is ranked by.
+	priceOf func(ord *ordersvc.Order) decimal.Decimal
 }
 
 type treeKey struct {
 	priceType ordersvc.PriceType
-	price     float64
+	price     decimal.Decimal
 	timestamp int64
+	// seq breaks ties between orders with the same price and timestamp.
+	seq uint64
 }
 
 func NewRedBlackTreeQueue(lowerPriceFirst bool) PriorityQueue {
+	return newRedBlackTree(lowerPriceFirst, func(ord *ordersvc.Order) decimal.Decimal {
+		return ord.Price
+	})
+}
+
+// NewTriggerQueue returns a queue ranking stop orders by their trigger prices.
+func NewTriggerQueue(lowerPriceFirst bool) PriorityQueue {
+	return newRedBlackTree(lowerPriceFirst, func(ord *ordersvc.Order) decimal.Decimal {
+		return ord.TriggerPrice
+	})
+}
+
+func newRedBlackTree(lowerPriceFirst bool, priceOf func(ord *ordersvc.Order) decimal.Decimal) *redBlackTree {
 	comp := ordersvcComparatorHigherPriceFirst
 	if lowerPriceFirst {
 		comp = ordersvcComparatorLowerPriceFirst
@@ -27,14 +46,17 @@ func NewRedBlackTreeQueue(lowerPriceFirst bool) PriorityQueue {
 	return &redBlackTree{
 		tree:     tree,
 		idKeyMap: map[string]*treeKey{},
+		priceOf:  priceOf,
 	}
 }
 
 func (t *redBlackTree) Push(ord *ordersvc.Order) {
+	t.seq++
 	key := &treeKey{
 		priceType: ord.PriceType,
-		price:     ord.Price,
+		price:     t.priceOf(ord),
 		timestamp: ord.ConfirmedAt,
+		seq:       t.seq,
 	}
 	t.tree.Put(key, ord)
 	t.idKeyMap[ord.ID] = key
@@ -46,7 +68,9 @@ func (t *redBlackTree) Pop() *ordersvc.Order {
 		return nil
 	}
 	t.tree.Remove(node.Key)
-	return node.Value.(*ordersvc.Order)
+	ord := node.Value.(*ordersvc.Order)
+	delete(t.idKeyMap, ord.ID)
+	return ord
 }
 
 func (t *redBlackTree) Peek() *ordersvc.Order {
@@ -57,14 +81,36 @@ func (t *redBlackTree) Peek() *ordersvc.Order {
 	return node.Value.(*ordersvc.Order)
 }
 
-func (t *redBlackTree) Delete(oid string) {
+func (t *redBlackTree) Delete(oid string) bool {
 	key, ok := t.idKeyMap[oid]
 	if !ok {
-		return
+		return false
 	}
 	t.tree.Remove(key)
 
 	delete(t.idKeyMap, oid)
+	return true
+}
+
+func (t *redBlackTree) Get(oid string) *ordersvc.Order {
+	key, ok := t.idKeyMap[oid]
+	if !ok {
+		return nil
+	}
+	v, ok := t.tree.Get(key)
+	if !ok {
+		return nil
+	}
+	return v.(*ordersvc.Order)
+}
+
+func (t *redBlackTree) Iterate(fn func(ord *ordersvc.Order) bool) {
+	it := t.tree.Iterator()
+	for it.Next() {
+		if !fn(it.Value().(*ordersvc.Order)) {
+			return
+		}
+	}
 }
 
 func ordersvcComparatorLowerPriceFirst(a, b interface{}) int {
@@ -78,9 +124,9 @@ func ordersvcComparatorLowerPriceFirst(a, b interface{}) int {
 		return 1
 	default:
 		switch {
-		case c1.price < c2.price:
+		case c1.price.LessThan(c2.price):
 			return -1
-		case c1.price > c2.price:
+		case c1.price.GreaterThan(c2.price):
 			return 1
 		default:
 			switch {
@@ -88,6 +134,10 @@ func ordersvcComparatorLowerPriceFirst(a, b interface{}) int {
 				return 1
 			case c1.timestamp < c2.timestamp:
 				return -1
+			case c1.seq > c2.seq:
+				return 1
+			case c1.seq < c2.seq:
+				return -1
 			default:
 				return 0
 			}
@@ -106,9 +156,9 @@ func ordersvcComparatorHigherPriceFirst(a, b interface{}) int {
 		return 1
 	default:
 		switch {
-		case c1.price < c2.price:
+		case c1.price.LessThan(c2.price):
 			return 1
-		case c1.price > c2.price:
+		case c1.price.GreaterThan(c2.price):
 			return -1
 		default:
 			switch {
@@ -116,6 +166,10 @@ func ordersvcComparatorHigherPriceFirst(a, b interface{}) int {
 				return 1
 			case c1.timestamp < c2.timestamp:
 				return -1
+			case c1.seq > c2.seq:
+				return 1
+			case c1.seq < c2.seq:
+				return -1
 			default:
 				return 0
 			}
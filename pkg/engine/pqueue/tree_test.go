@@ -9,6 +9,7 @@ import (
 	"github.com/stretchr/testify/assert"
 
 	ordersvc "trading-matching-service/pkg/service/order"
+	"trading-matching-service/util/decimal"
 )
 
 func Test_redBlackTreeHigherPriceFirst(t *testing.T) {
@@ -26,25 +27,25 @@ func Test_redBlackTreeHigherPriceFirst(t *testing.T) {
 		{
 			ID:          "2",
 			PriceType:   ordersvc.PriceTypeLimit,
-			Price:       10.,
+			Price:       decimal.New(10),
 			ConfirmedAt: 1,
 		},
 		{
 			ID:          "3",
 			PriceType:   ordersvc.PriceTypeLimit,
-			Price:       10.,
+			Price:       decimal.New(10),
 			ConfirmedAt: 2,
 		},
 		{
 			ID:          "4",
 			PriceType:   ordersvc.PriceTypeLimit,
-			Price:       5.,
+			Price:       decimal.New(5),
 			ConfirmedAt: 1,
 		},
 		{
 			ID:          "5",
 			PriceType:   ordersvc.PriceTypeLimit,
-			Price:       4.,
+			Price:       decimal.New(4),
 			ConfirmedAt: 10,
 		},
 	}
@@ -82,25 +83,25 @@ func Test_redBlackTreeLowerPriceFirst(t *testing.T) {
 		{
 			ID:          "2",
 			PriceType:   ordersvc.PriceTypeLimit,
-			Price:       2.,
+			Price:       decimal.New(2),
 			ConfirmedAt: 1,
 		},
 		{
 			ID:          "3",
 			PriceType:   ordersvc.PriceTypeLimit,
-			Price:       2.,
+			Price:       decimal.New(2),
 			ConfirmedAt: 2,
 		},
 		{
 			ID:          "4",
 			PriceType:   ordersvc.PriceTypeLimit,
-			Price:       5.,
+			Price:       decimal.New(5),
 			ConfirmedAt: 1,
 		},
 		{
 			ID:          "5",
 			PriceType:   ordersvc.PriceTypeLimit,
-			Price:       6.,
+			Price:       decimal.New(6),
 			ConfirmedAt: 10,
 		},
 	}
@@ -121,3 +122,32 @@ func Test_redBlackTreeLowerPriceFirst(t *testing.T) {
 		}
 	}
 }
+
+func Test_redBlackTreeGetAndIterate(t *testing.T) {
+	ords := []*ordersvc.Order{
+		{ID: "0", PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(2), ConfirmedAt: 1},
+		{ID: "1", PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(2), ConfirmedAt: 1},
+		{ID: "2", PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(3), ConfirmedAt: 1},
+	}
+
+	q := NewRedBlackTreeQueue(true)
+	for i := range ords {
+		q.Push(ords[i])
+	}
+
+	ids := []string{}
+	q.Iterate(func(ord *ordersvc.Order) bool {
+		ids = append(ids, ord.ID)
+		return true
+	})
+	assert.Equal(t, []string{"0", "1", "2"}, ids)
+
+	assert.Equal(t, "1", q.Get("1").ID)
+	assert.Equal(t, "0", q.Pop().ID)
+	assert.Nil(t, q.Get("0"))
+
+	assert.False(t, q.Delete("0"))
+	assert.Equal(t, "1", q.Peek().ID)
+	assert.True(t, q.Delete("1"))
+	assert.Equal(t, "2", q.Peek().ID)
+}
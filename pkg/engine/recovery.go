@@ -0,0 +1,298 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+
+	"trading-matching-service/pkg/engine/pqueue"
+	journalsvc "trading-matching-service/pkg/service/journal"
+	msgsvc "trading-matching-service/pkg/service/message"
+	ordersvc "trading-matching-service/pkg/service/order"
+	statussvc "trading-matching-service/pkg/service/status"
+	"trading-matching-service/util/decimal"
+)
+
+// snapshot is the state of the match engine and its order store after an inbound message.
+type snapshot struct {
+	Sequence uint64
+	Books    []bookSnapshot
+	// Expiries are kept in heap order, so expiries due at the same time pop in the same order.
+	Expiries []expirySnapshot
+	Groups   []groupSnapshot
+	Orders   []ordersvc.Order
+}
+
+// bookSnapshot is the state of an order book, whose orders are listed in priority order.
+type bookSnapshot struct {
+	Symbol         string
+	SellOrders     []*ordersvc.Order
+	BuyOrders      []*ordersvc.Order
+	SellStopOrders []*ordersvc.Order
+	BuyStopOrders  []*ordersvc.Order
+	MarketPrice    decimal.Decimal
+	Phase          statussvc.TradingPhase
+	PhaseEndAt     int64
+	TradeSeq       uint64
+}
+
+type expirySnapshot struct {
+	ExpireAt  int64
+	OrderID   string
+	Symbol    string
+	OrderKind ordersvc.OrderKind
+}
+
+// groupSnapshot is the state of an order group. Pending are the ids of its orders still tracked by
+// the group.
+type groupSnapshot struct {
+	Entry   *ordersvc.Order
+	Exits   []*ordersvc.Order
+	Linked  []*ordersvc.Order
+	Pending []string
+}
+
+// discardQueue drops every message pushed to it.
+type discardQueue struct{}
+
+func (discardQueue) Push(ctx context.Context, msg msgsvc.Message) error {
+	return nil
+}
+
+func (discardQueue) Pop(ctx context.Context) (msgsvc.AcknowledgementMessage, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+// Recover rebuilds the state of the match engine and its order store from the latest snapshot and
+// the journal entries after it. The output of the replayed messages was published before the
+// restart, so it is not published again.
+func (e *matchEngine) Recover(ctx context.Context) error {
+	if e.journal == nil {
+		return nil
+	}
+
+	e.mux.Lock()
+	defer e.mux.Unlock()
+
+	_, data, err := e.journal.LatestSnapshot()
+	if err != nil {
+		return err
+	}
+	if data != nil {
+		if err := e.restoreSnapshot(ctx, data); err != nil {
+			return err
+		}
+	}
+
+	tradeQ, cancelQ, statusQ, reportQ := e.tradeQ, e.cancelQ, e.statusQ, e.reportQ
+	defer func() {
+		e.tradeQ, e.cancelQ, e.statusQ, e.reportQ = tradeQ, cancelQ, statusQ, reportQ
+	}()
+	e.tradeQ, e.cancelQ = discardQueue{}, discardQueue{}
+	if e.statusQ != nil {
+		e.statusQ = discardQueue{}
+	}
+	if e.reportQ != nil {
+		e.reportQ = discardQueue{}
+	}
+
+	return e.journal.Replay(e.seq, func(entry journalsvc.Entry) error {
+		msg := msgsvc.NewMessageWithBytes(entry.Kind, entry.Data)
+		e.restoreStoreOrders(ctx, msg)
+		e.seq, e.at = entry.Sequence, entry.Timestamp
+		e.dispatch(ctx, msg)
+		return nil
+	})
+}
+
+// restoreStoreOrders creates the orders of a replayed message in store, if they were created after
+// the snapshot.
+func (e *matchEngine) restoreStoreOrders(ctx context.Context, msg msgsvc.Message) {
+	ords := []ordersvc.Order{}
+	switch msg.GetKind() {
+	case msgsvc.MessageKindOrderCreate:
+		ord := ordersvc.Order{}
+		if err := json.Unmarshal(msg.GetData(), &ord); err != nil {
+			return
+		}
+		ords = append(ords, ord)
+	case msgsvc.MessageKindOrderGroupCreate:
+		grp := ordersvc.OrderGroup{}
+		if err := json.Unmarshal(msg.GetData(), &grp); err != nil {
+			return
+		}
+		ords = append(ords, grp.Orders...)
+	}
+
+	for _, ord := range ords {
+		if _, err := e.orderStore.GetOrder(ctx, ord.ID); err != nil {
+			_, _ = e.orderStore.CreateOrder(ctx, ord)
+		}
+	}
+}
+
+// takeSnapshot copies the state of the match engine and the orders of its order store, so it
+// can be written while the match engine goes on.
+func (e *matchEngine) takeSnapshot(ctx context.Context) (*snapshot, error) {
+	snap := &snapshot{Sequence: e.seq}
+	for _, book := range e.sortedBooks() {
+		snap.Books = append(snap.Books, bookSnapshot{
+			Symbol:         book.symbol,
+			SellOrders:     queueOrders(book.sellQ),
+			BuyOrders:      queueOrders(book.buyQ),
+			SellStopOrders: queueOrders(book.sellStopQ),
+			BuyStopOrders:  queueOrders(book.buyStopQ),
+			MarketPrice:    book.marketPrice,
+			Phase:          book.phase,
+			PhaseEndAt:     book.phaseEndAt,
+			TradeSeq:       book.tradeSeq,
+		})
+	}
+	for _, exp := range e.expiries {
+		snap.Expiries = append(snap.Expiries, expirySnapshot{
+			ExpireAt:  exp.expireAt,
+			OrderID:   exp.orderID,
+			Symbol:    exp.symbol,
+			OrderKind: exp.orderKind,
+		})
+	}
+	snap.Groups = e.groupSnapshots()
+
+	ords, err := e.orderStore.ListOrders(ctx)
+	if err != nil {
+		return nil, err
+	}
+	snap.Orders = ords
+	return snap, nil
+}
+
+// writeSnapshots saves the snapshots to the journal until snapshots is closed.
+func (e *matchEngine) writeSnapshots(snapshots <-chan *snapshot) {
+	for snap := range snapshots {
+		bs, err := json.Marshal(snap)
+		if err != nil {
+			continue
+		}
+		_ = e.journal.WriteSnapshot(snap.Sequence, bs)
+	}
+}
+
+// groupSnapshots returns the order groups sorted by the ids of their pending orders.
+func (e *matchEngine) groupSnapshots() []groupSnapshot {
+	oids := make([]string, 0, len(e.groups))
+	for oid := range e.groups {
+		oids = append(oids, oid)
+	}
+	sort.Strings(oids)
+
+	snaps := []groupSnapshot{}
+	index := map[*orderGroup]int{}
+	for _, oid := range oids {
+		g := e.groups[oid]
+		i, ok := index[g]
+		if !ok {
+			i = len(snaps)
+			index[g] = i
+			snaps = append(snaps, groupSnapshot{Entry: copyOrder(g.entry), Exits: copyOrders(g.exits), Linked: copyOrders(g.linked)})
+		}
+		snaps[i].Pending = append(snaps[i].Pending, oid)
+	}
+	return snaps
+}
+
+// restoreSnapshot loads the state of the match engine and its order store from a snapshot.
+func (e *matchEngine) restoreSnapshot(ctx context.Context, data []byte) error {
+	snap := snapshot{}
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return err
+	}
+
+	for _, ord := range snap.Orders {
+		if _, err := e.orderStore.CreateOrder(ctx, ord); err != nil {
+			return err
+		}
+	}
+
+	// orders of groups resting in book must be the same orders as the ones in book
+	resting := map[string]*ordersvc.Order{}
+	for _, bs := range snap.Books {
+		book := e.getOrderBook(ctx, bs.Symbol)
+		for _, qs := range []struct {
+			q    pqueue.PriorityQueue
+			ords []*ordersvc.Order
+		}{
+			{book.sellQ, bs.SellOrders},
+			{book.buyQ, bs.BuyOrders},
+			{book.sellStopQ, bs.SellStopOrders},
+			{book.buyStopQ, bs.BuyStopOrders},
+		} {
+			for _, ord := range qs.ords {
+				qs.q.Push(ord)
+				resting[ord.ID] = ord
+			}
+		}
+		book.marketPrice = bs.MarketPrice
+		book.phase = bs.Phase
+		book.phaseEndAt = bs.PhaseEndAt
+		book.tradeSeq = bs.TradeSeq
+	}
+
+	e.expiries = expiryQueue{}
+	for _, exp := range snap.Expiries {
+		e.expiries = append(e.expiries, &expiry{
+			expireAt:  exp.ExpireAt,
+			orderID:   exp.OrderID,
+			symbol:    exp.Symbol,
+			orderKind: exp.OrderKind,
+		})
+	}
+
+	resolve := func(ords []*ordersvc.Order) []*ordersvc.Order {
+		for i, ord := range ords {
+			if v, ok := resting[ord.ID]; ok {
+				ords[i] = v
+			}
+		}
+		return ords
+	}
+	for _, gs := range snap.Groups {
+		g := &orderGroup{exits: resolve(gs.Exits), linked: resolve(gs.Linked)}
+		if gs.Entry != nil {
+			g.entry = resolve([]*ordersvc.Order{gs.Entry})[0]
+		}
+		for _, oid := range gs.Pending {
+			e.groups[oid] = g
+		}
+	}
+
+	e.seq = snap.Sequence
+	return nil
+}
+
+// queueOrders returns the orders of the queue in priority order.
+func queueOrders(q pqueue.PriorityQueue) []*ordersvc.Order {
+	ords := []*ordersvc.Order{}
+	q.Iterate(func(ord *ordersvc.Order) bool {
+		ords = append(ords, copyOrder(ord))
+		return true
+	})
+	return ords
+}
+
+// copyOrders returns copies of the orders, which the match engine goes on changing.
+func copyOrders(ords []*ordersvc.Order) []*ordersvc.Order {
+	cps := make([]*ordersvc.Order, 0, len(ords))
+	for _, ord := range ords {
+		cps = append(cps, copyOrder(ord))
+	}
+	return cps
+}
+
+func copyOrder(ord *ordersvc.Order) *ordersvc.Order {
+	if ord == nil {
+		return nil
+	}
+	cp := *ord
+	return &cp
+}
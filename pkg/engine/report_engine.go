@@ -0,0 +1,53 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+
+	msgsvc "trading-matching-service/pkg/service/message"
+	tradesvc "trading-matching-service/pkg/service/trade"
+)
+
+type reportEngine struct {
+	reportQ        msgsvc.Queue
+	reportRecorder tradesvc.ReportRecorder
+}
+
+// NewReportEngine returns a report engine recording execution reports.
+func NewReportEngine(reportQ msgsvc.Queue, reportRecorder tradesvc.ReportRecorder) Engine {
+	return &reportEngine{
+		reportQ:        reportQ,
+		reportRecorder: reportRecorder,
+	}
+}
+
+func (e *reportEngine) Run(ctx context.Context) error {
+	for {
+		msg, err := e.reportQ.Pop(ctx)
+		if err != nil {
+			return err
+		}
+		e.handle(ctx, msg)
+	}
+}
+
+func (e *reportEngine) handle(ctx context.Context, msg msgsvc.AcknowledgementMessage) {
+	if msg.GetKind() != msgsvc.MessageKindExecutionReport {
+		return
+	}
+
+	bs := msg.GetData()
+	rpt := tradesvc.ExecutionReport{}
+	if err := json.Unmarshal(bs, &rpt); err != nil {
+		// not a valid message, drop it
+		msg.Ack()
+		return
+	}
+
+	if err := e.reportRecorder.CreateExecutionReport(ctx, rpt); err != nil {
+		msg.Nack()
+		return
+	}
+
+	msg.Ack()
+}
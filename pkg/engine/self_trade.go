@@ -0,0 +1,46 @@
+package engine
+
+import (
+	"context"
+
+	"trading-matching-service/pkg/engine/pqueue"
+	cancelsvc "trading-matching-service/pkg/service/cancel"
+	ordersvc "trading-matching-service/pkg/service/order"
+	"trading-matching-service/util/decimal"
+)
+
+// isSelfTrade reports whether the orders belong to the same account and must not trade.
+func (e *matchEngine) isSelfTrade(ord, resting *ordersvc.Order) bool {
+	return e.stpMode != SelfTradePreventionNone && ord.AccountID != "" && ord.AccountID == resting.AccountID
+}
+
+// preventSelfTrade applies the self trade prevention mode to an incoming order and a resting
+// order of the same account. A canceled incoming order is left with no quantity.
+func (e *matchEngine) preventSelfTrade(ctx context.Context, q pqueue.PriorityQueue, ord, resting *ordersvc.Order) {
+	cancelNewest, cancelOldest := false, false
+	switch e.stpMode {
+	case SelfTradePreventionCancelNewest:
+		cancelNewest = true
+	case SelfTradePreventionCancelOldest:
+		cancelOldest = true
+	case SelfTradePreventionCancelBoth:
+		cancelNewest, cancelOldest = true, true
+	case SelfTradePreventionDecrementAndCancel:
+		qty := decimal.Min(ord.Quantity, resting.Quantity)
+		ord.Quantity = ord.Quantity.Sub(qty)
+		resting.Quantity = resting.Quantity.Sub(qty)
+		if resting.DisplayQuantity.Sign() > 0 {
+			resting.VisibleQuantity = decimal.Min(resting.VisibleQuantity, resting.Quantity)
+		}
+		cancelNewest, cancelOldest = ord.Quantity.IsZero(), resting.Quantity.IsZero()
+	}
+
+	if cancelOldest {
+		q.Delete(resting.ID)
+		e.cancelOrder(ctx, resting, cancelsvc.CancelReasonSelfTrade)
+	}
+	if cancelNewest {
+		e.cancelOrder(ctx, ord, cancelsvc.CancelReasonSelfTrade)
+		ord.Quantity = decimal.Zero
+	}
+}
@@ -0,0 +1,53 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+
+	msgsvc "trading-matching-service/pkg/service/message"
+	statussvc "trading-matching-service/pkg/service/status"
+)
+
+type statusEngine struct {
+	statusQ        msgsvc.Queue
+	statusRecorder statussvc.Recorder
+}
+
+// NewStatusEngine returns a status engine recording trading phase changes.
+func NewStatusEngine(statusQ msgsvc.Queue, statusRecorder statussvc.Recorder) Engine {
+	return &statusEngine{
+		statusQ:        statusQ,
+		statusRecorder: statusRecorder,
+	}
+}
+
+func (e *statusEngine) Run(ctx context.Context) error {
+	for {
+		msg, err := e.statusQ.Pop(ctx)
+		if err != nil {
+			return err
+		}
+		e.handle(ctx, msg)
+	}
+}
+
+func (e *statusEngine) handle(ctx context.Context, msg msgsvc.AcknowledgementMessage) {
+	if msg.GetKind() != msgsvc.MessageKindStatus {
+		return
+	}
+
+	bs := msg.GetData()
+	st := statussvc.Status{}
+	if err := json.Unmarshal(bs, &st); err != nil {
+		// not a valid message, drop it
+		msg.Ack()
+		return
+	}
+
+	if err := e.statusRecorder.CreateStatusRecord(ctx, st); err != nil {
+		msg.Nack()
+		return
+	}
+
+	msg.Ack()
+}
@@ -0,0 +1,79 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	msgsvc "trading-matching-service/pkg/service/message"
+	statussvc "trading-matching-service/pkg/service/status"
+)
+
+// timer is the payload of a timer message.
+type timer struct {
+	Timestamp int64
+}
+
+// runTimer pushes a timer message to order queue periodically, so time driven events are
+// handled in the same loop as orders.
+func (e *matchEngine) runTimer(ctx context.Context) {
+	ticker := time.NewTicker(e.timerInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			msg := msgsvc.NewMessage(msgsvc.MessageKindTimer, &timer{Timestamp: e.clock.Now().UnixNano()})
+			_ = e.orderQ.Push(ctx, msg)
+		}
+	}
+}
+
+func (e *matchEngine) handleTimer(ctx context.Context, msg msgsvc.Message) {
+	bs := msg.GetData()
+	tm := &timer{}
+	if err := json.Unmarshal(bs, tm); err != nil {
+		// not a valid message, drop it
+		return
+	}
+
+	// any book may expire orders or change its trading phase
+	for symbol, book := range e.books {
+		e.touched[symbol] = book
+	}
+	e.expireOrders(ctx, tm.Timestamp)
+	e.updateTradingPhases(ctx, tm.Timestamp)
+}
+
+// isTimerDue reports whether a timer message has anything to do: an expiry or a trading phase
+// change that is due at its timestamp.
+func (e *matchEngine) isTimerDue(bs []byte) bool {
+	tm := &timer{}
+	if err := json.Unmarshal(bs, tm); err != nil {
+		return false
+	}
+
+	if e.expiries.Len() > 0 && e.expiries[0].expireAt <= tm.Timestamp {
+		return true
+	}
+	inAuction := e.isAuctionTime(tm.Timestamp)
+	for _, book := range e.books {
+		switch book.phase {
+		case statussvc.TradingPhaseContinuous:
+			if inAuction {
+				return true
+			}
+		case statussvc.TradingPhaseHalted:
+			if tm.Timestamp >= book.phaseEndAt {
+				return true
+			}
+		case statussvc.TradingPhaseAuction:
+			if !inAuction && tm.Timestamp >= book.phaseEndAt {
+				return true
+			}
+		}
+	}
+	return false
+}
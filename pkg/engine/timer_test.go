@@ -0,0 +1,76 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	ordersvc "trading-matching-service/pkg/service/order"
+	statussvc "trading-matching-service/pkg/service/status"
+)
+
+func Test_matchEngineIsTimerDue(t *testing.T) {
+	tick := func(ts int64) []byte {
+		bs, _ := json.Marshal(&timer{Timestamp: ts})
+		return bs
+	}
+
+	tests := []struct {
+		name  string
+		setup func(e *matchEngine)
+		ts    int64
+		exp   bool
+	}{
+		{
+			name:  "idle",
+			setup: func(e *matchEngine) { e.getOrderBook(context.Background(), "BTC-USD") },
+			ts:    100,
+		},
+		{
+			name:  "expiryNotDue",
+			setup: func(e *matchEngine) { e.expiries.schedule(&ordersvc.Order{ID: "B1", ExpireAt: 200}) },
+			ts:    100,
+		},
+		{
+			name:  "expiryDue",
+			setup: func(e *matchEngine) { e.expiries.schedule(&ordersvc.Order{ID: "B1", ExpireAt: 100}) },
+			ts:    100,
+			exp:   true,
+		},
+		{
+			name: "haltNotOver",
+			setup: func(e *matchEngine) {
+				book := e.getOrderBook(context.Background(), "BTC-USD")
+				book.phase, book.phaseEndAt = statussvc.TradingPhaseHalted, 200
+			},
+			ts: 100,
+		},
+		{
+			name: "haltOver",
+			setup: func(e *matchEngine) {
+				book := e.getOrderBook(context.Background(), "BTC-USD")
+				book.phase, book.phaseEndAt = statussvc.TradingPhaseHalted, 100
+			},
+			ts:  100,
+			exp: true,
+		},
+		{
+			name: "auctionStarts",
+			setup: func(e *matchEngine) {
+				e.getOrderBook(context.Background(), "BTC-USD")
+				e.auctions = []AuctionWindow{{Start: 50, End: 150}}
+			},
+			ts:  100,
+			exp: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := NewMatchEngine(ordersvc.NewMemoryStore(), nil, nil, nil).(*matchEngine)
+			tt.setup(e)
+			assert.Equal(t, tt.exp, e.isTimerDue(tick(tt.ts)))
+		})
+	}
+}
@@ -3,6 +3,8 @@ package engine
 import (
 	"context"
 	"encoding/json"
+
+	feesvc "trading-matching-service/pkg/service/fee"
 	msgsvc "trading-matching-service/pkg/service/message"
 	tradesvc "trading-matching-service/pkg/service/trade"
 )
@@ -10,14 +12,29 @@ import (
 type tradeEngine struct {
 	tradeQ        msgsvc.Queue
 	tradeRecorder tradesvc.Recorder
+	feeStore      feesvc.Store
+}
+
+// TradeEngineOption configures a trade engine.
+type TradeEngineOption func(*tradeEngine)
+
+// WithFeeStore sets the store of the fee schedules charged on trades and of the charged fees.
+func WithFeeStore(feeStore feesvc.Store) TradeEngineOption {
+	return func(e *tradeEngine) {
+		e.feeStore = feeStore
+	}
 }
 
 // NewTradeEngined return a trade engine.
-func NewTradeEngine(tradeQ msgsvc.Queue, tradeRecorder tradesvc.Recorder) Engine {
-	return &tradeEngine{
+func NewTradeEngine(tradeQ msgsvc.Queue, tradeRecorder tradesvc.Recorder, opts ...TradeEngineOption) Engine {
+	e := &tradeEngine{
 		tradeQ:        tradeQ,
 		tradeRecorder: tradeRecorder,
 	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
 }
 
 func (e *tradeEngine) Run(ctx context.Context) error {
@@ -43,10 +60,62 @@ func (e *tradeEngine) handle(ctx context.Context, msg msgsvc.AcknowledgementMess
 		return
 	}
 
+	fees := e.chargeFees(ctx, &td)
 	if err := e.tradeRecorder.CreateTradeRecord(ctx, td); err != nil {
 		msg.Nack()
 		return
 	}
 
+	// fees are only recorded with their trade, so a retried trade is not charged twice
+	for _, fee := range fees {
+		_ = e.feeStore.CreateFee(ctx, fee)
+	}
+
 	msg.Ack()
 }
+
+// chargeFees attaches the fees of both orders to the trade and returns them.
+func (e *tradeEngine) chargeFees(ctx context.Context, td *tradesvc.Trade) []feesvc.Fee {
+	if e.feeStore == nil {
+		return nil
+	}
+
+	fees := []feesvc.Fee{}
+	if fee, ok := e.chargeFee(ctx, td, td.BuyOrderID, td.BuyAccountID); ok {
+		td.BuyFee = fee.Amount
+		fees = append(fees, fee)
+	}
+	if fee, ok := e.chargeFee(ctx, td, td.SellOrderID, td.SellAccountID); ok {
+		td.SellFee = fee.Amount
+		fees = append(fees, fee)
+	}
+	return fees
+}
+
+// chargeFee returns the fee of an order of the trade, or false if its account pays no fee.
+func (e *tradeEngine) chargeFee(ctx context.Context, td *tradesvc.Trade, oid, aid string) (feesvc.Fee, bool) {
+	sch, ok := e.feeStore.GetSchedule(ctx, e.feeStore.GetTier(ctx, aid), td.Symbol)
+	if !ok {
+		return feesvc.Fee{}, false
+	}
+
+	liquidity := tradesvc.LiquidityAuction
+	switch oid {
+	case td.MakerOrderID:
+		liquidity = tradesvc.LiquidityMaker
+	case td.TakerOrderID:
+		liquidity = tradesvc.LiquidityTaker
+	}
+
+	rate, amount := sch.Charge(td.Price, td.Quantity, liquidity)
+	return feesvc.Fee{
+		TradeID:   td.ID,
+		OrderID:   oid,
+		AccountID: aid,
+		Symbol:    td.Symbol,
+		Liquidity: liquidity,
+		Rate:      rate,
+		Amount:    amount,
+		Timestamp: td.Timestamp,
+	}, true
+}
@@ -0,0 +1,63 @@
+package engine
+
+import (
+	"trading-matching-service/pkg/engine/pqueue"
+	ordersvc "trading-matching-service/pkg/service/order"
+	"trading-matching-service/util/decimal"
+)
+
+// trailStopOrders moves the trigger prices of the trailing stop orders after trades printed
+// between low and high.
+func (b *orderBook) trailStopOrders(low, high decimal.Decimal) {
+	for _, q := range []pqueue.PriorityQueue{b.buyStopQ, b.sellStopQ} {
+		ords := []*ordersvc.Order{}
+		q.Iterate(func(ord *ordersvc.Order) bool {
+			if ord.IsTrailingStop() {
+				ords = append(ords, ord)
+			}
+			return true
+		})
+
+		for _, ord := range ords {
+			best := high
+			if ord.Kind == ordersvc.OrderKindBuy {
+				best = low
+			}
+			if !b.moveTrailAnchor(ord, best) {
+				continue
+			}
+			// the trigger price is the key of the order in queue
+			q.Delete(ord.ID)
+			q.Push(ord)
+		}
+	}
+}
+
+// moveTrailAnchor sets the trail anchor of the order to the price if it is better than the anchor,
+// and updates the trigger price. A percentage trail is rounded down to the tick size. It reports
+// whether the order is changed.
+func (b *orderBook) moveTrailAnchor(ord *ordersvc.Order, price decimal.Decimal) bool {
+	if price.IsZero() {
+		return false
+	}
+	if !ord.TrailAnchor.IsZero() {
+		if ord.Kind == ordersvc.OrderKindBuy && !price.LessThan(ord.TrailAnchor) {
+			return false
+		}
+		if ord.Kind == ordersvc.OrderKindSell && !price.GreaterThan(ord.TrailAnchor) {
+			return false
+		}
+	}
+
+	ord.TrailAnchor = price
+	trail := ord.TrailAmount
+	if trail.IsZero() {
+		trail = price.MulDiv(ord.TrailPercent, decimal.New(100)).Truncate(b.instrument.TickSize)
+	}
+	if ord.Kind == ordersvc.OrderKindBuy {
+		ord.TriggerPrice = price.Add(trail)
+	} else {
+		ord.TriggerPrice = price.Sub(trail)
+	}
+	return true
+}
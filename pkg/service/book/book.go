@@ -0,0 +1,59 @@
+package book
+
+import (
+	"context"
+
+	ordersvc "trading-matching-service/pkg/service/order"
+	"trading-matching-service/util/decimal"
+)
+
+// PriceLevel is the orders resting in book at a price.
+type PriceLevel struct {
+	Price decimal.Decimal
+	// Quantity is the total quantity shown in book, which leaves out the hidden quantity of
+	// iceberg orders.
+	Quantity   decimal.Decimal
+	OrderCount int
+}
+
+// Depth is the price levels of a book, best first. Market orders resting in book have no price,
+// so they are left out.
+type Depth struct {
+	Symbol string
+	// Sequence is the sequence number of the last inbound message the match engine handled.
+	Sequence uint64
+	Bids     []PriceLevel
+	Asks     []PriceLevel
+}
+
+// Order is an order resting in book.
+type Order struct {
+	OrderID   string
+	PriceType ordersvc.PriceType
+	// Price is 0 for a market order, which is ahead of every limit order.
+	Price decimal.Decimal
+	// Quantity is the remaining quantity shown in book, which leaves out the hidden quantity of an
+	// iceberg order.
+	Quantity decimal.Decimal
+	// Timestamp ranks the orders at the same price, the earliest first.
+	Timestamp int64
+}
+
+// Snapshot is every order resting in a book, in priority order.
+type Snapshot struct {
+	Symbol string
+	// Sequence is the sequence number of the last inbound message the match engine handled.
+	Sequence uint64
+	Bids     []Order
+	Asks     []Order
+}
+
+// Reader defines the ways reading the books of a running match engine.
+type Reader interface {
+	// GetDepth returns up to the number of levels of each side of the book of the symbol, which is
+	// empty if the match engine has not seen the symbol yet.
+	GetDepth(ctx context.Context, symbol string, levels int) (Depth, error)
+	// GetSnapshot returns every order resting in the book of the symbol, which is empty if the
+	// match engine has not seen the symbol yet.
+	GetSnapshot(ctx context.Context, symbol string) (Snapshot, error)
+}
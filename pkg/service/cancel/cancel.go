@@ -1,7 +1,64 @@
 package cancel
 
+import (
+	"trading-matching-service/util/decimal"
+)
+
+type CancelReason uint32
+
+const (
+	CancelReasonNone = CancelReason(iota)
+	// CancelReasonUser means the order is canceled by its owner.
+	CancelReasonUser = CancelReason(iota)
+	// CancelReasonImmediateOrCancel means the unfilled remainder of an IOC order is dropped.
+	CancelReasonImmediateOrCancel = CancelReason(iota)
+	// CancelReasonFillOrKill means a FOK order could not be filled completely.
+	CancelReasonFillOrKill = CancelReason(iota)
+	// CancelReasonExpired means a GTD or day order reached its expiry.
+	CancelReasonExpired = CancelReason(iota)
+	// CancelReasonPostOnly means a post only order is rejected since it would take liquidity.
+	CancelReasonPostOnly = CancelReason(iota)
+	// CancelReasonSelfTrade means the order is canceled to prevent trading with the same account.
+	CancelReasonSelfTrade = CancelReason(iota)
+	// CancelReasonMarketRemainder means the unfilled remainder of a market order is dropped.
+	CancelReasonMarketRemainder = CancelReason(iota)
+	// CancelReasonNoLiquidity means a market order is rejected since nothing can fill it.
+	CancelReasonNoLiquidity = CancelReason(iota)
+	// CancelReasonHalted means the order is rejected since the book is halted.
+	CancelReasonHalted = CancelReason(iota)
+	// CancelReasonLinked means the order is canceled by a fill or a cancel of a linked order of
+	// its order group.
+	CancelReasonLinked = CancelReason(iota)
+)
+
+// CancelRejectReason tells why a cancel request of the owner could not cancel the order.
+type CancelRejectReason uint32
+
+const (
+	CancelRejectReasonNone = CancelRejectReason(iota)
+	// CancelRejectReasonTooLateToCancel means the order was already filled or closed.
+	CancelRejectReasonTooLateToCancel = CancelRejectReason(iota)
+	// CancelRejectReasonUnknownOrder means the match engine has never seen the order.
+	CancelRejectReasonUnknownOrder = CancelRejectReason(iota)
+)
+
+// Cancel is the result of a cancel. A rejected cancel request of the owner has a RejectReason and
+// leaves the order untouched. A rejected amend request is published as a rejected cancel too.
 type Cancel struct {
-	OrderID     string
+	OrderID string
+	Symbol  string
+	Reason  CancelReason
+	// RejectReason is why the cancel was rejected, none for a successful cancel.
+	RejectReason CancelRejectReason
+	// Amend is whether the rejected request was an amend of the order.
+	Amend bool
+	// Quantity is the unfilled remainder taken out of book by the cancel.
+	Quantity    decimal.Decimal
 	CreatedAt   int64
 	ConfirmedAt int64
 }
+
+// IsRejected reports whether the cancel was rejected.
+func (c *Cancel) IsRejected() bool {
+	return c.RejectReason != CancelRejectReasonNone
+}
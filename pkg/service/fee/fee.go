@@ -0,0 +1,57 @@
+package fee
+
+import (
+	tradesvc "trading-matching-service/pkg/service/trade"
+	"trading-matching-service/util/decimal"
+)
+
+// DefaultTier is the tier of the accounts without one.
+const DefaultTier = "default"
+
+// Schedule is the fees of an account tier for an instrument. Rates are fractions of the traded
+// notional, and a negative rate is a rebate.
+type Schedule struct {
+	Tier string `json:"tier"`
+	// Symbol is the instrument of the schedule, empty for the instruments without their own schedule.
+	Symbol    string          `json:"symbol"`
+	MakerRate decimal.Decimal `json:"maker_rate" swaggertype:"number"`
+	TakerRate decimal.Decimal `json:"taker_rate" swaggertype:"number"`
+	// MinFee is the smallest fee charged for a fill. Rebates are not raised to it.
+	MinFee decimal.Decimal `json:"min_fee" swaggertype:"number"`
+}
+
+// Config is the fee schedules and the tiers of the accounts.
+type Config struct {
+	// Accounts maps account ids to tiers. Other accounts are in DefaultTier.
+	Accounts  map[string]string `json:"accounts"`
+	Schedules []Schedule        `json:"schedules"`
+}
+
+// Fee is charged to an account for a fill of its order, negative for a rebate.
+type Fee struct {
+	TradeID   string             `json:"trade_id"`
+	OrderID   string             `json:"order_id"`
+	AccountID string             `json:"account_id"`
+	Symbol    string             `json:"symbol"`
+	Liquidity tradesvc.Liquidity `json:"liquidity"`
+	Rate      decimal.Decimal    `json:"rate" swaggertype:"number"`
+	Amount    decimal.Decimal    `json:"amount" swaggertype:"number"`
+	// Timestamp is the unix timestamp in nanoseconds of the trade.
+	Timestamp int64 `json:"timestamp"`
+}
+
+// Charge returns the rate and the fee of a fill of the quantity at the price. Fills of an auction
+// uncross are charged the taker rate. The fee is computed without the notional in between, which
+// may be out of range of decimals while the fee is not.
+func (s Schedule) Charge(price, qty decimal.Decimal, liquidity tradesvc.Liquidity) (decimal.Decimal, decimal.Decimal) {
+	rate := s.TakerRate
+	if liquidity == tradesvc.LiquidityMaker {
+		rate = s.MakerRate
+	}
+
+	amount := decimal.Product(price, qty, rate)
+	if rate.Sign() >= 0 && amount.LessThan(s.MinFee) {
+		amount = s.MinFee
+	}
+	return rate, amount
+}
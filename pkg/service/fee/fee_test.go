@@ -0,0 +1,66 @@
+package fee
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	tradesvc "trading-matching-service/pkg/service/trade"
+	"trading-matching-service/util/decimal"
+)
+
+func TestScheduleCharge(t *testing.T) {
+	sch := Schedule{MakerRate: decimal.MustParse("-0.0001"), TakerRate: decimal.MustParse("0.001"), MinFee: decimal.New(1)}
+
+	rate, amount := sch.Charge(decimal.New(100), decimal.New(100), tradesvc.LiquidityMaker)
+	assert.Equal(t, decimal.MustParse("-0.0001"), rate)
+	assert.Equal(t, decimal.New(-1), amount)
+
+	// the rebate is not raised to the minimum fee, while the taker fee is
+	_, amount = sch.Charge(decimal.New(10), decimal.New(10), tradesvc.LiquidityMaker)
+	assert.Equal(t, decimal.MustParse("-0.01"), amount)
+	_, amount = sch.Charge(decimal.New(10), decimal.New(10), tradesvc.LiquidityTaker)
+	assert.Equal(t, decimal.New(1), amount)
+
+	// auction fills pay the taker rate
+	rate, amount = sch.Charge(decimal.New(100), decimal.New(100), tradesvc.LiquidityAuction)
+	assert.Equal(t, decimal.MustParse("0.001"), rate)
+	assert.Equal(t, decimal.New(10), amount)
+
+	// the notional of a large fill is out of range of decimals, while its fee is not
+	_, amount = sch.Charge(decimal.New(500), decimal.New(200000000), tradesvc.LiquidityTaker)
+	assert.Equal(t, decimal.New(100000000), amount)
+}
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore(Config{
+		Accounts: map[string]string{"A1": "vip"},
+		Schedules: []Schedule{
+			{Tier: DefaultTier, TakerRate: decimal.MustParse("0.002")},
+			{Tier: "vip", TakerRate: decimal.MustParse("0.001")},
+			{Tier: "vip", Symbol: "ETH-USD"},
+		},
+	})
+
+	assert.Equal(t, "vip", s.GetTier(ctx, "A1"))
+	assert.Equal(t, DefaultTier, s.GetTier(ctx, "A2"))
+
+	sch, ok := s.GetSchedule(ctx, "vip", "BTC-USD")
+	assert.True(t, ok)
+	assert.Equal(t, decimal.MustParse("0.001"), sch.TakerRate)
+	sch, ok = s.GetSchedule(ctx, "vip", "ETH-USD")
+	assert.True(t, ok)
+	assert.True(t, sch.TakerRate.IsZero())
+	_, ok = s.GetSchedule(ctx, "unknown", "BTC-USD")
+	assert.False(t, ok)
+	assert.Len(t, s.ListSchedules(ctx, "vip"), 2)
+
+	assert.NoError(t, s.CreateFee(ctx, Fee{AccountID: "A1", Amount: decimal.New(1), Timestamp: 10}))
+	assert.NoError(t, s.CreateFee(ctx, Fee{AccountID: "A1", Amount: decimal.New(2), Timestamp: 20}))
+	fees, err := s.ListFees(ctx, "A1", 10, 20)
+	assert.NoError(t, err)
+	assert.Len(t, fees, 1)
+	assert.Equal(t, decimal.New(1), fees[0].Amount)
+}
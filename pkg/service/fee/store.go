@@ -0,0 +1,105 @@
+package fee
+
+import (
+	"context"
+	"sync"
+)
+
+// Store defines the ways querying fee schedules and recording fees.
+type Store interface {
+	// GetTier returns the tier of the account.
+	GetTier(ctx context.Context, aid string) string
+	// GetSchedule returns the schedule of the tier for the symbol, or false if the tier pays no fee.
+	GetSchedule(ctx context.Context, tier, symbol string) (Schedule, bool)
+	// ListSchedules returns the schedules of the tier.
+	ListSchedules(ctx context.Context, tier string) []Schedule
+	// CreateFee records a fee.
+	CreateFee(ctx context.Context, fee Fee) error
+	// ListFees returns the fees of the account charged from from until before to, in unix nanoseconds.
+	ListFees(ctx context.Context, aid string, from, to int64) ([]Fee, error)
+}
+
+type memoryStore struct {
+	mux       sync.RWMutex
+	accounts  map[string]string
+	schedules []Schedule
+	fees      map[string][]Fee
+}
+
+// NewMemoryStore returns a memory store with the given config.
+func NewMemoryStore(cfg Config) Store {
+	accounts := make(map[string]string, len(cfg.Accounts))
+	for aid, tier := range cfg.Accounts {
+		accounts[aid] = tier
+	}
+
+	return &memoryStore{
+		accounts:  accounts,
+		schedules: append([]Schedule{}, cfg.Schedules...),
+		fees:      map[string][]Fee{},
+	}
+}
+
+func (s *memoryStore) GetTier(ctx context.Context, aid string) string {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	if tier, ok := s.accounts[aid]; ok {
+		return tier
+	}
+	return DefaultTier
+}
+
+func (s *memoryStore) GetSchedule(ctx context.Context, tier, symbol string) (Schedule, bool) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	sch, ok := Schedule{}, false
+	for _, v := range s.schedules {
+		if v.Tier != tier {
+			continue
+		}
+		if v.Symbol == symbol {
+			return v, true
+		}
+		if v.Symbol == "" {
+			sch, ok = v, true
+		}
+	}
+	return sch, ok
+}
+
+func (s *memoryStore) ListSchedules(ctx context.Context, tier string) []Schedule {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	schedules := []Schedule{}
+	for _, v := range s.schedules {
+		if v.Tier == tier {
+			schedules = append(schedules, v)
+		}
+	}
+	return schedules
+}
+
+func (s *memoryStore) CreateFee(ctx context.Context, fee Fee) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	s.fees[fee.AccountID] = append(s.fees[fee.AccountID], fee)
+
+	return nil
+}
+
+func (s *memoryStore) ListFees(ctx context.Context, aid string, from, to int64) ([]Fee, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	fees := []Fee{}
+	for _, fee := range s.fees[aid] {
+		if from <= fee.Timestamp && fee.Timestamp < to {
+			fees = append(fees, fee)
+		}
+	}
+	return fees, nil
+}
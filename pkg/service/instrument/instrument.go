@@ -0,0 +1,42 @@
+package instrument
+
+import (
+	"trading-matching-service/util/decimal"
+)
+
+type MatchingAlgorithm string
+
+const (
+	// MatchingAlgorithmFIFO fills orders of a price level in time priority.
+	MatchingAlgorithmFIFO = MatchingAlgorithm("fifo")
+	// MatchingAlgorithmProRata fills orders of a price level in proportion to their quantities.
+	MatchingAlgorithmProRata = MatchingAlgorithm("pro-rata")
+	// MatchingAlgorithmHybrid fills the oldest order of a price level up to TopOrderRatio of the
+	// incoming quantity first, and then fills the orders in proportion to their quantities.
+	MatchingAlgorithmHybrid = MatchingAlgorithm("hybrid")
+)
+
+// Instrument defines a tradable instrument.
+type Instrument struct {
+	Symbol string `json:"symbol"`
+	// TickSize is the minimum price increment, any price is allowed when unset.
+	TickSize decimal.Decimal `json:"tick_size"`
+	// LotSize is the minimum quantity increment, 1 when unset. A fractional lot size allows
+	// fractional quantities.
+	LotSize decimal.Decimal `json:"lot_size"`
+	// MatchingAlgorithm decides how a price level is filled, FIFO by default.
+	MatchingAlgorithm MatchingAlgorithm `json:"matching_algorithm"`
+	// TopOrderRatio is the part of an incoming quantity given to the oldest order first by hybrid matching.
+	TopOrderRatio decimal.Decimal `json:"top_order_ratio"`
+	// PriceBandPercent halts the book when a trade would print more than this percentage away from
+	// the last trade price, 0 for no band.
+	PriceBandPercent decimal.Decimal `json:"price_band_percent"`
+}
+
+// QuantityStep returns the lot size of the instrument, which is 1 when unset.
+func (i Instrument) QuantityStep() decimal.Decimal {
+	if i.LotSize.Sign() <= 0 {
+		return decimal.New(1)
+	}
+	return i.LotSize
+}
@@ -0,0 +1,42 @@
+package instrument
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// Store defines the ways querying instruments.
+type Store interface {
+	// GetInstrument returns the instrument of the symbol.
+	GetInstrument(ctx context.Context, symbol string) (Instrument, error)
+}
+
+type memoryStore struct {
+	mux  sync.RWMutex
+	pool map[string]Instrument
+}
+
+// NewMemoryStore returns a memory store with the given instruments.
+func NewMemoryStore(instruments ...Instrument) Store {
+	pool := make(map[string]Instrument, len(instruments))
+	for _, inst := range instruments {
+		pool[inst.Symbol] = inst
+	}
+
+	return &memoryStore{
+		pool: pool,
+	}
+}
+
+func (s *memoryStore) GetInstrument(ctx context.Context, symbol string) (Instrument, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	inst, ok := s.pool[symbol]
+	if !ok {
+		return inst, errors.New("unknown symbol")
+	}
+
+	return inst, nil
+}
@@ -0,0 +1,316 @@
+package journal
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	segmentPrefix  = "journal-"
+	segmentSuffix  = ".log"
+	snapshotPrefix = "snapshot-"
+	snapshotSuffix = ".json"
+	snapshotTemp   = ".snapshot.tmp"
+)
+
+// fileJournal keeps the journal in segment files of json lines. Each segment is named by the
+// sequence number of its first entry, and a snapshot drops the segments it covers.
+type fileJournal struct {
+	mux     sync.Mutex
+	cfg     Config
+	file    *os.File
+	segment uint64
+	size    int64
+	// next is the sequence number after the last appended entry, or 0 if none was appended since
+	// the journal was opened.
+	next     uint64
+	lastSync time.Time
+}
+
+// NewFileJournal opens the journal in the directory of the config, creating it if needed. An
+// entry torn by a crash at the end of the journal is cut off.
+func NewFileJournal(cfg Config) (Journal, error) {
+	if err := os.MkdirAll(cfg.Dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	segs, err := listFiles(cfg.Dir, segmentPrefix, segmentSuffix)
+	if err != nil {
+		return nil, err
+	}
+	segment := uint64(1)
+	if len(segs) > 0 {
+		segment = segs[len(segs)-1]
+	}
+
+	path := filepath.Join(cfg.Dir, fileName(segmentPrefix, segment, segmentSuffix))
+	size, err := cutTornTail(path)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	return &fileJournal{
+		cfg:      cfg,
+		file:     f,
+		segment:  segment,
+		size:     size,
+		lastSync: time.Now(),
+	}, nil
+}
+
+func (j *fileJournal) Append(entry Entry) error {
+	bs, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	j.mux.Lock()
+	defer j.mux.Unlock()
+
+	n, err := j.file.Write(append(bs, '\n'))
+	if err == nil {
+		err = j.sync()
+	}
+	if err != nil {
+		// drop what was written of the entry, so the next one starts on a clean line and an entry
+		// appended again is not journaled twice
+		_ = j.file.Truncate(j.size)
+		return err
+	}
+	j.size += int64(n)
+	j.next = entry.Sequence + 1
+	return nil
+}
+
+// sync flushes the writes of the current segment to disk as the sync policy decides.
+func (j *fileJournal) sync() error {
+	switch j.cfg.SyncPolicy {
+	case SyncPolicyAlways:
+		return j.file.Sync()
+	case SyncPolicyInterval:
+		if time.Since(j.lastSync) < j.cfg.SyncInterval {
+			return nil
+		}
+		j.lastSync = time.Now()
+		return j.file.Sync()
+	default:
+		return nil
+	}
+}
+
+func (j *fileJournal) WriteSnapshot(seq uint64, data []byte) error {
+	j.mux.Lock()
+	defer j.mux.Unlock()
+
+	if err := writeFileSync(j.cfg.Dir, fileName(snapshotPrefix, seq, snapshotSuffix), data); err != nil {
+		return err
+	}
+
+	// the snapshot may be written after entries following it were appended, so the next segment
+	// starts at the next entry and only the segments ending at the snapshot are dropped
+	next := j.next
+	if next == 0 {
+		next = seq + 1
+	}
+	if j.segment != next {
+		f, err := os.OpenFile(filepath.Join(j.cfg.Dir, fileName(segmentPrefix, next, segmentSuffix)), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return err
+		}
+		_ = j.file.Sync()
+		_ = j.file.Close()
+		j.file, j.segment, j.size = f, next, 0
+	}
+
+	segs, err := listFiles(j.cfg.Dir, segmentPrefix, segmentSuffix)
+	if err != nil {
+		return err
+	}
+	for i := 0; i+1 < len(segs); i++ {
+		if segs[i+1] <= seq+1 {
+			_ = os.Remove(filepath.Join(j.cfg.Dir, fileName(segmentPrefix, segs[i], segmentSuffix)))
+		}
+	}
+	snaps, err := listFiles(j.cfg.Dir, snapshotPrefix, snapshotSuffix)
+	if err != nil {
+		return err
+	}
+	for _, s := range snaps {
+		if s < seq {
+			_ = os.Remove(filepath.Join(j.cfg.Dir, fileName(snapshotPrefix, s, snapshotSuffix)))
+		}
+	}
+	return nil
+}
+
+func (j *fileJournal) LatestSnapshot() (uint64, []byte, error) {
+	j.mux.Lock()
+	defer j.mux.Unlock()
+
+	snaps, err := listFiles(j.cfg.Dir, snapshotPrefix, snapshotSuffix)
+	if err != nil || len(snaps) == 0 {
+		return 0, nil, err
+	}
+	seq := snaps[len(snaps)-1]
+	data, err := os.ReadFile(filepath.Join(j.cfg.Dir, fileName(snapshotPrefix, seq, snapshotSuffix)))
+	if err != nil {
+		return 0, nil, err
+	}
+	return seq, data, nil
+}
+
+func (j *fileJournal) Replay(after uint64, fn func(entry Entry) error) error {
+	j.mux.Lock()
+	defer j.mux.Unlock()
+
+	segs, err := listFiles(j.cfg.Dir, segmentPrefix, segmentSuffix)
+	if err != nil {
+		return err
+	}
+	for _, s := range segs {
+		if err := replaySegment(filepath.Join(j.cfg.Dir, fileName(segmentPrefix, s, segmentSuffix)), after, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (j *fileJournal) Close() error {
+	j.mux.Lock()
+	defer j.mux.Unlock()
+
+	if err := j.file.Sync(); err != nil {
+		return err
+	}
+	return j.file.Close()
+}
+
+// replaySegment calls fn for each entry of the segment after the sequence number.
+func replaySegment(path string, after uint64, fn func(entry Entry) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			// a line without its newline was torn by a crash and never handled
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		entry := Entry{}
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return fmt.Errorf("corrupted journal %s: %v", path, err)
+		}
+		if entry.Sequence <= after {
+			continue
+		}
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+}
+
+// cutTornTail truncates the segment after its last complete entry and returns its size. A missing
+// segment has size 0.
+func cutTornTail(path string) (int64, error) {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	size := int64(0)
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadBytes('\n')
+		if err != nil || !json.Valid(line) {
+			break
+		}
+		size += int64(len(line))
+	}
+	return size, f.Truncate(size)
+}
+
+// writeFileSync writes the file through a temporary file, so it is either complete or missing
+// after a crash.
+func writeFileSync(dir, name string, data []byte) error {
+	tmp := filepath.Join(dir, snapshotTemp)
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, filepath.Join(dir, name)); err != nil {
+		return err
+	}
+
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+// fileName returns the name of a segment or a snapshot file.
+func fileName(prefix string, seq uint64, suffix string) string {
+	return fmt.Sprintf("%s%020d%s", prefix, seq, suffix)
+}
+
+// listFiles returns the sequence numbers of the files in the directory with the prefix and the
+// suffix in ascending order.
+func listFiles(dir, prefix, suffix string) ([]uint64, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	seqs := []uint64{}
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, suffix) {
+			continue
+		}
+		seq, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimPrefix(name, prefix), suffix), 10, 64)
+		if err != nil {
+			continue
+		}
+		seqs = append(seqs, seq)
+	}
+	sort.Slice(seqs, func(i, j int) bool { return seqs[i] < seqs[j] })
+	return seqs, nil
+}
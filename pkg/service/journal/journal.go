@@ -0,0 +1,55 @@
+package journal
+
+import (
+	"encoding/json"
+	"time"
+
+	msgsvc "trading-matching-service/pkg/service/message"
+)
+
+// SyncPolicy decides when the journal flushes its writes to disk.
+type SyncPolicy uint32
+
+const (
+	// SyncPolicyAlways syncs every entry before it is handled.
+	SyncPolicyAlways = SyncPolicy(iota)
+	// SyncPolicyInterval syncs on the first entry after the sync interval has passed.
+	SyncPolicyInterval = SyncPolicy(iota)
+	// SyncPolicyNone leaves flushing to the operating system.
+	SyncPolicyNone = SyncPolicy(iota)
+)
+
+// Entry is an inbound message accepted by the match engine.
+type Entry struct {
+	// Sequence numbers the inbound messages of the match engine from 1.
+	Sequence uint64
+	// Timestamp is the time in nanoseconds at which the match engine handled the message.
+	Timestamp int64
+	Kind      msgsvc.MessageKind
+	Data      json.RawMessage
+}
+
+// Config defines where and how a journal is written.
+type Config struct {
+	// Dir is the directory holding the journal and its snapshots.
+	Dir          string
+	SyncPolicy   SyncPolicy
+	SyncInterval time.Duration
+}
+
+// Journal defines the ways keeping the inbound messages of the match engine and the snapshots of
+// its state.
+type Journal interface {
+	// Append appends an entry to the journal.
+	Append(entry Entry) error
+	// WriteSnapshot saves the state of the match engine after the entry of the sequence number, and
+	// drops the entries it covers. Entries after the snapshot may already be appended.
+	WriteSnapshot(seq uint64, data []byte) error
+	// LatestSnapshot returns the latest snapshot and its sequence number, or nil data if there is
+	// no snapshot.
+	LatestSnapshot() (uint64, []byte, error)
+	// Replay calls fn for each entry after the sequence number in order.
+	Replay(after uint64, fn func(entry Entry) error) error
+	// Close syncs and closes the journal.
+	Close() error
+}
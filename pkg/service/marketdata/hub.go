@@ -0,0 +1,249 @@
+package marketdata
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	booksvc "trading-matching-service/pkg/service/book"
+	"trading-matching-service/util/decimal"
+)
+
+// Publisher defines the ways publishing the market data of the match engine.
+type Publisher interface {
+	// Publish applies an update of a book and sends its events to the subscribed clients.
+	Publish(ctx context.Context, upd Update) error
+}
+
+// Hub keeps the market data of every book and fans its events out to clients.
+type Hub interface {
+	Publisher
+	// NewClient returns a client without any subscription.
+	NewClient() *Client
+}
+
+// bookState is the market data of a book as the clients see it. Its slices are replaced instead
+// of changed, so they can be shared with the events already sent.
+type bookState struct {
+	bids   []booksvc.PriceLevel
+	asks   []booksvc.PriceLevel
+	ticker Ticker
+	// trades are the most recent trades, the oldest first.
+	trades []Trade
+	// seqs are the sequence numbers of the last events of each channel.
+	seqs map[Channel]uint64
+}
+
+type subscription struct {
+	channel Channel
+	symbol  string
+}
+
+type memoryHub struct {
+	mux          sync.Mutex
+	books        map[string]*bookState
+	clients      map[*Client]struct{}
+	bufferSize   int
+	recentTrades int
+}
+
+// NewMemoryHub returns a hub buffering up to bufferSize events for each client, and sending the
+// recentTrades most recent trades as the snapshot of the trades channel.
+func NewMemoryHub(bufferSize, recentTrades int) Hub {
+	return &memoryHub{
+		books:        map[string]*bookState{},
+		clients:      map[*Client]struct{}{},
+		bufferSize:   bufferSize,
+		recentTrades: recentTrades,
+	}
+}
+
+func (h *memoryHub) NewClient() *Client {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+
+	c := &Client{
+		hub:    h,
+		events: make(chan Event, h.bufferSize),
+		subs:   map[subscription]struct{}{},
+	}
+	h.clients[c] = struct{}{}
+	return c
+}
+
+func (h *memoryHub) Publish(ctx context.Context, upd Update) error {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+
+	st := h.getBookState(upd.Symbol)
+	events := []Event{}
+	if upd.Snapshot {
+		st.bids, st.asks = upd.Bids, upd.Asks
+		if upd.Ticker != nil {
+			st.ticker = *upd.Ticker
+		}
+		st.seqs[ChannelDepth], st.seqs[ChannelTicker] = upd.Sequence, upd.Sequence
+		events = append(events, st.snapshot(upd.Symbol, ChannelDepth), st.snapshot(upd.Symbol, ChannelTicker))
+	} else {
+		if len(upd.Trades) > 0 {
+			trades := append(append([]Trade{}, st.trades...), upd.Trades...)
+			if len(trades) > h.recentTrades {
+				trades = trades[len(trades)-h.recentTrades:]
+			}
+			st.trades = trades
+			events = append(events, st.update(upd, ChannelTrades, Event{Trades: upd.Trades}))
+		}
+		if len(upd.Bids) > 0 || len(upd.Asks) > 0 {
+			st.bids = applyLevels(st.bids, upd.Bids, true)
+			st.asks = applyLevels(st.asks, upd.Asks, false)
+			events = append(events, st.update(upd, ChannelDepth, Event{Bids: upd.Bids, Asks: upd.Asks}))
+		}
+		if upd.Ticker != nil {
+			st.ticker = *upd.Ticker
+			events = append(events, st.update(upd, ChannelTicker, Event{Ticker: upd.Ticker}))
+		}
+	}
+
+	for c := range h.clients {
+		for _, ev := range events {
+			if _, ok := c.subs[subscription{ev.Channel, ev.Symbol}]; ok {
+				c.send(ev)
+			}
+		}
+	}
+	return nil
+}
+
+func (h *memoryHub) getBookState(symbol string) *bookState {
+	st, ok := h.books[symbol]
+	if !ok {
+		st = &bookState{
+			bids:   []booksvc.PriceLevel{},
+			asks:   []booksvc.PriceLevel{},
+			trades: []Trade{},
+			seqs:   map[Channel]uint64{},
+		}
+		h.books[symbol] = st
+	}
+	return st
+}
+
+// closeClient drops the client and closes its events.
+func (h *memoryHub) closeClient(c *Client) {
+	if c.closed {
+		return
+	}
+	c.closed = true
+	delete(h.clients, c)
+	close(c.events)
+}
+
+// snapshot returns the snapshot event of the channel.
+func (st *bookState) snapshot(symbol string, channel Channel) Event {
+	ev := Event{Channel: channel, Symbol: symbol, Type: EventTypeSnapshot, Sequence: st.seqs[channel]}
+	switch channel {
+	case ChannelTrades:
+		ev.Trades = st.trades
+	case ChannelDepth:
+		ev.Bids, ev.Asks = st.bids, st.asks
+	case ChannelTicker:
+		ticker := st.ticker
+		ev.Ticker = &ticker
+	}
+	return ev
+}
+
+// update fills in the header of an update event of the channel and moves the channel to the
+// sequence number of the update.
+func (st *bookState) update(upd Update, channel Channel, ev Event) Event {
+	ev.Channel, ev.Symbol, ev.Type = channel, upd.Symbol, EventTypeUpdate
+	ev.Sequence, ev.PrevSequence = upd.Sequence, st.seqs[channel]
+	st.seqs[channel] = upd.Sequence
+	return ev
+}
+
+// applyLevels returns the price levels with the changes applied, best first.
+func applyLevels(lvls, changes []booksvc.PriceLevel, higherPriceFirst bool) []booksvc.PriceLevel {
+	if len(changes) == 0 {
+		return lvls
+	}
+
+	out := make([]booksvc.PriceLevel, 0, len(lvls)+len(changes))
+	changed := map[decimal.Decimal]bool{}
+	for _, lvl := range changes {
+		changed[lvl.Price] = true
+	}
+	for _, lvl := range lvls {
+		if !changed[lvl.Price] {
+			out = append(out, lvl)
+		}
+	}
+	for _, lvl := range changes {
+		if !lvl.Quantity.IsZero() {
+			out = append(out, lvl)
+		}
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		if higherPriceFirst {
+			return out[i].Price.GreaterThan(out[j].Price)
+		}
+		return out[i].Price.LessThan(out[j].Price)
+	})
+	return out
+}
+
+// Client is a subscriber of market data. Its events start with a snapshot of each channel it
+// subscribes to, followed by the updates of the channel. A client that does not keep up with its
+// events is dropped, and its events are closed.
+type Client struct {
+	hub    *memoryHub
+	events chan Event
+	subs   map[subscription]struct{}
+	closed bool
+}
+
+// Events returns the events of the client, which are closed when the client is dropped.
+func (c *Client) Events() <-chan Event {
+	return c.events
+}
+
+// Subscribe subscribes the client to a channel of a symbol, and sends the snapshot of the channel.
+func (c *Client) Subscribe(channel Channel, symbol string) {
+	c.hub.mux.Lock()
+	defer c.hub.mux.Unlock()
+
+	if c.closed {
+		return
+	}
+	c.subs[subscription{channel, symbol}] = struct{}{}
+	c.send(c.hub.getBookState(symbol).snapshot(symbol, channel))
+}
+
+// Unsubscribe unsubscribes the client from a channel of a symbol.
+func (c *Client) Unsubscribe(channel Channel, symbol string) {
+	c.hub.mux.Lock()
+	defer c.hub.mux.Unlock()
+
+	delete(c.subs, subscription{channel, symbol})
+}
+
+// Close drops the client.
+func (c *Client) Close() {
+	c.hub.mux.Lock()
+	defer c.hub.mux.Unlock()
+
+	c.hub.closeClient(c)
+}
+
+// send sends an event to the client without waiting, and drops the client if its buffer is full.
+func (c *Client) send(ev Event) {
+	if c.closed {
+		return
+	}
+	select {
+	case c.events <- ev:
+	default:
+		c.hub.closeClient(c)
+	}
+}
@@ -0,0 +1,70 @@
+package marketdata
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	booksvc "trading-matching-service/pkg/service/book"
+	"trading-matching-service/util/decimal"
+)
+
+func level(price, qty int64) booksvc.PriceLevel {
+	return booksvc.PriceLevel{Price: decimal.New(price), Quantity: decimal.New(qty), OrderCount: 1}
+}
+
+func TestMemoryHubSnapshotAndUpdates(t *testing.T) {
+	ctx := context.Background()
+	hub := NewMemoryHub(10, 2)
+	_ = hub.Publish(ctx, Update{Symbol: "BTC-USD", Sequence: 5, Snapshot: true, Bids: []booksvc.PriceLevel{level(99, 1), level(98, 2)}, Asks: []booksvc.PriceLevel{level(101, 1)}, Ticker: &Ticker{}})
+	for i := 1; i <= 3; i++ {
+		_ = hub.Publish(ctx, Update{Symbol: "BTC-USD", Sequence: uint64(5 + i), Trades: []Trade{{Sequence: uint64(i)}}})
+	}
+
+	c := hub.NewClient()
+	c.Subscribe(ChannelDepth, "BTC-USD")
+	c.Subscribe(ChannelTrades, "BTC-USD")
+	assert.Equal(t, Event{Channel: ChannelDepth, Symbol: "BTC-USD", Type: EventTypeSnapshot, Sequence: 5, Bids: []booksvc.PriceLevel{level(99, 1), level(98, 2)}, Asks: []booksvc.PriceLevel{level(101, 1)}}, <-c.Events())
+	assert.Equal(t, Event{Channel: ChannelTrades, Symbol: "BTC-USD", Type: EventTypeSnapshot, Sequence: 8, Trades: []Trade{{Sequence: 2}, {Sequence: 3}}}, <-c.Events())
+
+	// the ticker is not subscribed, and the other symbol is not either
+	ticker := &Ticker{LastPrice: decimal.New(100)}
+	_ = hub.Publish(ctx, Update{Symbol: "ETH-USD", Sequence: 9, Bids: []booksvc.PriceLevel{level(10, 1)}})
+	_ = hub.Publish(ctx, Update{Symbol: "BTC-USD", Sequence: 10, Bids: []booksvc.PriceLevel{level(100, 3), {Price: decimal.New(98)}}, Ticker: ticker})
+	_ = hub.Publish(ctx, Update{Symbol: "BTC-USD", Sequence: 11, Trades: []Trade{{Sequence: 4}}, Asks: []booksvc.PriceLevel{{Price: decimal.New(101)}}})
+	assert.Equal(t, Event{Channel: ChannelDepth, Symbol: "BTC-USD", Type: EventTypeUpdate, Sequence: 10, PrevSequence: 5, Bids: []booksvc.PriceLevel{level(100, 3), {Price: decimal.New(98)}}}, <-c.Events())
+	assert.Equal(t, Event{Channel: ChannelTrades, Symbol: "BTC-USD", Type: EventTypeUpdate, Sequence: 11, PrevSequence: 8, Trades: []Trade{{Sequence: 4}}}, <-c.Events())
+	assert.Equal(t, Event{Channel: ChannelDepth, Symbol: "BTC-USD", Type: EventTypeUpdate, Sequence: 11, PrevSequence: 10, Asks: []booksvc.PriceLevel{{Price: decimal.New(101)}}}, <-c.Events())
+	assert.Empty(t, c.Events())
+
+	// a later subscriber sees the updates applied
+	c.Unsubscribe(ChannelDepth, "BTC-USD")
+	c.Subscribe(ChannelDepth, "BTC-USD")
+	c.Subscribe(ChannelTicker, "BTC-USD")
+	assert.Equal(t, Event{Channel: ChannelDepth, Symbol: "BTC-USD", Type: EventTypeSnapshot, Sequence: 11, Bids: []booksvc.PriceLevel{level(100, 3), level(99, 1)}, Asks: []booksvc.PriceLevel{}}, <-c.Events())
+	assert.Equal(t, Event{Channel: ChannelTicker, Symbol: "BTC-USD", Type: EventTypeSnapshot, Sequence: 10, Ticker: ticker}, <-c.Events())
+}
+
+func TestMemoryHubDropsSlowClient(t *testing.T) {
+	ctx := context.Background()
+	hub := NewMemoryHub(2, 10)
+	slow, fast := hub.NewClient(), hub.NewClient()
+	slow.Subscribe(ChannelTrades, "BTC-USD")
+	fast.Subscribe(ChannelTrades, "BTC-USD")
+	<-fast.Events()
+
+	for i := 1; i <= 3; i++ {
+		_ = hub.Publish(ctx, Update{Symbol: "BTC-USD", Sequence: uint64(i), Trades: []Trade{{Sequence: uint64(i)}}})
+		assert.Equal(t, uint64(i), (<-fast.Events()).Sequence)
+	}
+
+	n := 0
+	for range slow.Events() {
+		n++
+	}
+	// the snapshot and the first update fill the buffer, the second update drops the client
+	assert.Equal(t, 2, n)
+	slow.Subscribe(ChannelTrades, "BTC-USD")
+	slow.Close()
+}
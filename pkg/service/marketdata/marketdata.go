@@ -0,0 +1,78 @@
+package marketdata
+
+import (
+	booksvc "trading-matching-service/pkg/service/book"
+	ordersvc "trading-matching-service/pkg/service/order"
+	"trading-matching-service/util/decimal"
+)
+
+// Channel is a kind of market data a client subscribes to for a symbol.
+type Channel string
+
+const (
+	ChannelTrades = Channel("trades")
+	ChannelDepth  = Channel("depth")
+	ChannelTicker = Channel("ticker")
+)
+
+// Trade is the public part of a trade, without the orders and the accounts.
+type Trade struct {
+	ID string
+	// Sequence numbers the trades of a book in order, starting at 1.
+	Sequence uint64
+	// AggressorSide is the side of the incoming order, none for a trade of an auction uncross.
+	AggressorSide ordersvc.OrderKind
+	Price         decimal.Decimal
+	Quantity      decimal.Decimal
+	Timestamp     int64
+}
+
+// Ticker is the last trade price and the best price levels of a book.
+type Ticker struct {
+	LastPrice decimal.Decimal
+	// BestBid and BestAsk are empty levels if the side has no limit order.
+	BestBid booksvc.PriceLevel
+	BestAsk booksvc.PriceLevel
+}
+
+// Update is what an inbound message of the match engine changed in a book. A snapshot update
+// carries the whole top of the book instead of the changes.
+type Update struct {
+	Symbol string
+	// Sequence is the sequence number of the inbound message of the match engine.
+	Sequence uint64
+	Snapshot bool
+	Trades   []Trade
+	// Bids and Asks are the changed price levels of the top of the book. A level with 0 quantity
+	// left the top of the book.
+	Bids []booksvc.PriceLevel
+	Asks []booksvc.PriceLevel
+	// Ticker is the new ticker, nil if it did not change.
+	Ticker *Ticker
+}
+
+// EventType tells whether an event replaces the state of a channel or changes it.
+type EventType string
+
+const (
+	EventTypeSnapshot = EventType("snapshot")
+	EventTypeUpdate   = EventType("update")
+)
+
+// Event is what a client receives on a channel of a symbol. Only the fields of its channel are
+// set.
+type Event struct {
+	Channel Channel
+	Symbol  string
+	Type    EventType
+	// Sequence is the sequence number of the inbound message of the match engine the event is up
+	// to date with.
+	Sequence uint64
+	// PrevSequence is the sequence number of the previous event of the channel, so a client can
+	// tell if it missed one. It is 0 for a snapshot.
+	PrevSequence uint64
+	Trades       []Trade
+	Bids         []booksvc.PriceLevel
+	Asks         []booksvc.PriceLevel
+	Ticker       *Ticker
+}
@@ -5,12 +5,18 @@ import "encoding/json"
 type MessageKind uint32
 
 const (
-	MessageKindNone        = MessageKind(iota)
-	MessageKindOrderCreate = MessageKind(iota)
-	MessageKindOrderCancel = MessageKind(iota)
-	MessageKindTrade       = MessageKind(iota)
-	MessageKindCancel      = MessageKind(iota)
-	NumOfMessageKind       = int(iota)
+	MessageKindNone             = MessageKind(iota)
+	MessageKindOrderCreate      = MessageKind(iota)
+	MessageKindOrderCancel      = MessageKind(iota)
+	MessageKindTrade            = MessageKind(iota)
+	MessageKindCancel           = MessageKind(iota)
+	MessageKindTimer            = MessageKind(iota)
+	MessageKindOrderAmend       = MessageKind(iota)
+	MessageKindStatus           = MessageKind(iota)
+	MessageKindOrderGroupCreate = MessageKind(iota)
+	MessageKindExecutionReport  = MessageKind(iota)
+	MessageKindMarketData       = MessageKind(iota)
+	NumOfMessageKind            = int(iota)
 )
 
 type Message interface {
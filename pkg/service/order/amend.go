@@ -0,0 +1,18 @@
+package order
+
+import (
+	"trading-matching-service/util/decimal"
+)
+
+// Amend changes the price or the quantity of a pending order. A zero field is left unchanged.
+type Amend struct {
+	OrderID      string
+	Symbol       string
+	OrderKind    OrderKind
+	Price        decimal.Decimal
+	TriggerPrice decimal.Decimal
+	// Quantity is the new unfilled quantity of the order.
+	Quantity    decimal.Decimal
+	CreatedAt   int64
+	ConfirmedAt int64
+}
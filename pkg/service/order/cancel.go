@@ -2,6 +2,7 @@ package order
 
 type Cancel struct {
 	OrderID     string
+	Symbol      string
 	OrderKind   OrderKind
 	CreatedAt   int64
 	ConfirmedAt int64
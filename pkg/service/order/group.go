@@ -0,0 +1,22 @@
+package order
+
+type OrderGroupKind uint32
+
+const (
+	OrderGroupKindNone = OrderGroupKind(iota)
+	// OrderGroupKindOCO links orders of which a fill on one cancels the others.
+	OrderGroupKindOCO = OrderGroupKind(iota)
+	// OrderGroupKindBracket is an entry order followed by a take profit order and a stop loss
+	// order, which are placed as an OCO group once the entry order is filled.
+	OrderGroupKindBracket = OrderGroupKind(iota)
+)
+
+// OrderGroup is a set of linked orders placed together. The orders of a bracket are the entry,
+// the take profit and the stop loss orders in that order.
+type OrderGroup struct {
+	ID        string
+	Symbol    string
+	Kind      OrderGroupKind
+	Orders    []Order
+	CreatedAt int64
+}
@@ -1,5 +1,10 @@
 package order
 
+import (
+	cancelsvc "trading-matching-service/pkg/service/cancel"
+	"trading-matching-service/util/decimal"
+)
+
 type OrderKind uint32
 
 const (
@@ -14,14 +19,133 @@ const (
 	PriceTypeNone   = PriceType(iota)
 	PriceTypeMarket = PriceType(iota)
 	PriceTypeLimit  = PriceType(iota)
+	// PriceTypeStop becomes a market order when the market price reaches TriggerPrice.
+	PriceTypeStop = PriceType(iota)
+	// PriceTypeStopLimit becomes a limit order when the market price reaches TriggerPrice.
+	PriceTypeStopLimit = PriceType(iota)
+	// PriceTypeTrailingStop is a stop order whose TriggerPrice trails the best market price seen
+	// since entry by TrailAmount or TrailPercent.
+	PriceTypeTrailingStop = PriceType(iota)
+	// PriceTypeTrailingStopLimit is a trailing stop order becoming a limit order at TriggerPrice
+	// moved by LimitOffset in its favor.
+	PriceTypeTrailingStopLimit = PriceType(iota)
+)
+
+type TimeInForce uint32
+
+const (
+	TimeInForceNone = TimeInForce(iota)
+	// TimeInForceGTC keeps the order in book until it is filled or canceled.
+	TimeInForceGTC = TimeInForce(iota)
+	// TimeInForceIOC fills the order immediately and cancels the unfilled remainder.
+	TimeInForceIOC = TimeInForce(iota)
+	// TimeInForceFOK fills the order immediately and completely or not at all.
+	TimeInForceFOK = TimeInForce(iota)
+	// TimeInForceGTD keeps the order in book until ExpireAt.
+	TimeInForceGTD = TimeInForce(iota)
+	// TimeInForceDay keeps the order in book until the end of the trading day.
+	TimeInForceDay = TimeInForce(iota)
 )
 
+// OrderStatus is the lifecycle status of an order in store.
+type OrderStatus uint32
+
+const (
+	OrderStatusNone = OrderStatus(iota)
+	// OrderStatusPending means the order is accepted but not yet confirmed by the match engine.
+	OrderStatusPending = OrderStatus(iota)
+	// OrderStatusNew means the order is in book without any fill.
+	OrderStatusNew = OrderStatus(iota)
+	// OrderStatusPartiallyFilled means the order is in book with some fills.
+	OrderStatusPartiallyFilled = OrderStatus(iota)
+	// OrderStatusFilled means the whole quantity of the order is filled.
+	OrderStatusFilled = OrderStatus(iota)
+	// OrderStatusCanceled means the unfilled remainder of the order is canceled.
+	OrderStatusCanceled = OrderStatus(iota)
+	// OrderStatusRejected means the order is refused by the match engine without any fill.
+	OrderStatusRejected = OrderStatus(iota)
+	// OrderStatusExpired means the unfilled remainder of the order reached its expiry.
+	OrderStatusExpired = OrderStatus(iota)
+)
+
+// IsClosed reports whether the order with the status has left the book for good.
+func (s OrderStatus) IsClosed() bool {
+	return s >= OrderStatusFilled
+}
+
 type Order struct {
-	ID          string
-	Kind        OrderKind
-	PriceType   PriceType
-	Price       float64
-	Quantity    int
+	ID           string
+	AccountID    string
+	Symbol       string
+	Kind         OrderKind
+	PriceType    PriceType
+	Price        decimal.Decimal
+	TriggerPrice decimal.Decimal
+	// TrailAmount is the fixed distance of the trigger price of a trailing stop order from TrailAnchor.
+	TrailAmount decimal.Decimal
+	// TrailPercent is the distance of the trigger price of a trailing stop order from TrailAnchor in
+	// percent, used if TrailAmount is 0.
+	TrailPercent decimal.Decimal
+	// TrailAnchor is the best market price seen since a trailing stop order entered, the highest one
+	// for a sell order and the lowest one for a buy order.
+	TrailAnchor decimal.Decimal
+	// LimitOffset is how far from TriggerPrice a trailing stop limit order places its limit price.
+	LimitOffset decimal.Decimal
+	// ProtectionPrice is the worst price a market order accepts, 0 for no limit.
+	ProtectionPrice decimal.Decimal
+	Quantity        decimal.Decimal
+	// DisplayQuantity is the size of the visible slice of an iceberg order, 0 for a fully visible order.
+	DisplayQuantity decimal.Decimal
+	// VisibleQuantity is the quantity of an iceberg order currently shown in book.
+	VisibleQuantity decimal.Decimal
+	// MinQuantity is the smallest quantity the order accepts to fill on arrival, and in each fill
+	// while it rests in book.
+	MinQuantity decimal.Decimal
+	// AllOrNone makes the order only fill its whole remaining quantity at once.
+	AllOrNone bool
+	// PostOnly makes sure the order never takes liquidity from book.
+	PostOnly bool
+	// GroupID is the order group the order belongs to, empty for a standalone order.
+	GroupID     string
+	TimeInForce TimeInForce
+	ExpireAt    int64
 	CreatedAt   int64
 	ConfirmedAt int64
+
+	// The lifecycle of the order, only kept in store.
+	Status            OrderStatus
+	FilledQuantity    decimal.Decimal
+	RemainingQuantity decimal.Decimal
+	AverageFillPrice  decimal.Decimal
+	// CancelRejectReason is why the last cancel request of the order was rejected.
+	CancelRejectReason cancelsvc.CancelRejectReason
+	// AmendRejectReason is why the last amend request of the order was rejected.
+	AmendRejectReason cancelsvc.CancelRejectReason
+	UpdatedAt         int64
+}
+
+// IsStop reports whether the order waits for a trigger price.
+func (o *Order) IsStop() bool {
+	return o.PriceType == PriceTypeStop || o.PriceType == PriceTypeStopLimit || o.IsTrailingStop()
+}
+
+// IsTrailingStop reports whether the trigger price of the order follows the market price.
+func (o *Order) IsTrailingStop() bool {
+	return o.PriceType == PriceTypeTrailingStop || o.PriceType == PriceTypeTrailingStopLimit
+}
+
+// BookQuantity returns the quantity shown in book, which is only the visible part of an iceberg order.
+func (o *Order) BookQuantity() decimal.Decimal {
+	if o.DisplayQuantity.IsZero() {
+		return o.Quantity
+	}
+	return o.VisibleQuantity
+}
+
+// MinFillQuantity returns the smallest quantity the order accepts in a fill, 0 for any quantity.
+func (o *Order) MinFillQuantity() decimal.Decimal {
+	if o.AllOrNone {
+		return o.Quantity
+	}
+	return decimal.Min(o.MinQuantity, o.Quantity)
 }
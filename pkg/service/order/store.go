@@ -3,7 +3,11 @@ package order
 import (
 	"context"
 	"errors"
+	"sort"
 	"sync"
+
+	cancelsvc "trading-matching-service/pkg/service/cancel"
+	"trading-matching-service/util/decimal"
 )
 
 // Store defines the ways operating orders.
@@ -12,8 +16,23 @@ type Store interface {
 	CreateOrder(ctx context.Context, ord Order) (string, error)
 	// ConfirmOrderAt confirms the order at the specified timestamp.
 	ConfirmOrderAt(ctx context.Context, oid string, ts int64) error
+	// AmendOrder records the new price and quantity of the order at the specified timestamp.
+	AmendOrder(ctx context.Context, amend Amend, ts int64) error
+	// FillOrder records a fill of the order at the price at the specified timestamp.
+	FillOrder(ctx context.Context, oid string, price, qty decimal.Decimal, ts int64) error
+	// CloseOrder records that the unfilled remainder of the order left the book with the status at
+	// the specified timestamp.
+	CloseOrder(ctx context.Context, oid string, status OrderStatus, ts int64) error
+	// RejectCancel records that a cancel request of the order was rejected for the reason at the
+	// specified timestamp.
+	RejectCancel(ctx context.Context, oid string, reason cancelsvc.CancelRejectReason, ts int64) error
+	// RejectAmend records that an amend request of the order was rejected for the reason at the
+	// specified timestamp.
+	RejectAmend(ctx context.Context, oid string, reason cancelsvc.CancelRejectReason, ts int64) error
 	// GetOrder returns the order.
 	GetOrder(ctx context.Context, oid string) (Order, error)
+	// ListOrders returns all orders in store sorted by id.
+	ListOrders(ctx context.Context) ([]Order, error)
 }
 
 type memoryStore struct {
@@ -32,6 +51,11 @@ func (s *memoryStore) CreateOrder(ctx context.Context, ord Order) (string, error
 	s.mux.Lock()
 	defer s.mux.Unlock()
 
+	if ord.Status == OrderStatusNone {
+		ord.Status = OrderStatusPending
+		ord.RemainingQuantity = ord.Quantity
+		ord.UpdatedAt = ord.CreatedAt
+	}
 	s.pool[ord.ID] = ord
 
 	return ord.ID, nil
@@ -47,6 +71,113 @@ func (s *memoryStore) ConfirmOrderAt(ctx context.Context, oid string, ts int64)
 	}
 
 	ord.ConfirmedAt = ts
+	if ord.Status == OrderStatusPending {
+		ord.Status = OrderStatusNew
+	}
+	ord.UpdatedAt = ts
+	s.pool[oid] = ord
+
+	return nil
+}
+
+func (s *memoryStore) AmendOrder(ctx context.Context, amend Amend, ts int64) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	ord, ok := s.pool[amend.OrderID]
+	if !ok {
+		return errors.New("invalid order id")
+	}
+
+	if !amend.Price.IsZero() {
+		ord.Price = amend.Price
+	}
+	if !amend.TriggerPrice.IsZero() {
+		ord.TriggerPrice = amend.TriggerPrice
+	}
+	if !amend.Quantity.IsZero() {
+		ord.Quantity = ord.FilledQuantity.Add(amend.Quantity)
+		ord.RemainingQuantity = amend.Quantity
+	}
+	ord.UpdatedAt = ts
+	s.pool[amend.OrderID] = ord
+
+	return nil
+}
+
+func (s *memoryStore) FillOrder(ctx context.Context, oid string, price, qty decimal.Decimal, ts int64) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	ord, ok := s.pool[oid]
+	if !ok {
+		return errors.New("invalid order id")
+	}
+
+	// the average moves toward the price by the share of the fill, so the notional of the fills,
+	// which may be out of range of decimals, is never computed
+	filled := ord.FilledQuantity.Add(qty)
+	ord.AverageFillPrice = ord.AverageFillPrice.Add(price.Sub(ord.AverageFillPrice).MulDiv(qty, filled))
+	ord.FilledQuantity = filled
+	ord.RemainingQuantity = decimal.Max(ord.RemainingQuantity.Sub(qty), decimal.Zero)
+	ord.Status = OrderStatusPartiallyFilled
+	if ord.RemainingQuantity.IsZero() {
+		ord.Status = OrderStatusFilled
+	}
+	ord.UpdatedAt = ts
+	s.pool[oid] = ord
+
+	return nil
+}
+
+func (s *memoryStore) CloseOrder(ctx context.Context, oid string, status OrderStatus, ts int64) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	ord, ok := s.pool[oid]
+	if !ok {
+		return errors.New("invalid order id")
+	}
+
+	if ord.Status.IsClosed() {
+		return errors.New("order is closed")
+	}
+
+	ord.Status = status
+	ord.RemainingQuantity = decimal.Zero
+	ord.UpdatedAt = ts
+	s.pool[oid] = ord
+
+	return nil
+}
+
+func (s *memoryStore) RejectCancel(ctx context.Context, oid string, reason cancelsvc.CancelRejectReason, ts int64) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	ord, ok := s.pool[oid]
+	if !ok {
+		return errors.New("invalid order id")
+	}
+
+	ord.CancelRejectReason = reason
+	ord.UpdatedAt = ts
+	s.pool[oid] = ord
+
+	return nil
+}
+
+func (s *memoryStore) RejectAmend(ctx context.Context, oid string, reason cancelsvc.CancelRejectReason, ts int64) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	ord, ok := s.pool[oid]
+	if !ok {
+		return errors.New("invalid order id")
+	}
+
+	ord.AmendRejectReason = reason
+	ord.UpdatedAt = ts
 	s.pool[oid] = ord
 
 	return nil
@@ -64,3 +195,16 @@ func (s *memoryStore) GetOrder(ctx context.Context, oid string) (Order, error) {
 
 	return ord, nil
 }
+
+func (s *memoryStore) ListOrders(ctx context.Context) ([]Order, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	ords := make([]Order, 0, len(s.pool))
+	for _, ord := range s.pool {
+		ords = append(ords, ord)
+	}
+	sort.Slice(ords, func(i, j int) bool { return ords[i].ID < ords[j].ID })
+
+	return ords, nil
+}
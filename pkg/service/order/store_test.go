@@ -0,0 +1,60 @@
+package order
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"trading-matching-service/util/decimal"
+)
+
+func TestMemoryStoreLifecycle(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+
+	_, err := s.CreateOrder(ctx, Order{ID: "O1", Quantity: decimal.New(10)})
+	assert.NoError(t, err)
+	ord, _ := s.GetOrder(ctx, "O1")
+	assert.Equal(t, OrderStatusPending, ord.Status)
+	assert.Equal(t, decimal.New(10), ord.RemainingQuantity)
+
+	assert.NoError(t, s.ConfirmOrderAt(ctx, "O1", 1))
+	ord, _ = s.GetOrder(ctx, "O1")
+	assert.Equal(t, OrderStatusNew, ord.Status)
+
+	assert.NoError(t, s.FillOrder(ctx, "O1", decimal.New(10), decimal.New(1), 2))
+	assert.NoError(t, s.FillOrder(ctx, "O1", decimal.New(12), decimal.New(3), 3))
+	ord, _ = s.GetOrder(ctx, "O1")
+	assert.Equal(t, OrderStatusPartiallyFilled, ord.Status)
+	assert.Equal(t, decimal.New(4), ord.FilledQuantity)
+	assert.Equal(t, decimal.New(6), ord.RemainingQuantity)
+	assert.Equal(t, decimal.MustParse("11.5"), ord.AverageFillPrice)
+
+	assert.NoError(t, s.AmendOrder(ctx, Amend{OrderID: "O1", Quantity: decimal.New(2)}, 4))
+	ord, _ = s.GetOrder(ctx, "O1")
+	assert.Equal(t, decimal.New(6), ord.Quantity)
+	assert.Equal(t, decimal.New(2), ord.RemainingQuantity)
+
+	assert.NoError(t, s.FillOrder(ctx, "O1", decimal.New(10), decimal.New(2), 4))
+	ord, _ = s.GetOrder(ctx, "O1")
+	assert.Equal(t, OrderStatusFilled, ord.Status)
+	assert.Equal(t, decimal.MustParse("11"), ord.AverageFillPrice)
+
+	// a filled order can not be canceled any more
+	assert.Error(t, s.CloseOrder(ctx, "O1", OrderStatusCanceled, 5))
+	ord, _ = s.GetOrder(ctx, "O1")
+	assert.Equal(t, OrderStatusFilled, ord.Status)
+}
+
+func TestMemoryStoreLargeFills(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+
+	// the notional of the fills is out of range of decimals, while the average is not
+	_, _ = s.CreateOrder(ctx, Order{ID: "O1", Quantity: decimal.New(200000000)})
+	assert.NoError(t, s.FillOrder(ctx, "O1", decimal.New(500), decimal.New(100000000), 1))
+	assert.NoError(t, s.FillOrder(ctx, "O1", decimal.New(600), decimal.New(100000000), 2))
+	ord, _ := s.GetOrder(ctx, "O1")
+	assert.Equal(t, decimal.New(550), ord.AverageFillPrice)
+}
@@ -0,0 +1,22 @@
+package status
+
+import (
+	"context"
+	"log"
+)
+
+type Recorder interface {
+	CreateStatusRecord(ctx context.Context, st Status) error
+}
+
+type stdoutRecorder struct {
+}
+
+func NewStdoutRecorder() Recorder {
+	return &stdoutRecorder{}
+}
+
+func (r *stdoutRecorder) CreateStatusRecord(ctx context.Context, st Status) error {
+	log.Printf("status: %+v", st)
+	return nil
+}
@@ -0,0 +1,35 @@
+package status
+
+// TradingPhase is the state of the order book of a symbol.
+type TradingPhase uint32
+
+const (
+	// TradingPhaseContinuous matches incoming orders on arrival.
+	TradingPhaseContinuous = TradingPhase(iota)
+	// TradingPhaseAuction collects orders without matching them until the auction is uncrossed.
+	TradingPhaseAuction = TradingPhase(iota)
+	// TradingPhaseHalted stops matching after a volatility interruption until a cooldown is over.
+	TradingPhaseHalted = TradingPhase(iota)
+)
+
+type StatusReason uint32
+
+const (
+	StatusReasonNone = StatusReason(iota)
+	// StatusReasonSchedule means a scheduled call auction starts.
+	StatusReasonSchedule = StatusReason(iota)
+	// StatusReasonPriceBand means a trade would print outside the price band of the symbol.
+	StatusReasonPriceBand = StatusReason(iota)
+	// StatusReasonCooldown means a halted book resumes through an auction.
+	StatusReasonCooldown = StatusReason(iota)
+	// StatusReasonUncross means an auction is uncrossed and continuous trading resumes.
+	StatusReasonUncross = StatusReason(iota)
+)
+
+// Status is a change of the trading phase of a symbol.
+type Status struct {
+	Symbol    string
+	Phase     TradingPhase
+	Reason    StatusReason
+	Timestamp int64
+}
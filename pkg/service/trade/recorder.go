@@ -20,3 +20,19 @@ func (r *stdoutRecorder) CreateTradeRecord(ctx context.Context, td Trade) error
 	log.Printf("trade: %+v", td)
 	return nil
 }
+
+type ReportRecorder interface {
+	CreateExecutionReport(ctx context.Context, rpt ExecutionReport) error
+}
+
+type stdoutReportRecorder struct {
+}
+
+func NewStdoutReportRecorder() ReportRecorder {
+	return &stdoutReportRecorder{}
+}
+
+func (r *stdoutReportRecorder) CreateExecutionReport(ctx context.Context, rpt ExecutionReport) error {
+	log.Printf("execution report: %+v", rpt)
+	return nil
+}
@@ -0,0 +1,35 @@
+package trade
+
+import (
+	ordersvc "trading-matching-service/pkg/service/order"
+	"trading-matching-service/util/decimal"
+)
+
+type Liquidity uint32
+
+const (
+	LiquidityNone = Liquidity(iota)
+	// LiquidityMaker means the order was resting in book.
+	LiquidityMaker = Liquidity(iota)
+	// LiquidityTaker means the order was the incoming one.
+	LiquidityTaker = Liquidity(iota)
+	// LiquidityAuction means the order was filled by an auction uncross.
+	LiquidityAuction = Liquidity(iota)
+)
+
+// ExecutionReport notifies the owner of an order of a fill. Every trade makes a report for each
+// of its orders.
+type ExecutionReport struct {
+	TradeID   string
+	Sequence  uint64
+	OrderID   string
+	AccountID string
+	Symbol    string
+	Side      ordersvc.OrderKind
+	Liquidity Liquidity
+	Price     decimal.Decimal
+	Quantity  decimal.Decimal
+	// RemainingQuantity is the unfilled quantity of the order after the fill.
+	RemainingQuantity decimal.Decimal
+	Timestamp         int64
+}
@@ -1,9 +1,36 @@
 package trade
 
+import (
+	ordersvc "trading-matching-service/pkg/service/order"
+	"trading-matching-service/util/decimal"
+)
+
 type Trade struct {
-	BuyOrderID  string
-	SellOrderID string
-	Price       float64
-	Quantity    int
-	Timestamp   int64
+	// ID is unique across books and restarts, made of the symbol, the time in nanoseconds at which
+	// the match engine started and the sequence number. Deterministic mode leaves the start time out.
+	ID string
+	// Sequence numbers the trades of a book in order, starting at 1.
+	Sequence      uint64
+	Symbol        string
+	BuyOrderID    string
+	SellOrderID   string
+	BuyAccountID  string
+	SellAccountID string
+	// AggressorSide is the side of the incoming order, none for a trade of an auction uncross.
+	AggressorSide ordersvc.OrderKind
+	// MakerOrderID and TakerOrderID are the resting and the incoming orders, empty for a trade of
+	// an auction uncross.
+	MakerOrderID string
+	TakerOrderID string
+	Price        decimal.Decimal
+	Quantity     decimal.Decimal
+	// BuyRemainingQuantity and SellRemainingQuantity are the unfilled quantities of the orders
+	// after the trade.
+	BuyRemainingQuantity  decimal.Decimal
+	SellRemainingQuantity decimal.Decimal
+	// BuyFee and SellFee are the fees charged to the owners of the orders, negative for rebates.
+	BuyFee  decimal.Decimal
+	SellFee decimal.Decimal
+	// Timestamp is the unix timestamp in nanoseconds.
+	Timestamp int64
 }
@@ -0,0 +1,55 @@
+package unittest
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"trading-matching-service/pkg/engine"
+	msgsvc "trading-matching-service/pkg/service/message"
+	ordersvc "trading-matching-service/pkg/service/order"
+	"trading-matching-service/util/decimal"
+)
+
+func TestDeterministicReplay(t *testing.T) {
+	msgs := []msgsvc.Message{
+		msgsvc.NewMessage(msgsvc.MessageKindOrderCreate, &ordersvc.Order{ID: "S1", Symbol: "BTC-USD", AccountID: "A1", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(30)}),
+		msgsvc.NewMessage(msgsvc.MessageKindOrderCreate, &ordersvc.Order{ID: "S2", Symbol: "BTC-USD", AccountID: "A1", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(11), Quantity: decimal.New(10)}),
+		msgsvc.NewMessage(msgsvc.MessageKindOrderCreate, &ordersvc.Order{ID: "B1", Symbol: "BTC-USD", AccountID: "A2", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(11), Quantity: decimal.New(35)}),
+		msgsvc.NewMessage(msgsvc.MessageKindOrderCancel, &ordersvc.Cancel{OrderID: "S2", Symbol: "BTC-USD", OrderKind: ordersvc.OrderKindSell}),
+		msgsvc.NewMessage(msgsvc.MessageKindOrderCreate, &ordersvc.Order{ID: "B2", Symbol: "BTC-USD", AccountID: "A3", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeMarket, Quantity: decimal.New(5)}),
+	}
+
+	first, second := runDeterministic(msgs), runDeterministic(msgs)
+	assert.NotEmpty(t, first)
+	assert.Equal(t, string(first), string(second))
+}
+
+// runDeterministic runs the messages through a deterministic match engine and returns the
+// trades it published as json.
+func runDeterministic(msgs []msgsvc.Message) []byte {
+	orderQ, tradeQ, cancelQ := msgsvc.NewQueue(100), msgsvc.NewQueue(100), msgsvc.NewQueue(100)
+	trR := &tradeRecorder{}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	me := engine.NewMatchEngine(ordersvc.NewMemoryStore(), orderQ, tradeQ, cancelQ, engine.WithDeterministic())
+	te := engine.NewTradeEngine(tradeQ, trR)
+	go func() {
+		_ = me.Run(ctx)
+	}()
+	go func() {
+		_ = te.Run(ctx)
+	}()
+
+	for _, msg := range msgs {
+		_ = orderQ.Push(ctx, msg)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	bs, _ := json.Marshal(trR.get())
+	return bs
+}
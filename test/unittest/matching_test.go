@@ -3,6 +3,7 @@ package unittest
 import (
 	"context"
 	"fmt"
+	"sync"
 	"testing"
 	"time"
 
@@ -10,37 +11,69 @@ import (
 
 	"trading-matching-service/pkg/engine"
 	cancelsvc "trading-matching-service/pkg/service/cancel"
+	feesvc "trading-matching-service/pkg/service/fee"
+	instrumentsvc "trading-matching-service/pkg/service/instrument"
 	msgsvc "trading-matching-service/pkg/service/message"
 	ordersvc "trading-matching-service/pkg/service/order"
+	statussvc "trading-matching-service/pkg/service/status"
 	tradesvc "trading-matching-service/pkg/service/trade"
+	"trading-matching-service/util/decimal"
 )
 
 const (
 	tradeLenMismatch      = "trade length mismatch"
+	symbolMismatch        = "symbol mismatch at trade %d"
 	buyOrderIDMismatch    = "buy order id mismatch at trade %d"
 	sellOrderIDMismatch   = "sell order id mismatch at trade %d"
 	priceMismatch         = "price mismatch at trade %d"
 	quantityMismatch      = "quantity mismatch at trade %d"
 	cancelOrderIDMismatch = "cancel order id mismatch at cancel %d"
+	cancelReasonMismatch  = "cancel reason mismatch at cancel %d"
+	cancelRejectMismatch  = "cancel reject reason mismatch at cancel %d"
+	cancelQtyMismatch     = "canceled quantity mismatch at cancel %d"
+	statusPhaseMismatch   = "phase mismatch at status %d"
+	statusReasonMismatch  = "reason mismatch at status %d"
+	orderStatusMismatch   = "status mismatch at order %s"
+	filledMismatch        = "filled quantity mismatch at order %s"
+	remainingMismatch     = "remaining quantity mismatch at order %s"
+	avgPriceMismatch      = "average fill price mismatch at order %s"
+	cancelRejectOfOrder   = "cancel reject reason mismatch at order %s"
+	amendRejectOfOrder    = "amend reject reason mismatch at order %s"
+	cancelAmendMismatch   = "amend flag mismatch at cancel %d"
+	tradeDetailMismatch   = "trade detail mismatch at trade %d"
+	reportMismatch        = "execution report mismatch at report %d"
 )
 
 func TestTrading(t *testing.T) {
 	orderQ := msgsvc.NewQueue(1000)
 	tradeQ := msgsvc.NewQueue(100)
 	cancelQ := msgsvc.NewQueue(100)
-	pool := ordersvc.NewMemoryStore()
+	statusQ := msgsvc.NewQueue(100)
+	reportQ := msgsvc.NewQueue(100)
 
 	testCases := getTestCases()
 
-	for idx, testCase := range testCases {
+	for idx, getTestCase := range testCases {
+		// build the test case right before running it, so timestamps in it are up to date
+		testCase := getTestCase()
 		t.Run(fmt.Sprintf("case %d: %s", idx+1, testCase.name), func(t *testing.T) {
 			trR := &tradeRecorder{}
 			cclR := &cancelRecorder{}
+			stR := &statusRecorder{}
+			rptR := &reportRecorder{}
+			pool := ordersvc.NewMemoryStore()
 
 			ctx, cancel := context.WithCancel(context.Background())
-			me := engine.NewMatchEngine(pool, orderQ, tradeQ, cancelQ)
-			te := engine.NewTradeEngine(tradeQ, trR)
+			opts := append([]engine.MatchEngineOption{engine.WithTimerInterval(10 * time.Millisecond), engine.WithStatusQueue(statusQ), engine.WithReportQueue(reportQ)}, testCase.opts...)
+			me := engine.NewMatchEngine(pool, orderQ, tradeQ, cancelQ, opts...)
+			teOpts := []engine.TradeEngineOption{}
+			if testCase.fees != nil {
+				teOpts = append(teOpts, engine.WithFeeStore(feesvc.NewMemoryStore(*testCase.fees)))
+			}
+			te := engine.NewTradeEngine(tradeQ, trR, teOpts...)
 			ce := engine.NewCancelEngine(cancelQ, cclR)
+			se := engine.NewStatusEngine(statusQ, stR)
+			re := engine.NewReportEngine(reportQ, rptR)
 			go func() {
 				_ = me.Run(ctx)
 			}()
@@ -50,15 +83,31 @@ func TestTrading(t *testing.T) {
 			go func() {
 				_ = ce.Run(ctx)
 			}()
+			go func() {
+				_ = se.Run(ctx)
+			}()
+			go func() {
+				_ = re.Run(ctx)
+			}()
 
 			for i := range testCase.ords {
 				var msg msgsvc.Message
 				if v, ok := testCase.ords[i].(*ordersvc.Order); ok {
+					_, _ = pool.CreateOrder(ctx, *v)
 					msg = msgsvc.NewMessage(msgsvc.MessageKindOrderCreate, v)
 				}
 				if v, ok := testCase.ords[i].(*ordersvc.Cancel); ok {
 					msg = msgsvc.NewMessage(msgsvc.MessageKindOrderCancel, v)
 				}
+				if v, ok := testCase.ords[i].(*ordersvc.Amend); ok {
+					msg = msgsvc.NewMessage(msgsvc.MessageKindOrderAmend, v)
+				}
+				if v, ok := testCase.ords[i].(*ordersvc.OrderGroup); ok {
+					for _, ord := range v.Orders {
+						_, _ = pool.CreateOrder(ctx, ord)
+					}
+					msg = msgsvc.NewMessage(msgsvc.MessageKindOrderGroupCreate, v)
+				}
 				_ = orderQ.Push(ctx, msg)
 			}
 
@@ -66,15 +115,56 @@ func TestTrading(t *testing.T) {
 
 			assert.Equal(t, len(testCase.expTrades), len(trR.get()), tradeLenMismatch)
 			for n := 0; n < len(testCase.expTrades); n++ {
+				assert.Equal(t, testCase.expTrades[n].Symbol, trR.get()[n].Symbol, symbolMismatch, n+1)
 				assert.Equal(t, testCase.expTrades[n].BuyOrderID, trR.get()[n].BuyOrderID, buyOrderIDMismatch, n+1)
 				assert.Equal(t, testCase.expTrades[n].SellOrderID, trR.get()[n].SellOrderID, sellOrderIDMismatch, n+1)
 				assert.Equal(t, testCase.expTrades[n].Price, trR.get()[n].Price, priceMismatch, n+1)
 				assert.Equal(t, testCase.expTrades[n].Quantity, trR.get()[n].Quantity, quantityMismatch, n+1)
+				if exp := testCase.expTrades[n]; exp.ID != "" && n < len(trR.get()) {
+					td := trR.get()[n]
+					exp.Timestamp = td.Timestamp
+					assert.Equal(t, *exp, td, tradeDetailMismatch, n+1)
+				}
 			}
 
 			assert.Equal(t, len(testCase.expCancels), len(cclR.get()))
 			for n := 0; n < len(testCase.expCancels); n++ {
 				assert.Equal(t, testCase.expCancels[n].OrderID, cclR.get()[n].OrderID, cancelOrderIDMismatch, n+1)
+				assert.Equal(t, testCase.expCancels[n].Reason, cclR.get()[n].Reason, cancelReasonMismatch, n+1)
+				assert.Equal(t, testCase.expCancels[n].RejectReason, cclR.get()[n].RejectReason, cancelRejectMismatch, n+1)
+				assert.Equal(t, testCase.expCancels[n].Amend, cclR.get()[n].Amend, cancelAmendMismatch, n+1)
+				if !testCase.expCancels[n].Quantity.IsZero() {
+					assert.Equal(t, testCase.expCancels[n].Quantity, cclR.get()[n].Quantity, cancelQtyMismatch, n+1)
+				}
+			}
+
+			if testCase.expStatuses != nil {
+				assert.Equal(t, len(testCase.expStatuses), len(stR.get()))
+				for n := 0; n < len(testCase.expStatuses) && n < len(stR.get()); n++ {
+					assert.Equal(t, testCase.expStatuses[n].Phase, stR.get()[n].Phase, statusPhaseMismatch, n+1)
+					assert.Equal(t, testCase.expStatuses[n].Reason, stR.get()[n].Reason, statusReasonMismatch, n+1)
+				}
+			}
+
+			if testCase.expReports != nil {
+				assert.Equal(t, len(testCase.expReports), len(rptR.get()))
+				for n := 0; n < len(testCase.expReports) && n < len(rptR.get()); n++ {
+					rpt := rptR.get()[n]
+					exp := *testCase.expReports[n]
+					exp.Timestamp = rpt.Timestamp
+					assert.Equal(t, exp, rpt, reportMismatch, n+1)
+				}
+			}
+
+			for _, exp := range testCase.expOrders {
+				ord, err := pool.GetOrder(ctx, exp.ID)
+				assert.NoError(t, err)
+				assert.Equal(t, exp.Status, ord.Status, orderStatusMismatch, exp.ID)
+				assert.Equal(t, exp.FilledQuantity, ord.FilledQuantity, filledMismatch, exp.ID)
+				assert.Equal(t, exp.RemainingQuantity, ord.RemainingQuantity, remainingMismatch, exp.ID)
+				assert.Equal(t, exp.AverageFillPrice, ord.AverageFillPrice, avgPriceMismatch, exp.ID)
+				assert.Equal(t, exp.CancelRejectReason, ord.CancelRejectReason, cancelRejectOfOrder, exp.ID)
+				assert.Equal(t, exp.AmendRejectReason, ord.AmendRejectReason, amendRejectOfOrder, exp.ID)
 			}
 
 			cancel()
@@ -84,50 +174,156 @@ func TestTrading(t *testing.T) {
 }
 
 type tradeRecorder struct {
+	mux    sync.Mutex
 	trades []tradesvc.Trade
 }
 
 func (tr *tradeRecorder) CreateTradeRecord(ctx context.Context, td tradesvc.Trade) error {
+	tr.mux.Lock()
+	defer tr.mux.Unlock()
+
 	tr.trades = append(tr.trades, td)
 	return nil
 }
 
 func (tr *tradeRecorder) get() []tradesvc.Trade {
-	return tr.trades
+	tr.mux.Lock()
+	defer tr.mux.Unlock()
+
+	return append([]tradesvc.Trade{}, tr.trades...)
 }
 
 type cancelRecorder struct {
+	mux     sync.Mutex
 	cancels []cancelsvc.Cancel
 }
 
 func (cr *cancelRecorder) CreateCancelRecord(ctx context.Context, ccl cancelsvc.Cancel) error {
+	cr.mux.Lock()
+	defer cr.mux.Unlock()
+
 	cr.cancels = append(cr.cancels, ccl)
 	return nil
 }
 
 func (cr *cancelRecorder) get() []cancelsvc.Cancel {
-	return cr.cancels
+	cr.mux.Lock()
+	defer cr.mux.Unlock()
+
+	return append([]cancelsvc.Cancel{}, cr.cancels...)
+}
+
+type statusRecorder struct {
+	mux      sync.Mutex
+	statuses []statussvc.Status
+}
+
+func (sr *statusRecorder) CreateStatusRecord(ctx context.Context, st statussvc.Status) error {
+	sr.mux.Lock()
+	defer sr.mux.Unlock()
+
+	sr.statuses = append(sr.statuses, st)
+	return nil
+}
+
+func (sr *statusRecorder) get() []statussvc.Status {
+	sr.mux.Lock()
+	defer sr.mux.Unlock()
+
+	return append([]statussvc.Status{}, sr.statuses...)
+}
+
+type reportRecorder struct {
+	mux     sync.Mutex
+	reports []tradesvc.ExecutionReport
+}
+
+func (rr *reportRecorder) CreateExecutionReport(ctx context.Context, rpt tradesvc.ExecutionReport) error {
+	rr.mux.Lock()
+	defer rr.mux.Unlock()
+
+	rr.reports = append(rr.reports, rpt)
+	return nil
+}
+
+func (rr *reportRecorder) get() []tradesvc.ExecutionReport {
+	rr.mux.Lock()
+	defer rr.mux.Unlock()
+
+	return append([]tradesvc.ExecutionReport{}, rr.reports...)
 }
 
 type testCase struct {
 	name       string
+	opts       []engine.MatchEngineOption
 	ords       []interface{}
 	expTrades  []*tradesvc.Trade
 	expCancels []*cancelsvc.Cancel
+	// expStatuses is only checked if it is set
+	expStatuses []*statussvc.Status
+	// expOrders are the lifecycles of the orders in store to check
+	expOrders []*ordersvc.Order
+	// expReports is only checked if it is set
+	expReports []*tradesvc.ExecutionReport
+	// fees are the fee schedules charged by the trade engine, if set
+	fees *feesvc.Config
 }
 
-func getTestCases() []*testCase {
-	testCases := []*testCase{
-		getTestCase1(),
-		getTestCase2(),
-		getTestCase3(),
-		getTestCase4(),
-		getTestCase5(),
-		getTestCase6(),
-		getTestCase7(),
-		getTestCase8(),
-		getTestCase9(),
-		getTestCase10(),
+func getTestCases() []func() *testCase {
+	testCases := []func() *testCase{
+		getTestCase1,
+		getTestCase2,
+		getTestCase3,
+		getTestCase4,
+		getTestCase5,
+		getTestCase6,
+		getTestCase7,
+		getTestCase8,
+		getTestCase9,
+		getTestCase10,
+		getTestCase11,
+		getTestCase12,
+		getTestCase13,
+		getTestCase14,
+		getTestCase15,
+		getTestCase16,
+		getTestCase17,
+		getTestCase18,
+		getTestCase19,
+		getTestCase20,
+		getTestCase21,
+		getTestCase22,
+		getTestCase23,
+		getTestCase24,
+		getTestCase25,
+		getTestCase26,
+		getTestCase27,
+		getTestCase28,
+		getTestCase29,
+		getTestCase30,
+		getTestCase31,
+		getTestCase32,
+		getTestCase33,
+		getTestCase34,
+		getTestCase35,
+		getTestCase36,
+		getTestCase37,
+		getTestCase38,
+		getTestCase39,
+		getTestCase40,
+		getTestCase41,
+		getTestCase42,
+		getTestCase43,
+		getTestCase44,
+		getTestCase45,
+		getTestCase46,
+		getTestCase47,
+		getTestCase48,
+		getTestCase49,
+		getTestCase50,
+		getTestCase51,
+		getTestCase52,
+		getTestCase53,
 	}
 	return testCases
 }
@@ -136,14 +332,14 @@ func getTestCase1() *testCase {
 	return &testCase{
 		name: "2trade(limitXlimit,marketXmarket)",
 		ords: []interface{}{
-			&ordersvc.Order{ID: "B1", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeLimit, Price: 10., Quantity: 100},
-			&ordersvc.Order{ID: "S1", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: 10., Quantity: 100},
-			&ordersvc.Order{ID: "B2", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeMarket, Quantity: 110},
-			&ordersvc.Order{ID: "S2", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeMarket, Quantity: 50},
+			&ordersvc.Order{ID: "B1", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(100)},
+			&ordersvc.Order{ID: "S1", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(100)},
+			&ordersvc.Order{ID: "B2", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeMarket, Quantity: decimal.New(110)},
+			&ordersvc.Order{ID: "S2", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeMarket, Quantity: decimal.New(50)},
 		},
 		expTrades: []*tradesvc.Trade{
-			{BuyOrderID: "B1", SellOrderID: "S1", Price: 10., Quantity: 100},
-			{BuyOrderID: "B2", SellOrderID: "S2", Price: 10., Quantity: 50},
+			{BuyOrderID: "B1", SellOrderID: "S1", Price: decimal.New(10), Quantity: decimal.New(100)},
+			{BuyOrderID: "B2", SellOrderID: "S2", Price: decimal.New(10), Quantity: decimal.New(50)},
 		},
 	}
 }
@@ -152,16 +348,16 @@ func getTestCase2() *testCase {
 	return &testCase{
 		name: "3trade(limitXlimit,marketXlimit,marketXlimit)",
 		ords: []interface{}{
-			&ordersvc.Order{ID: "B1", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeLimit, Price: 10., Quantity: 100},
-			&ordersvc.Order{ID: "S1", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: 10., Quantity: 100},
-			&ordersvc.Order{ID: "B2", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeMarket, Quantity: 110},
-			&ordersvc.Order{ID: "S2", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: 12., Quantity: 50},
-			&ordersvc.Order{ID: "S3", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: 10., Quantity: 60},
+			&ordersvc.Order{ID: "B1", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(100)},
+			&ordersvc.Order{ID: "S1", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(100)},
+			&ordersvc.Order{ID: "B2", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeMarket, Quantity: decimal.New(110)},
+			&ordersvc.Order{ID: "S2", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(12), Quantity: decimal.New(50)},
+			&ordersvc.Order{ID: "S3", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(60)},
 		},
 		expTrades: []*tradesvc.Trade{
-			{BuyOrderID: "B1", SellOrderID: "S1", Price: 10., Quantity: 100},
-			{BuyOrderID: "B2", SellOrderID: "S2", Price: 12., Quantity: 50},
-			{BuyOrderID: "B2", SellOrderID: "S3", Price: 10., Quantity: 60},
+			{BuyOrderID: "B1", SellOrderID: "S1", Price: decimal.New(10), Quantity: decimal.New(100)},
+			{BuyOrderID: "B2", SellOrderID: "S2", Price: decimal.New(12), Quantity: decimal.New(50)},
+			{BuyOrderID: "B2", SellOrderID: "S3", Price: decimal.New(10), Quantity: decimal.New(60)},
 		},
 	}
 }
@@ -170,10 +366,10 @@ func getTestCase3() *testCase {
 	return &testCase{
 		name: "0trade(priceNotMatch)",
 		ords: []interface{}{
-			&ordersvc.Order{ID: "B1", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeLimit, Price: 9., Quantity: 100},
-			&ordersvc.Order{ID: "B2", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeLimit, Price: 10., Quantity: 110},
-			&ordersvc.Order{ID: "S1", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: 11., Quantity: 100},
-			&ordersvc.Order{ID: "S2", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: 13., Quantity: 50},
+			&ordersvc.Order{ID: "B1", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(9), Quantity: decimal.New(100)},
+			&ordersvc.Order{ID: "B2", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(110)},
+			&ordersvc.Order{ID: "S1", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(11), Quantity: decimal.New(100)},
+			&ordersvc.Order{ID: "S2", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(13), Quantity: decimal.New(50)},
 		},
 		expTrades: []*tradesvc.Trade{},
 	}
@@ -183,13 +379,13 @@ func getTestCase4() *testCase {
 	return &testCase{
 		name: "1trade(buyLowerPrice)",
 		ords: []interface{}{
-			&ordersvc.Order{ID: "S1", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: 11., Quantity: 100},
-			&ordersvc.Order{ID: "S2", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: 9., Quantity: 50},
-			&ordersvc.Order{ID: "B1", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeMarket, Quantity: 110},
+			&ordersvc.Order{ID: "S1", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(11), Quantity: decimal.New(100)},
+			&ordersvc.Order{ID: "S2", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(9), Quantity: decimal.New(50)},
+			&ordersvc.Order{ID: "B1", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeMarket, Quantity: decimal.New(110)},
 		},
 		expTrades: []*tradesvc.Trade{
-			{BuyOrderID: "B1", SellOrderID: "S2", Price: 9., Quantity: 50},
-			{BuyOrderID: "B1", SellOrderID: "S1", Price: 11., Quantity: 60},
+			{BuyOrderID: "B1", SellOrderID: "S2", Price: decimal.New(9), Quantity: decimal.New(50)},
+			{BuyOrderID: "B1", SellOrderID: "S1", Price: decimal.New(11), Quantity: decimal.New(60)},
 		},
 	}
 }
@@ -198,13 +394,13 @@ func getTestCase5() *testCase {
 	return &testCase{
 		name: "2trade(buyEarlierFirstAndThenLatest)",
 		ords: []interface{}{
-			&ordersvc.Order{ID: "S1", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: 10., Quantity: 50},
-			&ordersvc.Order{ID: "S2", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: 10., Quantity: 50},
-			&ordersvc.Order{ID: "B1", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeMarket, Quantity: 100},
+			&ordersvc.Order{ID: "S1", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(50)},
+			&ordersvc.Order{ID: "S2", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(50)},
+			&ordersvc.Order{ID: "B1", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeMarket, Quantity: decimal.New(100)},
 		},
 		expTrades: []*tradesvc.Trade{
-			{BuyOrderID: "B1", SellOrderID: "S1", Price: 10., Quantity: 50},
-			{BuyOrderID: "B1", SellOrderID: "S2", Price: 10., Quantity: 50},
+			{BuyOrderID: "B1", SellOrderID: "S1", Price: decimal.New(10), Quantity: decimal.New(50)},
+			{BuyOrderID: "B1", SellOrderID: "S2", Price: decimal.New(10), Quantity: decimal.New(50)},
 		},
 	}
 }
@@ -213,13 +409,13 @@ func getTestCase6() *testCase {
 	return &testCase{
 		name: "2trade(marketPriceFirst)",
 		ords: []interface{}{
-			&ordersvc.Order{ID: "B1", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeLimit, Price: 11., Quantity: 50},
-			&ordersvc.Order{ID: "B2", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeMarket, Quantity: 50},
-			&ordersvc.Order{ID: "S1", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: 11., Quantity: 100},
+			&ordersvc.Order{ID: "B1", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(11), Quantity: decimal.New(50)},
+			&ordersvc.Order{ID: "B2", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeMarket, Quantity: decimal.New(50)},
+			&ordersvc.Order{ID: "S1", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(11), Quantity: decimal.New(100)},
 		},
 		expTrades: []*tradesvc.Trade{
-			{BuyOrderID: "B2", SellOrderID: "S1", Price: 11., Quantity: 50},
-			{BuyOrderID: "B1", SellOrderID: "S1", Price: 11., Quantity: 50},
+			{BuyOrderID: "B2", SellOrderID: "S1", Price: decimal.New(11), Quantity: decimal.New(50)},
+			{BuyOrderID: "B1", SellOrderID: "S1", Price: decimal.New(11), Quantity: decimal.New(50)},
 		},
 	}
 }
@@ -228,9 +424,9 @@ func getTestCase7() *testCase {
 	return &testCase{
 		name: "0trade(allMarKetPriceAndNoInitMarketPrice)",
 		ords: []interface{}{
-			&ordersvc.Order{ID: "S1", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeMarket, Quantity: 50},
-			&ordersvc.Order{ID: "S2", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeMarket, Quantity: 50},
-			&ordersvc.Order{ID: "B1", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeMarket, Quantity: 100},
+			&ordersvc.Order{ID: "S1", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeMarket, Quantity: decimal.New(50)},
+			&ordersvc.Order{ID: "S2", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeMarket, Quantity: decimal.New(50)},
+			&ordersvc.Order{ID: "B1", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeMarket, Quantity: decimal.New(100)},
 		},
 		expTrades: []*tradesvc.Trade{},
 	}
@@ -240,19 +436,19 @@ func getTestCase8() *testCase {
 	return &testCase{
 		name: "5trade(multipleLimits)",
 		ords: []interface{}{
-			&ordersvc.Order{ID: "S1", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: 10, Quantity: 350},
-			&ordersvc.Order{ID: "S2", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: 10, Quantity: 50},
-			&ordersvc.Order{ID: "B1", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeLimit, Price: 10, Quantity: 100},
-			&ordersvc.Order{ID: "B2", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeLimit, Price: 10, Quantity: 100},
-			&ordersvc.Order{ID: "B3", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeLimit, Price: 10, Quantity: 100},
-			&ordersvc.Order{ID: "B4", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeLimit, Price: 10, Quantity: 100},
+			&ordersvc.Order{ID: "S1", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(350)},
+			&ordersvc.Order{ID: "S2", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(50)},
+			&ordersvc.Order{ID: "B1", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(100)},
+			&ordersvc.Order{ID: "B2", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(100)},
+			&ordersvc.Order{ID: "B3", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(100)},
+			&ordersvc.Order{ID: "B4", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(100)},
 		},
 		expTrades: []*tradesvc.Trade{
-			{BuyOrderID: "B1", SellOrderID: "S1", Price: 10., Quantity: 100},
-			{BuyOrderID: "B2", SellOrderID: "S1", Price: 10., Quantity: 100},
-			{BuyOrderID: "B3", SellOrderID: "S1", Price: 10., Quantity: 100},
-			{BuyOrderID: "B4", SellOrderID: "S1", Price: 10., Quantity: 50},
-			{BuyOrderID: "B4", SellOrderID: "S2", Price: 10., Quantity: 50},
+			{BuyOrderID: "B1", SellOrderID: "S1", Price: decimal.New(10), Quantity: decimal.New(100)},
+			{BuyOrderID: "B2", SellOrderID: "S1", Price: decimal.New(10), Quantity: decimal.New(100)},
+			{BuyOrderID: "B3", SellOrderID: "S1", Price: decimal.New(10), Quantity: decimal.New(100)},
+			{BuyOrderID: "B4", SellOrderID: "S1", Price: decimal.New(10), Quantity: decimal.New(50)},
+			{BuyOrderID: "B4", SellOrderID: "S2", Price: decimal.New(10), Quantity: decimal.New(50)},
 		},
 	}
 }
@@ -261,16 +457,16 @@ func getTestCase9() *testCase {
 	return &testCase{
 		name: "1trade(tradeAndCancel)",
 		ords: []interface{}{
-			&ordersvc.Order{ID: "S1", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: 10, Quantity: 350},
-			&ordersvc.Order{ID: "B1", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeLimit, Price: 10, Quantity: 100},
+			&ordersvc.Order{ID: "S1", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(350)},
+			&ordersvc.Order{ID: "B1", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(100)},
 			&ordersvc.Cancel{OrderID: "S1", OrderKind: ordersvc.OrderKindSell},
-			&ordersvc.Order{ID: "B2", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeLimit, Price: 10, Quantity: 100},
+			&ordersvc.Order{ID: "B2", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(100)},
 		},
 		expTrades: []*tradesvc.Trade{
-			{BuyOrderID: "B1", SellOrderID: "S1", Price: 10., Quantity: 100},
+			{BuyOrderID: "B1", SellOrderID: "S1", Price: decimal.New(10), Quantity: decimal.New(100)},
 		},
 		expCancels: []*cancelsvc.Cancel{
-			{OrderID: "S1"},
+			{OrderID: "S1", Reason: cancelsvc.CancelReasonUser},
 		},
 	}
 }
@@ -279,26 +475,923 @@ func getTestCase10() *testCase {
 	return &testCase{
 		name: "6trade(complicate)",
 		ords: []interface{}{
-			&ordersvc.Order{ID: "S1", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: 10, Quantity: 350},
-			&ordersvc.Order{ID: "S2", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: 10, Quantity: 100},
-			&ordersvc.Order{ID: "B1", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeLimit, Price: 10, Quantity: 100},
-			&ordersvc.Order{ID: "B2", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeLimit, Price: 10, Quantity: 100},
-			&ordersvc.Order{ID: "B3", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeMarket, Quantity: 100},
-			&ordersvc.Order{ID: "B4", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeLimit, Price: 10, Quantity: 100},
+			&ordersvc.Order{ID: "S1", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(350)},
+			&ordersvc.Order{ID: "S2", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(100)},
+			&ordersvc.Order{ID: "B1", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(100)},
+			&ordersvc.Order{ID: "B2", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(100)},
+			&ordersvc.Order{ID: "B3", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeMarket, Quantity: decimal.New(100)},
+			&ordersvc.Order{ID: "B4", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(100)},
+			&ordersvc.Cancel{OrderID: "S2", OrderKind: ordersvc.OrderKindSell},
+			&ordersvc.Order{ID: "B5", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeMarket, Quantity: decimal.New(100)},
+			&ordersvc.Order{ID: "S3", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeMarket, Quantity: decimal.New(150)},
+		},
+		expTrades: []*tradesvc.Trade{
+			{BuyOrderID: "B1", SellOrderID: "S1", Price: decimal.New(10), Quantity: decimal.New(100)},
+			{BuyOrderID: "B2", SellOrderID: "S1", Price: decimal.New(10), Quantity: decimal.New(100)},
+			{BuyOrderID: "B3", SellOrderID: "S1", Price: decimal.New(10), Quantity: decimal.New(100)},
+			{BuyOrderID: "B4", SellOrderID: "S1", Price: decimal.New(10), Quantity: decimal.New(50)},
+			{BuyOrderID: "B4", SellOrderID: "S2", Price: decimal.New(10), Quantity: decimal.New(50)},
+			{BuyOrderID: "B5", SellOrderID: "S3", Price: decimal.New(10), Quantity: decimal.New(100)},
+		},
+		expCancels: []*cancelsvc.Cancel{
+			{OrderID: "S2", Reason: cancelsvc.CancelReasonUser},
+		},
+	}
+}
+
+func getTestCase11() *testCase {
+	return &testCase{
+		name: "2trade(separateBooksPerSymbol)",
+		ords: []interface{}{
+			&ordersvc.Order{ID: "B1", Symbol: "BTC-USD", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(100)},
+			&ordersvc.Order{ID: "S1", Symbol: "ETH-USD", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(100)},
+			&ordersvc.Order{ID: "S2", Symbol: "BTC-USD", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(100)},
+			&ordersvc.Order{ID: "B2", Symbol: "ETH-USD", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(12), Quantity: decimal.New(50)},
+			&ordersvc.Order{ID: "B3", Symbol: "ETH-USD", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeMarket, Quantity: decimal.New(50)},
+			&ordersvc.Order{ID: "S3", Symbol: "BTC-USD", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeMarket, Quantity: decimal.New(50)},
+		},
+		expTrades: []*tradesvc.Trade{
+			{Symbol: "BTC-USD", BuyOrderID: "B1", SellOrderID: "S2", Price: decimal.New(10), Quantity: decimal.New(100)},
+			{Symbol: "ETH-USD", BuyOrderID: "B2", SellOrderID: "S1", Price: decimal.New(10), Quantity: decimal.New(50)},
+			{Symbol: "ETH-USD", BuyOrderID: "B3", SellOrderID: "S1", Price: decimal.New(10), Quantity: decimal.New(50)},
+		},
+	}
+}
+
+func getTestCase12() *testCase {
+	return &testCase{
+		name: "1trade(immediateOrCancelDropsRemainder)",
+		ords: []interface{}{
+			&ordersvc.Order{ID: "S1", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(50)},
+			&ordersvc.Order{ID: "B1", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(100), TimeInForce: ordersvc.TimeInForceIOC},
+			&ordersvc.Order{ID: "S2", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(50)},
+		},
+		expTrades: []*tradesvc.Trade{
+			{BuyOrderID: "B1", SellOrderID: "S1", Price: decimal.New(10), Quantity: decimal.New(50)},
+		},
+		expCancels: []*cancelsvc.Cancel{
+			{OrderID: "B1", Reason: cancelsvc.CancelReasonImmediateOrCancel},
+		},
+	}
+}
+
+func getTestCase13() *testCase {
+	return &testCase{
+		name: "0trade(fillOrKillNotEnoughQuantity)",
+		ords: []interface{}{
+			&ordersvc.Order{ID: "S1", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(50)},
+			&ordersvc.Order{ID: "S2", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(11), Quantity: decimal.New(50)},
+			&ordersvc.Order{ID: "B1", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(100), TimeInForce: ordersvc.TimeInForceFOK},
+		},
+		expTrades: []*tradesvc.Trade{},
+		expCancels: []*cancelsvc.Cancel{
+			{OrderID: "B1", Reason: cancelsvc.CancelReasonFillOrKill},
+		},
+	}
+}
+
+func getTestCase14() *testCase {
+	return &testCase{
+		name: "2trade(fillOrKillFilled)",
+		ords: []interface{}{
+			&ordersvc.Order{ID: "S1", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(50)},
+			&ordersvc.Order{ID: "S2", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(11), Quantity: decimal.New(50)},
+			&ordersvc.Order{ID: "B1", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(11), Quantity: decimal.New(100), TimeInForce: ordersvc.TimeInForceFOK},
+		},
+		expTrades: []*tradesvc.Trade{
+			{BuyOrderID: "B1", SellOrderID: "S1", Price: decimal.New(10), Quantity: decimal.New(50)},
+			{BuyOrderID: "B1", SellOrderID: "S2", Price: decimal.New(11), Quantity: decimal.New(50)},
+		},
+	}
+}
+
+func getTestCase15() *testCase {
+	return &testCase{
+		name: "1trade(goodTillDateExpires)",
+		ords: []interface{}{
+			&ordersvc.Order{ID: "S1", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(100), TimeInForce: ordersvc.TimeInForceGTD, ExpireAt: time.Now().Add(50 * time.Millisecond).UnixNano()},
+			&ordersvc.Order{ID: "B1", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(40)},
+		},
+		expTrades: []*tradesvc.Trade{
+			{BuyOrderID: "B1", SellOrderID: "S1", Price: decimal.New(10), Quantity: decimal.New(40)},
+		},
+		expCancels: []*cancelsvc.Cancel{
+			{OrderID: "S1", Reason: cancelsvc.CancelReasonExpired},
+		},
+	}
+}
+
+func getTestCase16() *testCase {
+	return &testCase{
+		name: "4trade(buyStopsTriggeredInCascade)",
+		ords: []interface{}{
+			&ordersvc.Order{ID: "S1", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(100)},
+			&ordersvc.Order{ID: "S2", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(11), Quantity: decimal.New(100)},
+			&ordersvc.Order{ID: "B1", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeStopLimit, TriggerPrice: decimal.New(11), Price: decimal.New(11), Quantity: decimal.New(50)},
+			&ordersvc.Order{ID: "B2", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeStop, TriggerPrice: decimal.MustParse("10.5"), Quantity: decimal.New(50)},
+			&ordersvc.Order{ID: "B3", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(100)},
+			&ordersvc.Order{ID: "B4", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(11), Quantity: decimal.New(10)},
+		},
+		expTrades: []*tradesvc.Trade{
+			{BuyOrderID: "B3", SellOrderID: "S1", Price: decimal.New(10), Quantity: decimal.New(100)},
+			{BuyOrderID: "B4", SellOrderID: "S2", Price: decimal.New(11), Quantity: decimal.New(10)},
+			{BuyOrderID: "B2", SellOrderID: "S2", Price: decimal.New(11), Quantity: decimal.New(50)},
+			{BuyOrderID: "B1", SellOrderID: "S2", Price: decimal.New(11), Quantity: decimal.New(40)},
+		},
+	}
+}
+
+func getTestCase17() *testCase {
+	return &testCase{
+		name: "3trade(sellStopTriggeredAndCanceledStop)",
+		ords: []interface{}{
+			&ordersvc.Order{ID: "B1", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(50)},
+			&ordersvc.Order{ID: "B2", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(8), Quantity: decimal.New(100)},
+			&ordersvc.Order{ID: "S1", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeStop, TriggerPrice: decimal.New(9), Quantity: decimal.New(50)},
+			&ordersvc.Order{ID: "S2", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeStop, TriggerPrice: decimal.New(8), Quantity: decimal.New(50)},
+			&ordersvc.Cancel{OrderID: "S2", OrderKind: ordersvc.OrderKindSell},
+			&ordersvc.Order{ID: "S3", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(50)},
+			&ordersvc.Order{ID: "S4", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeMarket, Quantity: decimal.New(10)},
+		},
+		expTrades: []*tradesvc.Trade{
+			{BuyOrderID: "B1", SellOrderID: "S3", Price: decimal.New(10), Quantity: decimal.New(50)},
+			{BuyOrderID: "B2", SellOrderID: "S4", Price: decimal.New(8), Quantity: decimal.New(10)},
+			{BuyOrderID: "B2", SellOrderID: "S1", Price: decimal.New(8), Quantity: decimal.New(50)},
+		},
+		expCancels: []*cancelsvc.Cancel{
+			{OrderID: "S2", Reason: cancelsvc.CancelReasonUser},
+		},
+	}
+}
+
+func getTestCase18() *testCase {
+	return &testCase{
+		name: "5trade(icebergRefillLosesPriority)",
+		ords: []interface{}{
+			&ordersvc.Order{ID: "S1", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(100), DisplayQuantity: decimal.New(30)},
+			&ordersvc.Order{ID: "S2", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(50)},
+			&ordersvc.Order{ID: "B1", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(40)},
+			&ordersvc.Order{ID: "B2", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(100)},
+		},
+		expTrades: []*tradesvc.Trade{
+			{BuyOrderID: "B1", SellOrderID: "S1", Price: decimal.New(10), Quantity: decimal.New(30)},
+			{BuyOrderID: "B1", SellOrderID: "S2", Price: decimal.New(10), Quantity: decimal.New(10)},
+			{BuyOrderID: "B2", SellOrderID: "S2", Price: decimal.New(10), Quantity: decimal.New(40)},
+			{BuyOrderID: "B2", SellOrderID: "S1", Price: decimal.New(10), Quantity: decimal.New(30)},
+			{BuyOrderID: "B2", SellOrderID: "S1", Price: decimal.New(10), Quantity: decimal.New(30)},
+		},
+	}
+}
+
+func getTestCase19() *testCase {
+	return &testCase{
+		name: "1trade(postOnlyRejected)",
+		ords: []interface{}{
+			&ordersvc.Order{ID: "S1", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(100)},
+			&ordersvc.Order{ID: "B1", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(100), PostOnly: true},
+			&ordersvc.Order{ID: "B2", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(9), Quantity: decimal.New(100), PostOnly: true},
+			&ordersvc.Order{ID: "S2", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(9), Quantity: decimal.New(100)},
+		},
+		expTrades: []*tradesvc.Trade{
+			{BuyOrderID: "B2", SellOrderID: "S2", Price: decimal.New(9), Quantity: decimal.New(100)},
+		},
+		expCancels: []*cancelsvc.Cancel{
+			{OrderID: "B1", Reason: cancelsvc.CancelReasonPostOnly},
+		},
+	}
+}
+
+func getTestCase20() *testCase {
+	return &testCase{
+		name: "1trade(postOnlyRepriced)",
+		opts: []engine.MatchEngineOption{
+			engine.WithPostOnlyPolicy(engine.PostOnlyPolicyReprice),
+			engine.WithInstrumentStore(instrumentsvc.NewMemoryStore(instrumentsvc.Instrument{Symbol: "BTC-USD", TickSize: decimal.MustParse("0.5")})),
+		},
+		ords: []interface{}{
+			&ordersvc.Order{ID: "S1", Symbol: "BTC-USD", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(100)},
+			&ordersvc.Order{ID: "B1", Symbol: "BTC-USD", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(11), Quantity: decimal.New(100), PostOnly: true},
+			&ordersvc.Order{ID: "S2", Symbol: "BTC-USD", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeMarket, Quantity: decimal.New(100)},
+		},
+		expTrades: []*tradesvc.Trade{
+			{Symbol: "BTC-USD", BuyOrderID: "B1", SellOrderID: "S2", Price: decimal.MustParse("9.5"), Quantity: decimal.New(100)},
+		},
+	}
+}
+
+func getTestCase21() *testCase {
+	return &testCase{
+		name: "2trade(amendQuantityDownKeepsPriority)",
+		ords: []interface{}{
+			&ordersvc.Order{ID: "S1", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(100)},
+			&ordersvc.Order{ID: "S2", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(100)},
+			&ordersvc.Amend{OrderID: "S1", OrderKind: ordersvc.OrderKindSell, Quantity: decimal.New(50)},
+			&ordersvc.Order{ID: "B1", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(100)},
+		},
+		expTrades: []*tradesvc.Trade{
+			{BuyOrderID: "B1", SellOrderID: "S1", Price: decimal.New(10), Quantity: decimal.New(50)},
+			{BuyOrderID: "B1", SellOrderID: "S2", Price: decimal.New(10), Quantity: decimal.New(50)},
+		},
+	}
+}
+
+func getTestCase22() *testCase {
+	return &testCase{
+		name: "3trade(amendQuantityUpOrPriceLosesPriority)",
+		ords: []interface{}{
+			&ordersvc.Order{ID: "S1", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(50)},
+			&ordersvc.Order{ID: "S2", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(50)},
+			&ordersvc.Amend{OrderID: "S1", OrderKind: ordersvc.OrderKindSell, Quantity: decimal.New(60)},
+			&ordersvc.Order{ID: "B1", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(100)},
+			&ordersvc.Order{ID: "B2", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(9), Quantity: decimal.New(20)},
+			&ordersvc.Amend{OrderID: "B2", OrderKind: ordersvc.OrderKindBuy, Price: decimal.New(10)},
+		},
+		expTrades: []*tradesvc.Trade{
+			{BuyOrderID: "B1", SellOrderID: "S2", Price: decimal.New(10), Quantity: decimal.New(50)},
+			{BuyOrderID: "B1", SellOrderID: "S1", Price: decimal.New(10), Quantity: decimal.New(50)},
+			{BuyOrderID: "B2", SellOrderID: "S1", Price: decimal.New(10), Quantity: decimal.New(10)},
+		},
+	}
+}
+
+func getTestCase23() *testCase {
+	return &testCase{
+		name: "1trade(selfTradeCancelNewest)",
+		opts: []engine.MatchEngineOption{engine.WithSelfTradePrevention(engine.SelfTradePreventionCancelNewest)},
+		ords: []interface{}{
+			&ordersvc.Order{ID: "S1", AccountID: "A", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(50)},
+			&ordersvc.Order{ID: "B1", AccountID: "A", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(50)},
+			&ordersvc.Order{ID: "B2", AccountID: "B", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(50)},
+		},
+		expTrades: []*tradesvc.Trade{
+			{BuyOrderID: "B2", SellOrderID: "S1", Price: decimal.New(10), Quantity: decimal.New(50)},
+		},
+		expCancels: []*cancelsvc.Cancel{
+			{OrderID: "B1", Reason: cancelsvc.CancelReasonSelfTrade},
+		},
+	}
+}
+
+func getTestCase24() *testCase {
+	return &testCase{
+		name: "2trade(selfTradeCancelOldest)",
+		opts: []engine.MatchEngineOption{engine.WithSelfTradePrevention(engine.SelfTradePreventionCancelOldest)},
+		ords: []interface{}{
+			&ordersvc.Order{ID: "S1", AccountID: "A", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(50)},
+			&ordersvc.Order{ID: "S2", AccountID: "B", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(50)},
+			&ordersvc.Order{ID: "B1", AccountID: "A", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(100)},
+			&ordersvc.Order{ID: "S3", AccountID: "B", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(50)},
+		},
+		expTrades: []*tradesvc.Trade{
+			{BuyOrderID: "B1", SellOrderID: "S2", Price: decimal.New(10), Quantity: decimal.New(50)},
+			{BuyOrderID: "B1", SellOrderID: "S3", Price: decimal.New(10), Quantity: decimal.New(50)},
+		},
+		expCancels: []*cancelsvc.Cancel{
+			{OrderID: "S1", Reason: cancelsvc.CancelReasonSelfTrade},
+		},
+	}
+}
+
+func getTestCase25() *testCase {
+	return &testCase{
+		name: "2trade(selfTradeDecrementAndCancel)",
+		opts: []engine.MatchEngineOption{engine.WithSelfTradePrevention(engine.SelfTradePreventionDecrementAndCancel)},
+		ords: []interface{}{
+			&ordersvc.Order{ID: "S1", AccountID: "A", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(80)},
+			&ordersvc.Order{ID: "S2", AccountID: "C", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(50)},
+			&ordersvc.Order{ID: "B1", AccountID: "A", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(50)},
+			&ordersvc.Order{ID: "B2", AccountID: "B", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(40)},
+		},
+		expTrades: []*tradesvc.Trade{
+			{BuyOrderID: "B2", SellOrderID: "S1", Price: decimal.New(10), Quantity: decimal.New(30)},
+			{BuyOrderID: "B2", SellOrderID: "S2", Price: decimal.New(10), Quantity: decimal.New(10)},
+		},
+		expCancels: []*cancelsvc.Cancel{
+			{OrderID: "B1", Reason: cancelsvc.CancelReasonSelfTrade},
+		},
+	}
+}
+
+func getTestCase26() *testCase {
+	return &testCase{
+		name: "5trade(proRata)",
+		opts: []engine.MatchEngineOption{
+			engine.WithInstrumentStore(instrumentsvc.NewMemoryStore(instrumentsvc.Instrument{Symbol: "SOFR", MatchingAlgorithm: instrumentsvc.MatchingAlgorithmProRata})),
+		},
+		ords: []interface{}{
+			&ordersvc.Order{ID: "S1", Symbol: "SOFR", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(30)},
+			&ordersvc.Order{ID: "S2", Symbol: "SOFR", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(60)},
+			&ordersvc.Order{ID: "S3", Symbol: "SOFR", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(10)},
+			&ordersvc.Order{ID: "B1", Symbol: "SOFR", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(50)},
+			&ordersvc.Order{ID: "B2", Symbol: "SOFR", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(7)},
+		},
+		expTrades: []*tradesvc.Trade{
+			{Symbol: "SOFR", BuyOrderID: "B1", SellOrderID: "S1", Price: decimal.New(10), Quantity: decimal.New(15)},
+			{Symbol: "SOFR", BuyOrderID: "B1", SellOrderID: "S2", Price: decimal.New(10), Quantity: decimal.New(30)},
+			{Symbol: "SOFR", BuyOrderID: "B1", SellOrderID: "S3", Price: decimal.New(10), Quantity: decimal.New(5)},
+			{Symbol: "SOFR", BuyOrderID: "B2", SellOrderID: "S1", Price: decimal.New(10), Quantity: decimal.New(3)},
+			{Symbol: "SOFR", BuyOrderID: "B2", SellOrderID: "S2", Price: decimal.New(10), Quantity: decimal.New(4)},
+		},
+	}
+}
+
+func getTestCase27() *testCase {
+	return &testCase{
+		name: "3trade(hybrid)",
+		opts: []engine.MatchEngineOption{
+			engine.WithInstrumentStore(instrumentsvc.NewMemoryStore(instrumentsvc.Instrument{Symbol: "SOFR", MatchingAlgorithm: instrumentsvc.MatchingAlgorithmHybrid, TopOrderRatio: decimal.MustParse("0.4")})),
+		},
+		ords: []interface{}{
+			&ordersvc.Order{ID: "S1", Symbol: "SOFR", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(30)},
+			&ordersvc.Order{ID: "S2", Symbol: "SOFR", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(60)},
+			&ordersvc.Order{ID: "S3", Symbol: "SOFR", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(10)},
+			&ordersvc.Order{ID: "B1", Symbol: "SOFR", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(50)},
+		},
+		expTrades: []*tradesvc.Trade{
+			{Symbol: "SOFR", BuyOrderID: "B1", SellOrderID: "S1", Price: decimal.New(10), Quantity: decimal.New(24)},
+			{Symbol: "SOFR", BuyOrderID: "B1", SellOrderID: "S2", Price: decimal.New(10), Quantity: decimal.New(23)},
+			{Symbol: "SOFR", BuyOrderID: "B1", SellOrderID: "S3", Price: decimal.New(10), Quantity: decimal.New(3)},
+		},
+	}
+}
+
+func getTestCase28() *testCase {
+	return &testCase{
+		name: "2trade(fractionalPriceAndQuantity)",
+		ords: []interface{}{
+			&ordersvc.Order{ID: "S1", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.MustParse("0.1").Add(decimal.MustParse("0.2")), Quantity: decimal.MustParse("0.5")},
+			&ordersvc.Order{ID: "B1", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeLimit, Price: decimal.MustParse("0.3"), Quantity: decimal.MustParse("0.2")},
+			&ordersvc.Order{ID: "B2", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeMarket, Quantity: decimal.MustParse("0.35")},
+		},
+		expTrades: []*tradesvc.Trade{
+			{BuyOrderID: "B1", SellOrderID: "S1", Price: decimal.MustParse("0.3"), Quantity: decimal.MustParse("0.2")},
+			{BuyOrderID: "B2", SellOrderID: "S1", Price: decimal.MustParse("0.3"), Quantity: decimal.MustParse("0.3")},
+		},
+	}
+}
+
+func getTestCase29() *testCase {
+	return &testCase{
+		name: "1trade(marketRemainderCanceled)",
+		opts: []engine.MatchEngineOption{engine.WithMarketOrderPolicy(engine.MarketOrderPolicyCancel)},
+		ords: []interface{}{
+			&ordersvc.Order{ID: "S1", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(50)},
+			&ordersvc.Order{ID: "B1", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeMarket, Quantity: decimal.New(100)},
+			&ordersvc.Order{ID: "S2", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(100), Quantity: decimal.New(50)},
+		},
+		expTrades: []*tradesvc.Trade{
+			{BuyOrderID: "B1", SellOrderID: "S1", Price: decimal.New(10), Quantity: decimal.New(50)},
+		},
+		expCancels: []*cancelsvc.Cancel{
+			{OrderID: "B1", Reason: cancelsvc.CancelReasonMarketRemainder},
+		},
+	}
+}
+
+func getTestCase30() *testCase {
+	return &testCase{
+		name: "3trade(marketRemainderConvertedToLimit)",
+		opts: []engine.MatchEngineOption{engine.WithMarketOrderPolicy(engine.MarketOrderPolicyConvertToLimit)},
+		ords: []interface{}{
+			&ordersvc.Order{ID: "S1", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(50)},
+			&ordersvc.Order{ID: "S2", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(11), Quantity: decimal.New(50)},
+			&ordersvc.Order{ID: "B1", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeMarket, Quantity: decimal.New(150)},
+			&ordersvc.Order{ID: "B2", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(12), Quantity: decimal.New(50)},
+			&ordersvc.Order{ID: "S3", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(12), Quantity: decimal.New(50)},
+			&ordersvc.Order{ID: "S4", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(11), Quantity: decimal.New(50)},
+		},
+		expTrades: []*tradesvc.Trade{
+			{BuyOrderID: "B1", SellOrderID: "S1", Price: decimal.New(10), Quantity: decimal.New(50)},
+			{BuyOrderID: "B1", SellOrderID: "S2", Price: decimal.New(11), Quantity: decimal.New(50)},
+			{BuyOrderID: "B2", SellOrderID: "S3", Price: decimal.New(12), Quantity: decimal.New(50)},
+			{BuyOrderID: "B1", SellOrderID: "S4", Price: decimal.New(11), Quantity: decimal.New(50)},
+		},
+	}
+}
+
+func getTestCase31() *testCase {
+	return &testCase{
+		name: "0trade(marketRejectedWithoutLiquidity)",
+		opts: []engine.MatchEngineOption{engine.WithMarketOrderPolicy(engine.MarketOrderPolicyReject)},
+		ords: []interface{}{
+			&ordersvc.Order{ID: "B1", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeMarket, Quantity: decimal.New(100)},
+			&ordersvc.Order{ID: "S1", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(50)},
+		},
+		expCancels: []*cancelsvc.Cancel{
+			{OrderID: "B1", Reason: cancelsvc.CancelReasonNoLiquidity},
+		},
+	}
+}
+
+func getTestCase32() *testCase {
+	return &testCase{
+		name: "1trade(marketProtectionPrice)",
+		opts: []engine.MatchEngineOption{engine.WithMarketOrderPolicy(engine.MarketOrderPolicyCancel)},
+		ords: []interface{}{
+			&ordersvc.Order{ID: "S1", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(50)},
+			&ordersvc.Order{ID: "S2", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(1000), Quantity: decimal.New(50)},
+			&ordersvc.Order{ID: "B1", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeMarket, ProtectionPrice: decimal.New(11), Quantity: decimal.New(100)},
+		},
+		expTrades: []*tradesvc.Trade{
+			{BuyOrderID: "B1", SellOrderID: "S1", Price: decimal.New(10), Quantity: decimal.New(50)},
+		},
+		expCancels: []*cancelsvc.Cancel{
+			{OrderID: "B1", Reason: cancelsvc.CancelReasonMarketRemainder},
+		},
+	}
+}
+
+func getTestCase33() *testCase {
+	// an auction running from an hour ago until shortly after the orders are sent
+	now := time.Now().UTC()
+	timeOfDay := now.Sub(now.Truncate(24 * time.Hour))
+	window := engine.AuctionWindow{Start: (timeOfDay - time.Hour + 24*time.Hour) % (24 * time.Hour), End: (timeOfDay + 50*time.Millisecond) % (24 * time.Hour)}
+
+	return &testCase{
+		name: "2trade(callAuction)",
+		opts: []engine.MatchEngineOption{engine.WithAuctions(window)},
+		ords: []interface{}{
+			&ordersvc.Order{ID: "B1", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(101), Quantity: decimal.New(10)},
+			&ordersvc.Order{ID: "S1", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(99), Quantity: decimal.New(20)},
+			&ordersvc.Order{ID: "B2", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(100), Quantity: decimal.New(20)},
+			&ordersvc.Order{ID: "S2", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(102), Quantity: decimal.New(10)},
+			&ordersvc.Order{ID: "B3", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(102), Quantity: decimal.New(5), TimeInForce: ordersvc.TimeInForceIOC},
+		},
+		expTrades: []*tradesvc.Trade{
+			{BuyOrderID: "B1", SellOrderID: "S1", Price: decimal.New(100), Quantity: decimal.New(10)},
+			{BuyOrderID: "B2", SellOrderID: "S1", Price: decimal.New(100), Quantity: decimal.New(10)},
+		},
+		expCancels: []*cancelsvc.Cancel{
+			{OrderID: "B3", Reason: cancelsvc.CancelReasonImmediateOrCancel},
+		},
+	}
+}
+
+func getTestCase34() *testCase {
+	return &testCase{
+		name: "3trade(priceBandHaltAndResume)",
+		opts: []engine.MatchEngineOption{
+			engine.WithInstrumentStore(instrumentsvc.NewMemoryStore(instrumentsvc.Instrument{Symbol: "BTC-USD", PriceBandPercent: decimal.New(5)})),
+			engine.WithCircuitBreaker(20*time.Millisecond, 20*time.Millisecond),
+		},
+		ords: []interface{}{
+			&ordersvc.Order{ID: "S1", Symbol: "BTC-USD", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(100)},
+			&ordersvc.Order{ID: "B1", Symbol: "BTC-USD", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(100)},
+			&ordersvc.Order{ID: "S2", Symbol: "BTC-USD", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(50)},
+			&ordersvc.Order{ID: "S3", Symbol: "BTC-USD", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(12), Quantity: decimal.New(50)},
+			&ordersvc.Order{ID: "B2", Symbol: "BTC-USD", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeMarket, Quantity: decimal.New(100)},
+		},
+		expTrades: []*tradesvc.Trade{
+			{Symbol: "BTC-USD", BuyOrderID: "B1", SellOrderID: "S1", Price: decimal.New(10), Quantity: decimal.New(100)},
+			{Symbol: "BTC-USD", BuyOrderID: "B2", SellOrderID: "S2", Price: decimal.New(10), Quantity: decimal.New(50)},
+			{Symbol: "BTC-USD", BuyOrderID: "B2", SellOrderID: "S3", Price: decimal.New(12), Quantity: decimal.New(50)},
+		},
+		expStatuses: []*statussvc.Status{
+			{Phase: statussvc.TradingPhaseHalted, Reason: statussvc.StatusReasonPriceBand},
+			{Phase: statussvc.TradingPhaseAuction, Reason: statussvc.StatusReasonCooldown},
+			{Phase: statussvc.TradingPhaseContinuous, Reason: statussvc.StatusReasonUncross},
+		},
+	}
+}
+
+func getTestCase35() *testCase {
+	return &testCase{
+		name: "1trade(haltedRejectsNewOrders)",
+		opts: []engine.MatchEngineOption{
+			engine.WithInstrumentStore(instrumentsvc.NewMemoryStore(instrumentsvc.Instrument{Symbol: "BTC-USD", PriceBandPercent: decimal.New(5)})),
+			engine.WithCircuitBreaker(time.Hour, time.Minute),
+			engine.WithHaltOrderPolicy(engine.HaltOrderPolicyReject),
+		},
+		ords: []interface{}{
+			&ordersvc.Order{ID: "S1", Symbol: "BTC-USD", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(100)},
+			&ordersvc.Order{ID: "B1", Symbol: "BTC-USD", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(100)},
+			&ordersvc.Order{ID: "S2", Symbol: "BTC-USD", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(12), Quantity: decimal.New(50)},
+			&ordersvc.Order{ID: "B2", Symbol: "BTC-USD", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(12), Quantity: decimal.New(50)},
+			&ordersvc.Order{ID: "B3", Symbol: "BTC-USD", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(10)},
+		},
+		expTrades: []*tradesvc.Trade{
+			{Symbol: "BTC-USD", BuyOrderID: "B1", SellOrderID: "S1", Price: decimal.New(10), Quantity: decimal.New(100)},
+		},
+		expCancels: []*cancelsvc.Cancel{
+			{OrderID: "B3", Reason: cancelsvc.CancelReasonHalted},
+		},
+		expStatuses: []*statussvc.Status{
+			{Phase: statussvc.TradingPhaseHalted, Reason: statussvc.StatusReasonPriceBand},
+		},
+	}
+}
+
+func getTestCase36() *testCase {
+	return &testCase{
+		name: "4trade(trailingStopAmount)",
+		ords: []interface{}{
+			&ordersvc.Order{ID: "S1", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(100), Quantity: decimal.New(10)},
+			&ordersvc.Order{ID: "B1", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(100), Quantity: decimal.New(10)},
+			&ordersvc.Order{ID: "T1", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeTrailingStop, TrailAmount: decimal.New(2), Quantity: decimal.New(5)},
+			&ordersvc.Order{ID: "S2", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(104), Quantity: decimal.New(10)},
+			&ordersvc.Order{ID: "B2", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(104), Quantity: decimal.New(10)},
+			&ordersvc.Order{ID: "B3", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(101), Quantity: decimal.New(5)},
+			&ordersvc.Order{ID: "S4", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(102), Quantity: decimal.New(5)},
+			&ordersvc.Order{ID: "B4", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(102), Quantity: decimal.New(5)},
+		},
+		expTrades: []*tradesvc.Trade{
+			{BuyOrderID: "B1", SellOrderID: "S1", Price: decimal.New(100), Quantity: decimal.New(10)},
+			{BuyOrderID: "B2", SellOrderID: "S2", Price: decimal.New(104), Quantity: decimal.New(10)},
+			{BuyOrderID: "B4", SellOrderID: "S4", Price: decimal.New(102), Quantity: decimal.New(5)},
+			{BuyOrderID: "B3", SellOrderID: "T1", Price: decimal.New(101), Quantity: decimal.New(5)},
+		},
+	}
+}
+
+func getTestCase37() *testCase {
+	return &testCase{
+		name: "4trade(trailingStopLimitPercent)",
+		ords: []interface{}{
+			&ordersvc.Order{ID: "S1", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(100), Quantity: decimal.New(10)},
+			&ordersvc.Order{ID: "B1", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(100), Quantity: decimal.New(10)},
+			&ordersvc.Order{ID: "T1", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeTrailingStopLimit, TrailPercent: decimal.New(10), LimitOffset: decimal.New(1), Quantity: decimal.New(5)},
+			&ordersvc.Order{ID: "S2", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(90), Quantity: decimal.New(10)},
+			&ordersvc.Order{ID: "B2", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(90), Quantity: decimal.New(10)},
+			&ordersvc.Order{ID: "S3", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(100), Quantity: decimal.New(5)},
+			&ordersvc.Order{ID: "S4", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(99), Quantity: decimal.New(5)},
+			&ordersvc.Order{ID: "B3", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(99), Quantity: decimal.New(5)},
+		},
+		expTrades: []*tradesvc.Trade{
+			{BuyOrderID: "B1", SellOrderID: "S1", Price: decimal.New(100), Quantity: decimal.New(10)},
+			{BuyOrderID: "B2", SellOrderID: "S2", Price: decimal.New(90), Quantity: decimal.New(10)},
+			{BuyOrderID: "B3", SellOrderID: "S4", Price: decimal.New(99), Quantity: decimal.New(5)},
+			{BuyOrderID: "T1", SellOrderID: "S3", Price: decimal.New(100), Quantity: decimal.New(5)},
+		},
+	}
+}
+
+func getTestCase38() *testCase {
+	return &testCase{
+		name: "2trade(ocoFillCancelsOther)",
+		ords: []interface{}{
+			&ordersvc.OrderGroup{ID: "G1", Kind: ordersvc.OrderGroupKindOCO, Orders: []ordersvc.Order{
+				{ID: "TP", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(110), Quantity: decimal.New(10)},
+				{ID: "SL", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeStop, TriggerPrice: decimal.New(95), Quantity: decimal.New(10)},
+			}},
+			&ordersvc.Order{ID: "B1", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(110), Quantity: decimal.New(4)},
+			&ordersvc.Order{ID: "B2", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(110), Quantity: decimal.New(6)},
+		},
+		expTrades: []*tradesvc.Trade{
+			{BuyOrderID: "B1", SellOrderID: "TP", Price: decimal.New(110), Quantity: decimal.New(4)},
+			{BuyOrderID: "B2", SellOrderID: "TP", Price: decimal.New(110), Quantity: decimal.New(6)},
+		},
+		expCancels: []*cancelsvc.Cancel{
+			{OrderID: "SL", Reason: cancelsvc.CancelReasonLinked},
+		},
+	}
+}
+
+func getTestCase39() *testCase {
+	return &testCase{
+		name: "4trade(bracketExitsPlacedAfterEntryFill)",
+		ords: []interface{}{
+			&ordersvc.OrderGroup{ID: "G1", Kind: ordersvc.OrderGroupKindBracket, Orders: []ordersvc.Order{
+				{ID: "E1", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(100), Quantity: decimal.New(10)},
+				{ID: "TP", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(110), Quantity: decimal.New(10)},
+				{ID: "SL", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeStop, TriggerPrice: decimal.New(95), Quantity: decimal.New(10)},
+			}},
+			&ordersvc.Order{ID: "B1", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(110), Quantity: decimal.New(5)},
+			&ordersvc.Order{ID: "S1", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(100), Quantity: decimal.New(15)},
+			&ordersvc.Order{ID: "B2", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(94), Quantity: decimal.New(11)},
+			&ordersvc.Order{ID: "S2", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(94), Quantity: decimal.New(1)},
+		},
+		expTrades: []*tradesvc.Trade{
+			{BuyOrderID: "B1", SellOrderID: "S1", Price: decimal.New(110), Quantity: decimal.New(5)},
+			{BuyOrderID: "E1", SellOrderID: "S1", Price: decimal.New(100), Quantity: decimal.New(10)},
+			{BuyOrderID: "B2", SellOrderID: "S2", Price: decimal.New(94), Quantity: decimal.New(1)},
+			{BuyOrderID: "B2", SellOrderID: "SL", Price: decimal.New(94), Quantity: decimal.New(10)},
+		},
+		expCancels: []*cancelsvc.Cancel{
+			{OrderID: "TP", Reason: cancelsvc.CancelReasonLinked},
+		},
+	}
+}
+
+func getTestCase40() *testCase {
+	return &testCase{
+		name: "0trade(bracketEntryCanceled)",
+		ords: []interface{}{
+			&ordersvc.OrderGroup{ID: "G1", Kind: ordersvc.OrderGroupKindBracket, Orders: []ordersvc.Order{
+				{ID: "E1", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(100), Quantity: decimal.New(10)},
+				{ID: "TP", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(110), Quantity: decimal.New(10)},
+				{ID: "SL", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeStop, TriggerPrice: decimal.New(95), Quantity: decimal.New(10)},
+			}},
+			&ordersvc.Cancel{OrderID: "E1", OrderKind: ordersvc.OrderKindBuy},
+			&ordersvc.Order{ID: "B1", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(110), Quantity: decimal.New(10)},
+		},
+		expTrades: []*tradesvc.Trade{},
+		expCancels: []*cancelsvc.Cancel{
+			{OrderID: "E1", Reason: cancelsvc.CancelReasonUser},
+			{OrderID: "TP", Reason: cancelsvc.CancelReasonLinked},
+			{OrderID: "SL", Reason: cancelsvc.CancelReasonLinked},
+		},
+	}
+}
+
+func getTestCase41() *testCase {
+	return &testCase{
+		name: "2trade(allOrNoneSkipped)",
+		ords: []interface{}{
+			&ordersvc.Order{ID: "S1", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(100), AllOrNone: true},
+			&ordersvc.Order{ID: "S2", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(11), Quantity: decimal.New(50)},
+			&ordersvc.Order{ID: "B1", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(11), Quantity: decimal.New(60), TimeInForce: ordersvc.TimeInForceIOC},
+			&ordersvc.Order{ID: "B2", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(100)},
+		},
+		expTrades: []*tradesvc.Trade{
+			{BuyOrderID: "B1", SellOrderID: "S2", Price: decimal.New(11), Quantity: decimal.New(50)},
+			{BuyOrderID: "B2", SellOrderID: "S1", Price: decimal.New(10), Quantity: decimal.New(100)},
+		},
+		expCancels: []*cancelsvc.Cancel{
+			{OrderID: "B1", Reason: cancelsvc.CancelReasonImmediateOrCancel},
+		},
+	}
+}
+
+func getTestCase42() *testCase {
+	return &testCase{
+		name: "2trade(minQuantity)",
+		ords: []interface{}{
+			&ordersvc.Order{ID: "S1", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(30)},
+			&ordersvc.Order{ID: "B1", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(100), MinQuantity: decimal.New(50), TimeInForce: ordersvc.TimeInForceIOC},
+			&ordersvc.Order{ID: "S2", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(30)},
+			&ordersvc.Order{ID: "B2", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(100), MinQuantity: decimal.New(50), TimeInForce: ordersvc.TimeInForceIOC},
+		},
+		expTrades: []*tradesvc.Trade{
+			{BuyOrderID: "B2", SellOrderID: "S1", Price: decimal.New(10), Quantity: decimal.New(30)},
+			{BuyOrderID: "B2", SellOrderID: "S2", Price: decimal.New(10), Quantity: decimal.New(30)},
+		},
+		expCancels: []*cancelsvc.Cancel{
+			{OrderID: "B1", Reason: cancelsvc.CancelReasonImmediateOrCancel},
+			{OrderID: "B2", Reason: cancelsvc.CancelReasonImmediateOrCancel},
+		},
+	}
+}
+
+func getTestCase43() *testCase {
+	return &testCase{
+		name: "3trade(orderLifecycle)",
+		ords: []interface{}{
+			&ordersvc.Order{ID: "S1", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(50)},
+			&ordersvc.Order{ID: "B1", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(11), Quantity: decimal.New(30)},
+			&ordersvc.Order{ID: "S2", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(12), Quantity: decimal.New(40)},
+			&ordersvc.Order{ID: "B2", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeMarket, Quantity: decimal.New(40)},
+			&ordersvc.Cancel{OrderID: "S2", OrderKind: ordersvc.OrderKindSell},
+			&ordersvc.Order{ID: "B3", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(9), Quantity: decimal.New(5), TimeInForce: ordersvc.TimeInForceFOK},
+			&ordersvc.Order{ID: "B4", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(9), Quantity: decimal.New(5)},
+		},
+		expTrades: []*tradesvc.Trade{
+			{BuyOrderID: "B1", SellOrderID: "S1", Price: decimal.New(10), Quantity: decimal.New(30)},
+			{BuyOrderID: "B2", SellOrderID: "S1", Price: decimal.New(10), Quantity: decimal.New(20)},
+			{BuyOrderID: "B2", SellOrderID: "S2", Price: decimal.New(12), Quantity: decimal.New(20)},
+		},
+		expCancels: []*cancelsvc.Cancel{
+			{OrderID: "S2", Reason: cancelsvc.CancelReasonUser},
+			{OrderID: "B3", Reason: cancelsvc.CancelReasonFillOrKill},
+		},
+		expOrders: []*ordersvc.Order{
+			{ID: "S1", Status: ordersvc.OrderStatusFilled, FilledQuantity: decimal.New(50), AverageFillPrice: decimal.New(10)},
+			{ID: "B1", Status: ordersvc.OrderStatusFilled, FilledQuantity: decimal.New(30), AverageFillPrice: decimal.New(10)},
+			{ID: "B2", Status: ordersvc.OrderStatusFilled, FilledQuantity: decimal.New(40), AverageFillPrice: decimal.New(11)},
+			{ID: "S2", Status: ordersvc.OrderStatusCanceled, FilledQuantity: decimal.New(20), AverageFillPrice: decimal.New(12)},
+			{ID: "B3", Status: ordersvc.OrderStatusRejected},
+			{ID: "B4", Status: ordersvc.OrderStatusNew, RemainingQuantity: decimal.New(5)},
+		},
+	}
+}
+
+func getTestCase44() *testCase {
+	return &testCase{
+		name: "2trade(executionReports)",
+		// trade IDs have no start time in them in deterministic mode
+		opts: []engine.MatchEngineOption{engine.WithDeterministic()},
+		ords: []interface{}{
+			&ordersvc.Order{ID: "S1", Symbol: "BTC-USD", AccountID: "A1", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(30)},
+			&ordersvc.Order{ID: "B1", Symbol: "BTC-USD", AccountID: "A2", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(10)},
+			&ordersvc.Order{ID: "B2", Symbol: "BTC-USD", AccountID: "A3", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeMarket, Quantity: decimal.New(5)},
+		},
+		expTrades: []*tradesvc.Trade{
+			{ID: "BTC-USD-1", Sequence: 1, Symbol: "BTC-USD", BuyOrderID: "B1", SellOrderID: "S1", BuyAccountID: "A2", SellAccountID: "A1", AggressorSide: ordersvc.OrderKindBuy, MakerOrderID: "S1", TakerOrderID: "B1",
+				Price: decimal.New(10), Quantity: decimal.New(10), BuyRemainingQuantity: decimal.Zero, SellRemainingQuantity: decimal.New(20)},
+			{ID: "BTC-USD-2", Sequence: 2, Symbol: "BTC-USD", BuyOrderID: "B2", SellOrderID: "S1", BuyAccountID: "A3", SellAccountID: "A1", AggressorSide: ordersvc.OrderKindBuy, MakerOrderID: "S1", TakerOrderID: "B2",
+				Price: decimal.New(10), Quantity: decimal.New(5), BuyRemainingQuantity: decimal.Zero, SellRemainingQuantity: decimal.New(15)},
+		},
+		expReports: []*tradesvc.ExecutionReport{
+			{TradeID: "BTC-USD-1", Sequence: 1, OrderID: "B1", AccountID: "A2", Symbol: "BTC-USD", Side: ordersvc.OrderKindBuy, Liquidity: tradesvc.LiquidityTaker,
+				Price: decimal.New(10), Quantity: decimal.New(10), RemainingQuantity: decimal.Zero},
+			{TradeID: "BTC-USD-1", Sequence: 1, OrderID: "S1", AccountID: "A1", Symbol: "BTC-USD", Side: ordersvc.OrderKindSell, Liquidity: tradesvc.LiquidityMaker,
+				Price: decimal.New(10), Quantity: decimal.New(10), RemainingQuantity: decimal.New(20)},
+			{TradeID: "BTC-USD-2", Sequence: 2, OrderID: "B2", AccountID: "A3", Symbol: "BTC-USD", Side: ordersvc.OrderKindBuy, Liquidity: tradesvc.LiquidityTaker,
+				Price: decimal.New(10), Quantity: decimal.New(5), RemainingQuantity: decimal.Zero},
+			{TradeID: "BTC-USD-2", Sequence: 2, OrderID: "S1", AccountID: "A1", Symbol: "BTC-USD", Side: ordersvc.OrderKindSell, Liquidity: tradesvc.LiquidityMaker,
+				Price: decimal.New(10), Quantity: decimal.New(5), RemainingQuantity: decimal.New(15)},
+		},
+	}
+}
+
+func getTestCase45() *testCase {
+	return &testCase{
+		name: "2trade(makerTakerFees)",
+		// trade IDs have no start time in them in deterministic mode
+		opts: []engine.MatchEngineOption{engine.WithDeterministic()},
+		fees: &feesvc.Config{
+			Accounts: map[string]string{"A1": "vip"},
+			Schedules: []feesvc.Schedule{
+				{Tier: feesvc.DefaultTier, MakerRate: decimal.MustParse("0.001"), TakerRate: decimal.MustParse("0.002"), MinFee: decimal.MustParse("0.5")},
+				{Tier: "vip", MakerRate: decimal.MustParse("-0.0001"), TakerRate: decimal.MustParse("0.001")},
+				{Tier: "vip", Symbol: "ETH-USD", MakerRate: decimal.Zero, TakerRate: decimal.Zero},
+			},
+		},
+		ords: []interface{}{
+			&ordersvc.Order{ID: "S1", Symbol: "BTC-USD", AccountID: "A1", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(100), Quantity: decimal.New(30)},
+			&ordersvc.Order{ID: "B1", Symbol: "BTC-USD", AccountID: "A2", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(100), Quantity: decimal.New(20)},
+			&ordersvc.Order{ID: "B2", Symbol: "BTC-USD", AccountID: "A2", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(100), Quantity: decimal.MustParse("0.1")},
+		},
+		expTrades: []*tradesvc.Trade{
+			{ID: "BTC-USD-1", Sequence: 1, Symbol: "BTC-USD", BuyOrderID: "B1", SellOrderID: "S1", BuyAccountID: "A2", SellAccountID: "A1",
+				AggressorSide: ordersvc.OrderKindBuy, MakerOrderID: "S1", TakerOrderID: "B1", Price: decimal.New(100), Quantity: decimal.New(20),
+				BuyRemainingQuantity: decimal.Zero, SellRemainingQuantity: decimal.New(10), BuyFee: decimal.New(4), SellFee: decimal.MustParse("-0.2")},
+			{ID: "BTC-USD-2", Sequence: 2, Symbol: "BTC-USD", BuyOrderID: "B2", SellOrderID: "S1", BuyAccountID: "A2", SellAccountID: "A1",
+				AggressorSide: ordersvc.OrderKindBuy, MakerOrderID: "S1", TakerOrderID: "B2", Price: decimal.New(100), Quantity: decimal.MustParse("0.1"),
+				BuyRemainingQuantity: decimal.Zero, SellRemainingQuantity: decimal.MustParse("9.9"), BuyFee: decimal.MustParse("0.5"), SellFee: decimal.MustParse("-0.001")},
+		},
+	}
+}
+
+func getTestCase46() *testCase {
+	return &testCase{
+		name: "1trade(cancelRejects)",
+		ords: []interface{}{
+			&ordersvc.Order{ID: "S1", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(10)},
+			&ordersvc.Order{ID: "B1", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(10)},
+			&ordersvc.Order{ID: "S2", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(11), Quantity: decimal.New(5)},
+			&ordersvc.Cancel{OrderID: "S1", OrderKind: ordersvc.OrderKindSell},
+			&ordersvc.Cancel{OrderID: "X1", OrderKind: ordersvc.OrderKindSell},
 			&ordersvc.Cancel{OrderID: "S2", OrderKind: ordersvc.OrderKindSell},
-			&ordersvc.Order{ID: "B5", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeMarket, Quantity: 100},
-			&ordersvc.Order{ID: "S3", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeMarket, Quantity: 150},
+			&ordersvc.Cancel{OrderID: "S2", OrderKind: ordersvc.OrderKindSell},
+		},
+		expTrades: []*tradesvc.Trade{
+			{BuyOrderID: "B1", SellOrderID: "S1", Price: decimal.New(10), Quantity: decimal.New(10)},
+		},
+		expCancels: []*cancelsvc.Cancel{
+			{OrderID: "S1", Reason: cancelsvc.CancelReasonUser, RejectReason: cancelsvc.CancelRejectReasonTooLateToCancel},
+			{OrderID: "X1", Reason: cancelsvc.CancelReasonUser, RejectReason: cancelsvc.CancelRejectReasonUnknownOrder},
+			{OrderID: "S2", Reason: cancelsvc.CancelReasonUser, Quantity: decimal.New(5)},
+			{OrderID: "S2", Reason: cancelsvc.CancelReasonUser, RejectReason: cancelsvc.CancelRejectReasonTooLateToCancel},
+		},
+		expOrders: []*ordersvc.Order{
+			{ID: "S1", Status: ordersvc.OrderStatusFilled, FilledQuantity: decimal.New(10), AverageFillPrice: decimal.New(10), CancelRejectReason: cancelsvc.CancelRejectReasonTooLateToCancel},
+			{ID: "S2", Status: ordersvc.OrderStatusCanceled, CancelRejectReason: cancelsvc.CancelRejectReasonTooLateToCancel},
+		},
+	}
+}
+
+func getTestCase47() *testCase {
+	return &testCase{
+		name: "1trade(selfTradeCancelNewestOnlyOnFilledOrders)",
+		opts: []engine.MatchEngineOption{engine.WithSelfTradePrevention(engine.SelfTradePreventionCancelNewest)},
+		ords: []interface{}{
+			&ordersvc.Order{ID: "S1", AccountID: "A2", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(10)},
+			&ordersvc.Order{ID: "S2", AccountID: "A1", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(10)},
+			&ordersvc.Order{ID: "B1", AccountID: "A1", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(5)},
+		},
+		expTrades: []*tradesvc.Trade{
+			{BuyOrderID: "B1", SellOrderID: "S1", Price: decimal.New(10), Quantity: decimal.New(5)},
+		},
+	}
+}
+
+func getTestCase48() *testCase {
+	return &testCase{
+		name: "1trade(selfTradeCancelOldestOnlyOnFilledOrders)",
+		opts: []engine.MatchEngineOption{engine.WithSelfTradePrevention(engine.SelfTradePreventionCancelOldest)},
+		ords: []interface{}{
+			&ordersvc.Order{ID: "S1", AccountID: "A2", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(10)},
+			&ordersvc.Order{ID: "S2", AccountID: "A1", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(10)},
+			&ordersvc.Order{ID: "B1", AccountID: "A1", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(5)},
+		},
+		expTrades: []*tradesvc.Trade{
+			{BuyOrderID: "B1", SellOrderID: "S1", Price: decimal.New(10), Quantity: decimal.New(5)},
+		},
+		expOrders: []*ordersvc.Order{
+			{ID: "S2", Status: ordersvc.OrderStatusNew, RemainingQuantity: decimal.New(10)},
+		},
+	}
+}
+
+func getTestCase49() *testCase {
+	return &testCase{
+		name: "1trade(selfTradeProRataReallocates)",
+		opts: []engine.MatchEngineOption{
+			engine.WithSelfTradePrevention(engine.SelfTradePreventionCancelOldest),
+			engine.WithInstrumentStore(instrumentsvc.NewMemoryStore(instrumentsvc.Instrument{Symbol: "SOFR", MatchingAlgorithm: instrumentsvc.MatchingAlgorithmProRata})),
+		},
+		ords: []interface{}{
+			&ordersvc.Order{ID: "S1", Symbol: "SOFR", AccountID: "A2", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(10)},
+			&ordersvc.Order{ID: "S2", Symbol: "SOFR", AccountID: "A1", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(10)},
+			&ordersvc.Order{ID: "B1", Symbol: "SOFR", AccountID: "A1", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(10)},
+		},
+		expTrades: []*tradesvc.Trade{
+			{Symbol: "SOFR", BuyOrderID: "B1", SellOrderID: "S1", Price: decimal.New(10), Quantity: decimal.New(10)},
+		},
+		expCancels: []*cancelsvc.Cancel{
+			{OrderID: "S2", Reason: cancelsvc.CancelReasonSelfTrade},
+		},
+	}
+}
+
+func getTestCase50() *testCase {
+	return &testCase{
+		name: "0trade(fillOrKillProRataMinQuantity)",
+		opts: []engine.MatchEngineOption{
+			engine.WithInstrumentStore(instrumentsvc.NewMemoryStore(instrumentsvc.Instrument{Symbol: "SOFR", MatchingAlgorithm: instrumentsvc.MatchingAlgorithmProRata})),
+		},
+		ords: []interface{}{
+			&ordersvc.Order{ID: "S0", Symbol: "SOFR", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(2)},
+			&ordersvc.Order{ID: "S1", Symbol: "SOFR", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(10), MinQuantity: decimal.New(5)},
+			&ordersvc.Order{ID: "S2", Symbol: "SOFR", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(10), MinQuantity: decimal.New(5)},
+			&ordersvc.Order{ID: "B1", Symbol: "SOFR", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(8), TimeInForce: ordersvc.TimeInForceFOK},
+		},
+		expCancels: []*cancelsvc.Cancel{
+			{OrderID: "B1", Reason: cancelsvc.CancelReasonFillOrKill},
+		},
+	}
+}
+
+func getTestCase51() *testCase {
+	return &testCase{
+		name: "1trade(amendRejects)",
+		ords: []interface{}{
+			&ordersvc.Order{ID: "S1", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(10)},
+			&ordersvc.Order{ID: "B1", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(10)},
+			&ordersvc.Order{ID: "S2", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(11), Quantity: decimal.New(5)},
+			&ordersvc.Amend{OrderID: "S1", OrderKind: ordersvc.OrderKindSell, Quantity: decimal.New(5)},
+			&ordersvc.Amend{OrderID: "X1", OrderKind: ordersvc.OrderKindSell, Price: decimal.New(12)},
+			&ordersvc.Amend{OrderID: "S2", OrderKind: ordersvc.OrderKindSell, Quantity: decimal.New(4)},
+		},
+		expTrades: []*tradesvc.Trade{
+			{BuyOrderID: "B1", SellOrderID: "S1", Price: decimal.New(10), Quantity: decimal.New(10)},
+		},
+		expCancels: []*cancelsvc.Cancel{
+			{OrderID: "S1", Reason: cancelsvc.CancelReasonUser, RejectReason: cancelsvc.CancelRejectReasonTooLateToCancel, Amend: true},
+			{OrderID: "X1", Reason: cancelsvc.CancelReasonUser, RejectReason: cancelsvc.CancelRejectReasonUnknownOrder, Amend: true},
+		},
+		expOrders: []*ordersvc.Order{
+			{ID: "S1", Status: ordersvc.OrderStatusFilled, FilledQuantity: decimal.New(10), AverageFillPrice: decimal.New(10), AmendRejectReason: cancelsvc.CancelRejectReasonTooLateToCancel},
+			{ID: "S2", Status: ordersvc.OrderStatusNew, RemainingQuantity: decimal.New(4)},
+		},
+	}
+}
+
+func getTestCase52() *testCase {
+	return &testCase{
+		name: "2trade(bracketWaitingExitAmended)",
+		ords: []interface{}{
+			&ordersvc.OrderGroup{ID: "G1", Kind: ordersvc.OrderGroupKindBracket, Orders: []ordersvc.Order{
+				{ID: "E1", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(100), Quantity: decimal.New(10)},
+				{ID: "TP", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(110), Quantity: decimal.New(10)},
+				{ID: "SL", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeStop, TriggerPrice: decimal.New(95), Quantity: decimal.New(10)},
+			}},
+			&ordersvc.Amend{OrderID: "TP", OrderKind: ordersvc.OrderKindSell, Price: decimal.New(105)},
+			&ordersvc.Order{ID: "S1", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(100), Quantity: decimal.New(10)},
+			&ordersvc.Order{ID: "B1", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(105), Quantity: decimal.New(10)},
 		},
 		expTrades: []*tradesvc.Trade{
-			{BuyOrderID: "B1", SellOrderID: "S1", Price: 10., Quantity: 100},
-			{BuyOrderID: "B2", SellOrderID: "S1", Price: 10., Quantity: 100},
-			{BuyOrderID: "B3", SellOrderID: "S1", Price: 10., Quantity: 100},
-			{BuyOrderID: "B4", SellOrderID: "S1", Price: 10., Quantity: 50},
-			{BuyOrderID: "B4", SellOrderID: "S2", Price: 10., Quantity: 50},
-			{BuyOrderID: "B5", SellOrderID: "S3", Price: 10., Quantity: 100},
+			{BuyOrderID: "E1", SellOrderID: "S1", Price: decimal.New(100), Quantity: decimal.New(10)},
+			{BuyOrderID: "B1", SellOrderID: "TP", Price: decimal.New(105), Quantity: decimal.New(10)},
 		},
 		expCancels: []*cancelsvc.Cancel{
-			{OrderID: "S2"},
+			{OrderID: "SL", Reason: cancelsvc.CancelReasonLinked},
+		},
+		expOrders: []*ordersvc.Order{
+			{ID: "TP", Status: ordersvc.OrderStatusFilled, FilledQuantity: decimal.New(10), AverageFillPrice: decimal.New(105)},
+		},
+	}
+}
+
+func getTestCase53() *testCase {
+	// an auction running from an hour ago until shortly after the orders are sent
+	now := time.Now().UTC()
+	timeOfDay := now.Sub(now.Truncate(24 * time.Hour))
+	window := engine.AuctionWindow{Start: (timeOfDay - time.Hour + 24*time.Hour) % (24 * time.Hour), End: (timeOfDay + 50*time.Millisecond) % (24 * time.Hour)}
+
+	return &testCase{
+		name: "3trade(allOrNoneAfterUncross)",
+		opts: []engine.MatchEngineOption{engine.WithAuctions(window)},
+		ords: []interface{}{
+			&ordersvc.Order{ID: "B1", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(101), Quantity: decimal.New(10), AllOrNone: true},
+			&ordersvc.Order{ID: "S1", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(99), Quantity: decimal.New(4)},
+			&ordersvc.Order{ID: "S2", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(100), Quantity: decimal.New(8)},
+			&ordersvc.Order{ID: "B2", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(100), Quantity: decimal.New(2)},
+		},
+		expTrades: []*tradesvc.Trade{
+			{BuyOrderID: "B2", SellOrderID: "S1", Price: decimal.New(99), Quantity: decimal.New(2)},
+			{BuyOrderID: "B1", SellOrderID: "S1", Price: decimal.New(99), Quantity: decimal.New(2)},
+			{BuyOrderID: "B1", SellOrderID: "S2", Price: decimal.New(100), Quantity: decimal.New(8)},
 		},
 	}
 }
@@ -0,0 +1,191 @@
+package unittest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"trading-matching-service/pkg/engine"
+	cancelsvc "trading-matching-service/pkg/service/cancel"
+	journalsvc "trading-matching-service/pkg/service/journal"
+	msgsvc "trading-matching-service/pkg/service/message"
+	ordersvc "trading-matching-service/pkg/service/order"
+	"trading-matching-service/util/decimal"
+)
+
+func TestRecoverFromJournal(t *testing.T) {
+	before := []msgsvc.Message{
+		msgsvc.NewMessage(msgsvc.MessageKindOrderCreate, &ordersvc.Order{ID: "S1", Symbol: "BTC-USD", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(30)}),
+		msgsvc.NewMessage(msgsvc.MessageKindOrderCreate, &ordersvc.Order{ID: "S2", Symbol: "BTC-USD", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(11), Quantity: decimal.New(10)}),
+		msgsvc.NewMessage(msgsvc.MessageKindOrderCreate, &ordersvc.Order{ID: "B1", Symbol: "BTC-USD", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(5)}),
+		msgsvc.NewMessage(msgsvc.MessageKindOrderCreate, &ordersvc.Order{ID: "B2", Symbol: "ETH-USD", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(3), Quantity: decimal.New(7)}),
+		msgsvc.NewMessage(msgsvc.MessageKindOrderCancel, &ordersvc.Cancel{OrderID: "S2", Symbol: "BTC-USD", OrderKind: ordersvc.OrderKindSell}),
+	}
+	after := []msgsvc.Message{
+		msgsvc.NewMessage(msgsvc.MessageKindOrderCreate, &ordersvc.Order{ID: "B3", Symbol: "BTC-USD", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeMarket, Quantity: decimal.New(40)}),
+		msgsvc.NewMessage(msgsvc.MessageKindOrderCreate, &ordersvc.Order{ID: "S3", Symbol: "ETH-USD", Kind: ordersvc.OrderKindSell, PriceType: ordersvc.PriceTypeMarket, Quantity: decimal.New(7)}),
+		msgsvc.NewMessage(msgsvc.MessageKindOrderCancel, &ordersvc.Cancel{OrderID: "B1", Symbol: "BTC-USD", OrderKind: ordersvc.OrderKindBuy}),
+	}
+
+	tests := []struct {
+		name             string
+		snapshotInterval uint64
+	}{
+		{name: "journal only", snapshotInterval: 0},
+		{name: "snapshot and journal tail", snapshotInterval: 2},
+		{name: "snapshot only", snapshotInterval: 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+
+			_, _ = runJournaled(t, dir, tt.snapshotInterval, before)
+			trades, store := runJournaled(t, dir, tt.snapshotInterval, after)
+
+			expected := []tradeTuple{
+				{"BTC-USD-2", "B3", "S1", decimal.New(10), decimal.New(25)},
+				{"ETH-USD-1", "B2", "S3", decimal.New(3), decimal.New(7)},
+			}
+			assert.Equal(t, expected, trades)
+
+			ord, err := store.GetOrder(context.Background(), "S2")
+			assert.NoError(t, err)
+			assert.Equal(t, ordersvc.OrderStatusCanceled, ord.Status)
+			ord, err = store.GetOrder(context.Background(), "S1")
+			assert.NoError(t, err)
+			assert.Equal(t, ordersvc.OrderStatusFilled, ord.Status)
+			// B1 was filled before the restart, so canceling it after is too late
+			ord, err = store.GetOrder(context.Background(), "B1")
+			assert.NoError(t, err)
+			assert.Equal(t, ordersvc.OrderStatusFilled, ord.Status)
+			assert.Equal(t, decimal.New(5), ord.FilledQuantity)
+			assert.Equal(t, cancelsvc.CancelRejectReasonTooLateToCancel, ord.CancelRejectReason)
+		})
+	}
+}
+
+func TestJournalAppendFailure(t *testing.T) {
+	tests := []struct {
+		name     string
+		failures int
+		timeout  time.Duration
+		wantErr  bool
+	}{
+		{name: "retried in place", failures: 2, timeout: 200 * time.Millisecond},
+		{name: "stops the engine", failures: 100, timeout: 5 * time.Second, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jnl, err := journalsvc.NewFileJournal(journalsvc.Config{Dir: t.TempDir(), SyncPolicy: journalsvc.SyncPolicyNone})
+			if !assert.NoError(t, err) {
+				return
+			}
+			defer jnl.Close()
+
+			orderQ := msgsvc.NewQueue(1)
+			fj := &failingJournal{Journal: jnl, failures: tt.failures}
+			me := engine.NewMatchEngine(ordersvc.NewMemoryStore(), orderQ, msgsvc.NewQueue(10), msgsvc.NewQueue(10), engine.WithDeterministic(), engine.WithJournal(fj, 0))
+
+			ctx, cancel := context.WithTimeout(context.Background(), tt.timeout)
+			defer cancel()
+			_ = orderQ.Push(ctx, msgsvc.NewMessage(msgsvc.MessageKindOrderCreate, &ordersvc.Order{ID: "B1", Symbol: "BTC-USD", Kind: ordersvc.OrderKindBuy, PriceType: ordersvc.PriceTypeLimit, Price: decimal.New(10), Quantity: decimal.New(5)}))
+
+			err = me.Run(ctx)
+			if tt.wantErr {
+				assert.EqualError(t, err, "failed to journal message 1: disk failure")
+				// the message is not requeued behind later ones
+				popCtx, popCancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+				defer popCancel()
+				_, err := orderQ.Pop(popCtx)
+				assert.Error(t, err)
+				return
+			}
+			assert.Equal(t, context.DeadlineExceeded, err)
+			assert.Equal(t, tt.failures+1, fj.appends)
+			n := 0
+			assert.NoError(t, jnl.Replay(0, func(entry journalsvc.Entry) error {
+				n++
+				return nil
+			}))
+			assert.Equal(t, 1, n)
+		})
+	}
+}
+
+// failingJournal fails the first appends to the journal.
+type failingJournal struct {
+	journalsvc.Journal
+	failures int
+	appends  int
+}
+
+func (j *failingJournal) Append(entry journalsvc.Entry) error {
+	j.appends++
+	if j.appends <= j.failures {
+		return errors.New("disk failure")
+	}
+	return j.Journal.Append(entry)
+}
+
+type tradeTuple struct {
+	ID          string
+	BuyOrderID  string
+	SellOrderID string
+	Price       decimal.Decimal
+	Quantity    decimal.Decimal
+}
+
+// runJournaled recovers a deterministic match engine from the journal in dir, runs the messages
+// through it and returns the trades it published and its order store.
+func runJournaled(t *testing.T, dir string, snapshotInterval uint64, msgs []msgsvc.Message) ([]tradeTuple, ordersvc.Store) {
+	jnl, err := journalsvc.NewFileJournal(journalsvc.Config{Dir: dir, SyncPolicy: journalsvc.SyncPolicyAlways})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer jnl.Close()
+
+	orderQ, tradeQ, cancelQ := msgsvc.NewQueue(100), msgsvc.NewQueue(100), msgsvc.NewQueue(100)
+	trR := &tradeRecorder{}
+	store := ordersvc.NewMemoryStore()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	me := engine.NewMatchEngine(store, orderQ, tradeQ, cancelQ, engine.WithDeterministic(), engine.WithJournal(jnl, snapshotInterval))
+	if !assert.NoError(t, me.(engine.Recoverer).Recover(ctx)) {
+		t.FailNow()
+	}
+	te := engine.NewTradeEngine(tradeQ, trR)
+	done, teDone := make(chan struct{}), make(chan struct{})
+	go func() {
+		_ = me.Run(ctx)
+		close(done)
+	}()
+	go func() {
+		_ = te.Run(ctx)
+		close(teDone)
+	}()
+
+	for _, msg := range msgs {
+		// orders are created in store before they are pushed, as the controller does
+		if msg.GetKind() == msgsvc.MessageKindOrderCreate {
+			ord := ordersvc.Order{}
+			_ = json.Unmarshal(msg.GetData(), &ord)
+			_, _ = store.CreateOrder(ctx, ord)
+		}
+		_ = orderQ.Push(ctx, msg)
+	}
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+	<-done
+	<-teDone
+
+	tuples := []tradeTuple{}
+	for _, td := range trR.get() {
+		tuples = append(tuples, tradeTuple{td.ID, td.BuyOrderID, td.SellOrderID, td.Price, td.Quantity})
+	}
+	return tuples, store
+}
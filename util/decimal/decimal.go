@@ -0,0 +1,293 @@
+package decimal
+
+import (
+	"errors"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Places is the number of fractional digits a Decimal keeps.
+const Places = 8
+
+const scale = int64(100000000)
+
+var (
+	errInvalidDecimal = errors.New("invalid decimal")
+	errTooManyPlaces  = errors.New("too many decimal places")
+	errOutOfRange     = errors.New("decimal out of range")
+)
+
+// Decimal is a fixed-point decimal number with 8 fractional digits. The zero value is 0.
+// Decimals are exactly comparable with ==. Arithmetic saturates at MaxValue and MinValue instead
+// of wrapping around.
+type Decimal struct {
+	units int64
+}
+
+var (
+	// Zero is the decimal 0.
+	Zero = Decimal{}
+	// MaxValue is the largest decimal, 92233720368.54775807.
+	MaxValue = Decimal{units: math.MaxInt64}
+	// MinValue is the smallest decimal, -MaxValue.
+	MinValue = Decimal{units: -math.MaxInt64}
+)
+
+// New returns the decimal of an integer.
+func New(v int64) Decimal {
+	return Decimal{units: v * scale}
+}
+
+// NewFromFloat returns the decimal closest to a float.
+func NewFromFloat(f float64) Decimal {
+	return Decimal{units: int64(math.Round(f * float64(scale)))}
+}
+
+// Parse parses a decimal string such as "-12.345". It fails on more than 8 fractional digits.
+func Parse(s string) (Decimal, error) {
+	neg := false
+	switch {
+	case strings.HasPrefix(s, "-"):
+		neg = true
+		s = s[1:]
+	case strings.HasPrefix(s, "+"):
+		s = s[1:]
+	}
+
+	intPart, fracPart := s, ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		intPart, fracPart = s[:i], s[i+1:]
+	}
+	if intPart == "" && fracPart == "" {
+		return Zero, errInvalidDecimal
+	}
+
+	fracPart = strings.TrimRight(fracPart, "0")
+	if len(fracPart) > Places {
+		return Zero, errTooManyPlaces
+	}
+	fracPart += strings.Repeat("0", Places-len(fracPart))
+
+	if intPart == "" {
+		intPart = "0"
+	}
+	for _, c := range intPart + fracPart {
+		if c < '0' || c > '9' {
+			return Zero, errInvalidDecimal
+		}
+	}
+
+	i, err := strconv.ParseInt(intPart, 10, 64)
+	if err != nil || i > math.MaxInt64/scale {
+		return Zero, errOutOfRange
+	}
+	f, _ := strconv.ParseInt(fracPart, 10, 64)
+	units := i*scale + f
+	if units < 0 {
+		return Zero, errOutOfRange
+	}
+
+	if neg {
+		units = -units
+	}
+	return Decimal{units: units}, nil
+}
+
+// MustParse is like Parse but panics on an invalid string.
+func MustParse(s string) Decimal {
+	d, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+// String returns the decimal without trailing fractional zeros, e.g. "12.5".
+func (d Decimal) String() string {
+	units := d.units
+	sign := ""
+	if units < 0 {
+		sign = "-"
+		units = -units
+	}
+
+	i, f := units/scale, units%scale
+	if f == 0 {
+		return sign + strconv.FormatInt(i, 10)
+	}
+	frac := strconv.FormatInt(f, 10)
+	frac = strings.Repeat("0", Places-len(frac)) + frac
+	return sign + strconv.FormatInt(i, 10) + "." + strings.TrimRight(frac, "0")
+}
+
+// Float64 returns the nearest float of the decimal.
+func (d Decimal) Float64() float64 {
+	return float64(d.units) / float64(scale)
+}
+
+// IsZero reports whether the decimal is 0.
+func (d Decimal) IsZero() bool {
+	return d.units == 0
+}
+
+// Sign returns -1, 0 or 1 for a negative, zero or positive decimal.
+func (d Decimal) Sign() int {
+	switch {
+	case d.units < 0:
+		return -1
+	case d.units > 0:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// Cmp returns -1, 0 or 1 when d is less than, equal to or greater than o.
+func (d Decimal) Cmp(o Decimal) int {
+	switch {
+	case d.units < o.units:
+		return -1
+	case d.units > o.units:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// LessThan reports whether d < o.
+func (d Decimal) LessThan(o Decimal) bool {
+	return d.units < o.units
+}
+
+// GreaterThan reports whether d > o.
+func (d Decimal) GreaterThan(o Decimal) bool {
+	return d.units > o.units
+}
+
+// Add returns d + o.
+func (d Decimal) Add(o Decimal) Decimal {
+	units := d.units + o.units
+	switch {
+	case o.units > 0 && units < d.units:
+		return MaxValue
+	case o.units < 0 && units > d.units, units < MinValue.units:
+		return MinValue
+	}
+	return Decimal{units: units}
+}
+
+// Sub returns d - o.
+func (d Decimal) Sub(o Decimal) Decimal {
+	units := d.units - o.units
+	switch {
+	case o.units < 0 && units < d.units:
+		return MaxValue
+	case o.units > 0 && units > d.units, units < MinValue.units:
+		return MinValue
+	}
+	return Decimal{units: units}
+}
+
+// Neg returns -d.
+func (d Decimal) Neg() Decimal {
+	return Decimal{units: -d.units}
+}
+
+// Mul returns d * o truncated to 8 fractional digits.
+func (d Decimal) Mul(o Decimal) Decimal {
+	return d.MulDiv(o, New(1))
+}
+
+// MulDiv returns d * m / n truncated to 8 fractional digits, without losing precision in between.
+func (d Decimal) MulDiv(m, n Decimal) Decimal {
+	v := new(big.Int).Mul(big.NewInt(d.units), big.NewInt(m.units))
+	v.Quo(v, big.NewInt(n.units))
+	return saturate(v)
+}
+
+// Product returns the product of the decimals truncated to 8 fractional digits, without losing
+// precision or range in between, so it is in range whenever the result is.
+func Product(ds ...Decimal) Decimal {
+	v, div := big.NewInt(scale), big.NewInt(1)
+	for _, d := range ds {
+		v.Mul(v, big.NewInt(d.units))
+		div.Mul(div, big.NewInt(scale))
+	}
+	return saturate(v.Quo(v, div))
+}
+
+// saturate returns the decimal of the units, or MaxValue or MinValue if they are out of range.
+func saturate(units *big.Int) Decimal {
+	switch {
+	case units.Cmp(big.NewInt(MaxValue.units)) > 0:
+		return MaxValue
+	case units.Cmp(big.NewInt(MinValue.units)) < 0:
+		return MinValue
+	default:
+		return Decimal{units: units.Int64()}
+	}
+}
+
+// Truncate rounds d toward zero to a multiple of step. A non-positive step leaves d as it is.
+func (d Decimal) Truncate(step Decimal) Decimal {
+	if step.units <= 0 {
+		return d
+	}
+	return Decimal{units: d.units - d.units%step.units}
+}
+
+// IsMultipleOf reports whether d is a whole multiple of step. Any decimal is a multiple of a
+// non-positive step.
+func (d Decimal) IsMultipleOf(step Decimal) bool {
+	return step.units <= 0 || d.units%step.units == 0
+}
+
+// MarshalJSON encodes the decimal as a JSON number.
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalJSON decodes the decimal from a JSON number or string.
+func (d *Decimal) UnmarshalJSON(bs []byte) error {
+	s := string(bs)
+	if s == "null" {
+		return nil
+	}
+	if uq, err := strconv.Unquote(s); err == nil {
+		s = uq
+	}
+
+	// accept exponents, which are valid in JSON numbers
+	if strings.ContainsAny(s, "eE") {
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return errInvalidDecimal
+		}
+		s = strconv.FormatFloat(f, 'f', -1, 64)
+	}
+
+	v, err := Parse(s)
+	if err != nil {
+		return err
+	}
+	*d = v
+	return nil
+}
+
+// Min returns the smaller of a and b.
+func Min(a, b Decimal) Decimal {
+	if a.units < b.units {
+		return a
+	}
+	return b
+}
+
+// Max returns the larger of a and b.
+func Max(a, b Decimal) Decimal {
+	if a.units > b.units {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,90 @@
+package decimal
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in     string
+		exp    string
+		hasErr bool
+	}{
+		{in: "10", exp: "10"},
+		{in: "0.1", exp: "0.1"},
+		{in: "-12.50", exp: "-12.5"},
+		{in: ".5", exp: "0.5"},
+		{in: "0.00000001", exp: "0.00000001"},
+		{in: "0.000000001", hasErr: true},
+		{in: "1.2.3", hasErr: true},
+		{in: "abc", hasErr: true},
+		{in: "", hasErr: true},
+		{in: "100000000000000000000", hasErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			d, err := Parse(tt.in)
+			if tt.hasErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.exp, d.String())
+		})
+	}
+}
+
+func TestArithmetic(t *testing.T) {
+	// the sum is exact unlike with floats
+	assert.Equal(t, MustParse("0.3"), MustParse("0.1").Add(MustParse("0.2")))
+	assert.Equal(t, MustParse("0.1"), MustParse("0.3").Sub(MustParse("0.2")))
+	assert.Equal(t, MustParse("1.5"), MustParse("0.5").Mul(New(3)))
+	assert.Equal(t, MustParse("3.33333333"), New(10).MulDiv(New(1), New(3)))
+	assert.Equal(t, MustParse("1.25"), MustParse("1.27").Truncate(MustParse("0.05")))
+	assert.True(t, MustParse("1.25").IsMultipleOf(MustParse("0.05")))
+	assert.False(t, MustParse("1.26").IsMultipleOf(MustParse("0.05")))
+	assert.Equal(t, New(1), Min(New(1), New(2)))
+	assert.Equal(t, New(2), Max(New(1), New(2)))
+	assert.Equal(t, 1, New(2).Cmp(New(1)))
+}
+
+func TestSaturation(t *testing.T) {
+	// out of range results saturate instead of wrapping around
+	assert.Equal(t, MaxValue, MustParse("500").Mul(MustParse("200000000")))
+	assert.Equal(t, MinValue, MustParse("-500").Mul(MustParse("200000000")))
+	assert.Equal(t, MaxValue, MaxValue.MulDiv(New(3), New(2)))
+	assert.Equal(t, MaxValue, MaxValue.Add(MustParse("0.00000001")))
+	assert.Equal(t, MinValue, MinValue.Add(MustParse("-0.00000001")))
+	assert.Equal(t, MaxValue, MaxValue.Sub(MustParse("-0.00000001")))
+	assert.Equal(t, MinValue, MinValue.Sub(MustParse("0.00000001")))
+	assert.Equal(t, MinValue, MinValue.Sub(New(1)).Sub(New(1)))
+	assert.Equal(t, MustParse("92233720367.54775807"), MaxValue.Sub(New(1)))
+	assert.Equal(t, Zero, MaxValue.Add(MinValue))
+
+	// a product in range is exact even if a partial product is out of range
+	assert.Equal(t, New(100000000), Product(MustParse("500"), MustParse("200000000"), MustParse("0.001")))
+	assert.Equal(t, MustParse("0.00000005"), Product(MustParse("0.0001"), MustParse("0.5"), MustParse("0.001")))
+	assert.Equal(t, MaxValue, Product(MustParse("500"), MustParse("200000000")))
+}
+
+func TestJSON(t *testing.T) {
+	v := struct {
+		Price    Decimal `json:"price"`
+		Quantity Decimal `json:"quantity"`
+	}{}
+	assert.NoError(t, json.Unmarshal([]byte(`{"price": 0.1, "quantity": "2.5"}`), &v))
+	assert.Equal(t, MustParse("0.1"), v.Price)
+	assert.Equal(t, MustParse("2.5"), v.Quantity)
+
+	bs, err := json.Marshal(v)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"price":0.1,"quantity":2.5}`, string(bs))
+
+	assert.NoError(t, json.Unmarshal([]byte(`{"price": 1e2}`), &v))
+	assert.Equal(t, New(100), v.Price)
+	assert.Error(t, json.Unmarshal([]byte(`{"price": 0.123456789}`), &v))
+}